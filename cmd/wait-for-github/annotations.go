@@ -0,0 +1,121 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"github.com/grafana/wait-for-github/internal/actions"
+	"github.com/grafana/wait-for-github/internal/github"
+)
+
+// checkAnnotation is an annotation of a check run, with the check it's from.
+type checkAnnotation struct {
+	github.CheckRunAnnotation
+	check string
+}
+
+// failedCheckAnnotations returns the failure and warning annotations of the
+// failed check runs the overall status depends on. Annotations which can't be
+// fetched are skipped, as they're only a convenience.
+func failedCheckAnnotations(ctx context.Context, client github.GetCheckRunAnnotations, logger *slog.Logger, owner, repo string, checks []github.CICheckStatus, waitedFor, excludes []string) []checkAnnotation {
+	var result []checkAnnotation
+	for _, run := range failedCheckRuns(checks, waitedFor, excludes) {
+		name := checkRunName(run)
+
+		annotations, err := client.GetCheckRunAnnotations(ctx, owner, repo, run.DatabaseID)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get annotations of failed check", "check", name, "error", err)
+			continue
+		}
+
+		for _, annotation := range annotations {
+			if annotation.Level == github.AnnotationLevelFailure || annotation.Level == github.AnnotationLevelWarning {
+				result = append(result, checkAnnotation{CheckRunAnnotation: annotation, check: name})
+			}
+		}
+	}
+
+	return result
+}
+
+// showAnnotations writes the annotations to out. When running in GitHub
+// Actions, they're written as workflow commands, so that they're shown on the
+// workflow run and inline on the pull request.
+func showAnnotations(out io.Writer, annotations []checkAnnotation, inActions bool) {
+	if len(annotations) == 0 {
+		return
+	}
+
+	if inActions {
+		for _, annotation := range annotations {
+			fmt.Fprintln(out, workflowAnnotation(annotation).Command())
+		}
+		return
+	}
+
+	fmt.Fprintln(out, "Annotations of failed checks:")
+	for _, annotation := range annotations {
+		fmt.Fprintf(out, "%s: %s\n", annotation.check, formatAnnotation(annotation.CheckRunAnnotation))
+	}
+}
+
+// workflowAnnotation returns the check run annotation as a GitHub Actions
+// annotation of the calling workflow.
+func workflowAnnotation(annotation checkAnnotation) actions.Annotation {
+	level := actions.LevelWarning
+	if annotation.Level == github.AnnotationLevelFailure {
+		level = actions.LevelError
+	}
+
+	title := annotation.check
+	if annotation.Title != "" {
+		title += ": " + annotation.Title
+	}
+
+	return actions.Annotation{
+		Level:   level,
+		File:    annotation.Path,
+		Line:    annotation.StartLine,
+		EndLine: annotation.EndLine,
+		Title:   title,
+		Message: annotation.Message,
+	}
+}
+
+// formatAnnotation formats an annotation like compilers do, e.g.
+// "main.go:3: failure: unused variable".
+func formatAnnotation(annotation github.CheckRunAnnotation) string {
+	location := annotation.Path
+	if annotation.StartLine != 0 {
+		location += ":" + strconv.Itoa(annotation.StartLine)
+		if annotation.EndLine > annotation.StartLine {
+			location += "-" + strconv.Itoa(annotation.EndLine)
+		}
+	}
+
+	message := annotation.Message
+	if annotation.Title != "" {
+		message = annotation.Title + ": " + message
+	}
+
+	return fmt.Sprintf("%s: %s: %s", location, annotation.Level, message)
+}
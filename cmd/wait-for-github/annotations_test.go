@@ -0,0 +1,112 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/actions"
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	lintAnnotations = []github.CheckRunAnnotation{
+		{Path: "main.go", StartLine: 3, EndLine: 3, Level: github.AnnotationLevelFailure, Message: "unused variable"},
+		{Path: "util.go", StartLine: 10, EndLine: 12, Level: github.AnnotationLevelWarning, Title: "gocyclo", Message: "too complex"},
+		{Path: "util.go", StartLine: 1, EndLine: 1, Level: github.AnnotationLevelNotice, Message: "not shown"},
+	}
+	annotatedChecks = []github.CICheckStatus{
+		github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 10, Name: "build", Status: "COMPLETED", Conclusion: "SUCCESS"}},
+		github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 20, Name: "lint", Status: "COMPLETED", Conclusion: "FAILURE"}},
+		github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 30, Name: "flaky", Status: "COMPLETED", Conclusion: "FAILURE"}},
+	}
+	checkAnnotations = map[int64][]github.CheckRunAnnotation{
+		10: {{Path: "build.go", Level: github.AnnotationLevelWarning, Message: "passed, so not shown"}},
+		20: lintAnnotations,
+		30: {{Path: "flaky_test.go", Level: github.AnnotationLevelFailure, Message: "excluded, so not shown"}},
+	}
+)
+
+func TestCIAnnotations(t *testing.T) {
+	t.Parallel()
+
+	client := &FakeCIStatusChecker{
+		status:      github.CIStatusFailed,
+		sha:         "abc123",
+		checks:      annotatedChecks,
+		annotations: checkAnnotations,
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main", excludes: []string{"flaky"}}
+
+	var out bytes.Buffer
+	require.Error(t, checkCIStatus(context.Background(), client, cfg, ciConf, &out))
+	require.Equal(t, `Annotations of failed checks:
+lint: main.go:3: failure: unused variable
+lint: util.go:10-12: warning: gocyclo: too complex
+`, out.String())
+	require.Equal(t, []string{"lint"}, cfg.summary.failedChecks)
+}
+
+func TestPRAnnotationsInActions(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeGithubClientPRCheck{
+		HeadSHA:     "abc123",
+		CIStatus:    github.CIStatusFailed,
+		Checks:      annotatedChecks,
+		Annotations: checkAnnotations,
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+		actions:         &actions.Step{},
+	}
+	prConf := &prConfig{owner: "owner", repo: "repo", pr: 1, excludes: []string{"flaky"}}
+
+	var out bytes.Buffer
+	require.Error(t, checkPRMerged(context.Background(), client, cfg, prConf, &out))
+	require.Equal(t, "::error file=main.go,line=3,endLine=3,title=lint::unused variable\n"+
+		"::warning file=util.go,line=10,endLine=12,title=lint%3A gocyclo::too complex\n", out.String())
+}
+
+func TestNoAnnotationsUnlessFailed(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeGithubClientPRCheck{
+		MergedCommit: "abc123",
+		Checks:       annotatedChecks,
+		Annotations:  checkAnnotations,
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+		actions:         &actions.Step{},
+	}
+	prConf := &prConfig{owner: "owner", repo: "repo", pr: 1}
+
+	var out bytes.Buffer
+	require.Error(t, checkPRMerged(context.Background(), client, cfg, prConf, &out))
+	require.Empty(t, out.String())
+}
@@ -0,0 +1,516 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/logging"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// The kinds of wait a manifest can declare. Apart from deployment, they match
+// the commands of the same name.
+const (
+	waitTypeCI           = "ci"
+	waitTypePR           = "pr"
+	waitTypeArtifact     = "artifact"
+	waitTypeCodeScanning = "code-scanning"
+	waitTypeDeployment   = "deployment"
+)
+
+func waitTypes() []string {
+	return []string{waitTypeCI, waitTypePR, waitTypeArtifact, waitTypeCodeScanning, waitTypeDeployment}
+}
+
+var (
+	// ${node.output}
+	outputRefRegexp = regexp.MustCompile(`\$\{([^.}]+)\.([^}]+)\}`)
+)
+
+// waitManifest is the file given to `apply`.
+type waitManifest struct {
+	Timeout time.Duration `yaml:"timeout"`
+	Waits   []waitSpec    `yaml:"waits"`
+}
+
+// waitSpec is one named condition in a manifest. Which of the fields are used
+// depends on the type, and they mirror the flags of the matching command.
+// String fields can refer to outputs of the waits this one depends on as
+// ${name.output}.
+type waitSpec struct {
+	Name      string        `yaml:"name"`
+	Type      string        `yaml:"type"`
+	DependsOn []string      `yaml:"depends_on"`
+	Timeout   time.Duration `yaml:"timeout"`
+
+	// ci, pr and code-scanning can be given a URL instead of owner, repo and
+	// ref or pr
+	URL   string `yaml:"url"`
+	Owner string `yaml:"owner"`
+	Repo  string `yaml:"repo"`
+	Ref   string `yaml:"ref"`
+	PR    string `yaml:"pr"`
+
+	// ci and pr
+	Checks        []string `yaml:"checks"`
+	Excludes      []string `yaml:"excludes"`
+	ActionRetries int      `yaml:"action_retries"`
+
+	// pr
+	IgnoreFailedCI  bool   `yaml:"ignore_failed_ci"`
+	AutoMerge       bool   `yaml:"auto_merge"`
+	AutoMergeMethod string `yaml:"auto_merge_method"`
+
+	// artifact, and sha for deployment
+	Artifact string `yaml:"artifact"`
+	SHA      string `yaml:"sha"`
+	Run      string `yaml:"run"`
+	Download string `yaml:"download"`
+
+	// deployment
+	Environment string `yaml:"environment"`
+
+	// code-scanning
+	Tools          []string `yaml:"tools"`
+	FailOnSeverity string   `yaml:"fail_on_severity"`
+}
+
+// parseWaitManifest reads and validates a manifest.
+func parseWaitManifest(r io.Reader) (*waitManifest, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var manifest waitManifest
+	if err := dec.Decode(&manifest); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("manifest is empty")
+		}
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if len(manifest.Waits) == 0 {
+		return nil, errors.New("manifest has no waits")
+	}
+
+	for _, w := range manifest.Waits {
+		if err := w.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// check for unknown dependencies and cycles before anything runs
+	nodes := make([]utils.GraphNode, 0, len(manifest.Waits))
+	for _, w := range manifest.Waits {
+		nodes = append(nodes, utils.GraphNode{Name: w.Name, DependsOn: w.DependsOn})
+	}
+	if err := utils.ValidateGraph(nodes); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+func (w *waitSpec) validate() error {
+	if w.Name == "" {
+		return errors.New("every wait must have a name")
+	}
+
+	if !slices.Contains(waitTypes(), w.Type) {
+		return fmt.Errorf("wait %q has unsupported type %q: must be one of %s", w.Name, w.Type, strings.Join(waitTypes(), ", "))
+	}
+
+	// Outputs can only come from waits which are guaranteed to have finished
+	for _, s := range w.strings() {
+		for _, match := range outputRefRegexp.FindAllStringSubmatch(*s, -1) {
+			if !slices.Contains(w.DependsOn, match[1]) {
+				return fmt.Errorf("wait %q refers to %s, but doesn't depend on %q", w.Name, match[0], match[1])
+			}
+		}
+	}
+
+	hasTarget := w.URL != "" || (w.Owner != "" && w.Repo != "")
+	switch w.Type {
+	case waitTypeCI, waitTypeCodeScanning:
+		if !hasTarget || (w.URL == "" && w.Ref == "") {
+			return fmt.Errorf("wait %q must have either url, or owner, repo and ref", w.Name)
+		}
+	case waitTypePR:
+		if !hasTarget || (w.URL == "" && w.PR == "") {
+			return fmt.Errorf("wait %q must have either url, or owner, repo and pr", w.Name)
+		}
+	case waitTypeArtifact:
+		if w.Owner == "" || w.Repo == "" || w.Artifact == "" {
+			return fmt.Errorf("wait %q must have owner, repo and artifact", w.Name)
+		}
+		if (w.SHA == "") == (w.Run == "") {
+			return fmt.Errorf("wait %q must have exactly one of sha or run", w.Name)
+		}
+	case waitTypeDeployment:
+		if w.Owner == "" || w.Repo == "" || w.SHA == "" || w.Environment == "" {
+			return fmt.Errorf("wait %q must have owner, repo, sha and environment", w.Name)
+		}
+	}
+
+	return nil
+}
+
+// strings returns pointers to the fields which outputs can be substituted
+// into.
+func (w *waitSpec) strings() []*string {
+	fields := []*string{&w.URL, &w.Owner, &w.Repo, &w.Ref, &w.PR, &w.Artifact, &w.SHA, &w.Run, &w.Download, &w.Environment}
+	for _, slice := range [][]string{w.Checks, w.Excludes, w.Tools} {
+		for i := range slice {
+			fields = append(fields, &slice[i])
+		}
+	}
+
+	return fields
+}
+
+// expand returns a copy of the wait with references to outputs replaced.
+func (w waitSpec) expand(inputs map[string]map[string]string) (waitSpec, error) {
+	w.Checks = slices.Clone(w.Checks)
+	w.Excludes = slices.Clone(w.Excludes)
+	w.Tools = slices.Clone(w.Tools)
+
+	var errs []error
+	for _, s := range w.strings() {
+		*s = outputRefRegexp.ReplaceAllStringFunc(*s, func(ref string) string {
+			match := outputRefRegexp.FindStringSubmatch(ref)
+			value, ok := inputs[match[1]][match[2]]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: %q has no output %q", ref, match[1], match[2]))
+			}
+			return value
+		})
+	}
+
+	return w, errors.Join(errs...)
+}
+
+// applyClient is everything the waits in a manifest might need
+type applyClient interface {
+	checkCIStatusWithRerun
+	checkMergedAndOverallCI
+	findAndDownloadArtifact
+	github.GetCodeScanningResults
+	github.GetDeploymentStatus
+}
+
+// newWaitCheck returns the check for an expanded wait, and a function which
+// returns its outputs once it has succeeded.
+func newWaitCheck(ctx context.Context, githubClient applyClient, cfg *config, w waitSpec) (utils.Check, func() map[string]string, error) {
+	switch w.Type {
+	case waitTypeCI, waitTypeCodeScanning:
+		owner, repo, ref := w.Owner, w.Repo, w.Ref
+		if w.URL != "" {
+			var err error
+			if owner, repo, ref, err = parseCIURL(w.URL); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		ciConf := ciConfig{
+			owner:         owner,
+			repo:          repo,
+			ref:           ref,
+			checks:        w.Checks,
+			excludes:      w.Excludes,
+			actionRetries: w.ActionRetries,
+		}
+		logger := cfg.logger.With(logging.OwnerAttr(owner), logging.RepoAttr(repo), logging.RefAttr(ref))
+		outputs := func() map[string]string { return map[string]string{"ref": ref} }
+
+		if w.Type == waitTypeCI {
+			return newCICheck(ctx, githubClient, cfg, &ciConf, logger), outputs, nil
+		}
+
+		failOnSeverity := w.FailOnSeverity
+		if failOnSeverity == "" {
+			failOnSeverity = github.SeverityHigh
+		}
+
+		return &codeScanningCheck{
+			codeScanningConfig: codeScanningConfig{
+				ciConfig:       ciConf,
+				tools:          w.Tools,
+				failOnSeverity: failOnSeverity,
+			},
+			githubClient: githubClient,
+			table:        &logTableWriter{logger: logger},
+			logger:       logger,
+		}, outputs, nil
+
+	case waitTypePR:
+		owner, repo, number := w.Owner, w.Repo, w.PR
+		if w.URL != "" {
+			if owner, repo, number = extractNumberFromPrURL(w.URL); number == "" {
+				return nil, nil, ErrInvalidPRURL{w.URL}
+			}
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, nil, fmt.Errorf("PR must be a number, got '%s'", number)
+		}
+
+		autoMergeMethod := w.AutoMergeMethod
+		if autoMergeMethod == "" {
+			autoMergeMethod = "merge"
+		}
+
+		logger := cfg.logger.With(logging.OwnerAttr(owner), logging.RepoAttr(repo), "pr", n)
+		check := newPRCheck(githubClient, cfg, &prConfig{
+			owner:           owner,
+			repo:            repo,
+			pr:              n,
+			excludes:        w.Excludes,
+			ignoreFailedCI:  w.IgnoreFailedCI,
+			actionRetries:   w.ActionRetries,
+			autoMerge:       w.AutoMerge,
+			autoMergeMethod: autoMergeMethod,
+			writer:          osFileWriter{},
+		}, logger)
+
+		return check, func() map[string]string {
+			return map[string]string{
+				"commit":    check.mergedCommit,
+				"merged_at": strconv.FormatInt(check.mergedAt, 10),
+			}
+		}, nil
+
+	case waitTypeArtifact:
+		var runID int64
+		if w.Run != "" {
+			var err error
+			if runID, err = strconv.ParseInt(w.Run, 10, 64); err != nil {
+				return nil, nil, fmt.Errorf("run must be a number, got '%s'", w.Run)
+			}
+		}
+
+		artifactConf := artifactConfig{
+			owner:       w.Owner,
+			repo:        w.Repo,
+			name:        w.Artifact,
+			sha:         w.SHA,
+			runID:       runID,
+			downloadDir: w.Download,
+		}
+		check := &artifactCheck{
+			artifactConfig: artifactConf,
+			githubClient:   githubClient,
+			logger:         cfg.logger.With(logging.OwnerAttr(w.Owner), logging.RepoAttr(w.Repo), logging.NameAttr(w.Artifact)),
+		}
+
+		return check, func() map[string]string {
+			return map[string]string{
+				"id":     strconv.FormatInt(check.found.ID, 10),
+				"run_id": strconv.FormatInt(check.found.RunID, 10),
+			}
+		}, nil
+
+	case waitTypeDeployment:
+		check := &deploymentCheck{
+			deploymentConfig: deploymentConfig{
+				owner:       w.Owner,
+				repo:        w.Repo,
+				sha:         w.SHA,
+				environment: w.Environment,
+			},
+			githubClient: githubClient,
+			logger:       cfg.logger.With(logging.OwnerAttr(w.Owner), logging.RepoAttr(w.Repo), "sha", w.SHA, "environment", w.Environment),
+		}
+
+		return check, func() map[string]string {
+			return map[string]string{
+				"id":  strconv.FormatInt(check.found.ID, 10),
+				"url": check.found.EnvironmentURL,
+			}
+		}, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported type %q", w.Type)
+}
+
+// logTableWriter logs table rows rather than printing them, so that waits in a
+// manifest which print tables on their own don't clutter the output.
+type logTableWriter struct {
+	logger  *slog.Logger
+	headers []string
+	rows    [][]string
+}
+
+func (l *logTableWriter) Header(headers ...any) {
+	if len(headers) == 1 {
+		if h, ok := headers[0].([]string); ok {
+			l.headers = h
+		}
+	}
+}
+
+func (l *logTableWriter) Bulk(data any) error {
+	switch d := data.(type) {
+	case [][]string:
+		l.rows = append(l.rows, d...)
+	case []string:
+		l.rows = append(l.rows, d)
+	}
+	return nil
+}
+
+func (l *logTableWriter) Render() error {
+	for _, row := range l.rows {
+		var attrs []any
+		for i, cell := range row {
+			key := strconv.Itoa(i)
+			if i < len(l.headers) {
+				key = l.headers[i]
+			}
+			attrs = append(attrs, key, cell)
+		}
+		l.logger.Info("result", attrs...)
+	}
+	l.rows = nil
+
+	return nil
+}
+
+// applyManifest runs the waits in a manifest, and reports the state of each.
+func applyManifest(ctx context.Context, githubClient applyClient, cfg *config, manifest *waitManifest, table tableWriter) error {
+	if manifest.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, manifest.Timeout)
+		defer cancel()
+	}
+
+	nodes := make([]utils.GraphNode, 0, len(manifest.Waits))
+	for _, w := range manifest.Waits {
+		nodes = append(nodes, utils.GraphNode{
+			Name:      w.Name,
+			DependsOn: w.DependsOn,
+			Timeout:   w.Timeout,
+			Run: func(ctx context.Context, inputs map[string]map[string]string) (map[string]string, error) {
+				expanded, err := w.expand(inputs)
+				if err != nil {
+					return nil, err
+				}
+
+				check, outputs, err := newWaitCheck(ctx, githubClient, cfg, expanded)
+				if err != nil {
+					return nil, err
+				}
+
+				err = utils.RunUntilCancelledOrTimeout(ctx, cfg.logger.With("node", w.Name), check, cfg.pollStrategy())
+
+				var exitErr cli.ExitCoder
+				if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+					return outputs(), err
+				}
+
+				return nil, err
+			},
+		})
+	}
+
+	results, exitErr := utils.RunGraph(ctx, cfg.logger, nodes)
+	if results == nil {
+		return exitErr
+	}
+
+	if err := renderGraphResults(results, table); err != nil {
+		return err
+	}
+
+	return exitErr
+}
+
+// renderGraphResults writes a table with the state of each wait.
+func renderGraphResults(results []utils.GraphNodeResult, table tableWriter) error {
+	table.Header([]string{"Wait", "State", "Duration", "Message"})
+
+	var data [][]string
+	for _, result := range results {
+		duration := ""
+		if result.Duration > 0 {
+			duration = result.Duration.Round(time.Second).String()
+		}
+
+		data = append(data, []string{result.Name, result.State, duration, result.Message})
+	}
+
+	if err := table.Bulk(data); err != nil {
+		return fmt.Errorf("failed to write table: %w", err)
+	}
+
+	return table.Render()
+}
+
+func applyCommand(cfg *config) *cli.Command {
+	return &cli.Command{
+		Name:      "apply",
+		Usage:     "Wait for the conditions declared in a manifest, in dependency order",
+		ArgsUsage: "<manifest.yaml>",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.NArg() != 1 {
+				lineage := cmd.Lineage()
+				parent := lineage[1]
+				if err := cli.ShowCommandHelp(ctx, parent, "apply"); err != nil {
+					return err
+				}
+
+				return cli.Exit("invalid number of arguments", utils.ExitUsage)
+			}
+
+			contents, err := os.ReadFile(cmd.Args().Get(0))
+			if err != nil {
+				return fmt.Errorf("failed to read manifest: %w", err)
+			}
+
+			manifest, err := parseWaitManifest(bytes.NewReader(contents))
+			if err != nil {
+				return cli.Exit(err.Error(), utils.ExitUsage)
+			}
+
+			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo)
+			if err != nil {
+				return err
+			}
+
+			table, err := newTableWriter(os.Stdout)
+			if err != nil {
+				return err
+			}
+
+			return applyManifest(ctx, githubClient, cfg, manifest, table)
+		},
+	}
+}
@@ -0,0 +1,320 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// fakeApplyClient implements the applyClient interface
+type fakeApplyClient struct {
+	*fakeGithubClientPRCheck
+	*fakeArtifactClient
+	*fakeCodeScanningClient
+	*fakeDeploymentClient
+
+	mu           sync.Mutex
+	ciStatusRefs []string
+}
+
+func (f *fakeApplyClient) GetCIStatus(ctx context.Context, owner, repo string, commitHash string, excludes []string) (github.CIStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ciStatusRefs = append(f.ciStatusRefs, commitHash)
+	return f.CIStatus, nil
+}
+
+func (f *fakeApplyClient) GetCIStatusForChecks(ctx context.Context, owner, repo string, commitHash string, checkNames []string) (github.CIStatus, []string, error) {
+	return f.CIStatus, checkNames, nil
+}
+
+func (f *fakeApplyClient) GetDetailedCIStatus(ctx context.Context, owner, repo string, commitHash string) ([]github.CICheckStatus, error) {
+	return nil, nil
+}
+
+func TestParseWaitManifest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name: "valid",
+			manifest: `
+timeout: 1h
+waits:
+  - name: merge
+    type: pr
+    url: https://github.com/owner/repo/pull/1
+    timeout: 30m
+  - name: ci
+    type: ci
+    depends_on: [merge]
+    owner: owner
+    repo: repo
+    ref: ${merge.commit}
+`,
+		},
+		{
+			name:     "empty",
+			manifest: ``,
+			wantErr:  "manifest is empty",
+		},
+		{
+			name:     "no waits",
+			manifest: `timeout: 1h`,
+			wantErr:  "manifest has no waits",
+		},
+		{
+			name: "unknown field",
+			manifest: `
+waits:
+  - name: ci
+    type: ci
+    url: https://github.com/owner/repo/commit/abc123
+    chekcs: [build]
+`,
+			wantErr: "field chekcs not found",
+		},
+		{
+			name: "unsupported type",
+			manifest: `
+waits:
+  - name: release
+    type: release
+`,
+			wantErr: `wait "release" has unsupported type "release": must be one of ci, pr, artifact, code-scanning, deployment`,
+		},
+		{
+			name: "deployment without an environment",
+			manifest: `
+waits:
+  - name: deploy
+    type: deployment
+    owner: owner
+    repo: repo
+    sha: abc123
+`,
+			wantErr: `wait "deploy" must have owner, repo, sha and environment`,
+		},
+		{
+			name: "missing target",
+			manifest: `
+waits:
+  - name: ci
+    type: ci
+    owner: owner
+    repo: repo
+`,
+			wantErr: `wait "ci" must have either url, or owner, repo and ref`,
+		},
+		{
+			name: "output of a wait which isn't a dependency",
+			manifest: `
+waits:
+  - name: merge
+    type: pr
+    url: https://github.com/owner/repo/pull/1
+  - name: ci
+    type: ci
+    owner: owner
+    repo: repo
+    ref: ${merge.commit}
+`,
+			wantErr: `wait "ci" refers to ${merge.commit}, but doesn't depend on "merge"`,
+		},
+		{
+			name: "unknown dependency",
+			manifest: `
+waits:
+  - name: ci
+    type: ci
+    url: https://github.com/owner/repo/commit/abc123
+    depends_on: [merge]
+`,
+			wantErr: `node "ci" depends on unknown node "merge"`,
+		},
+		{
+			name: "cycle",
+			manifest: `
+waits:
+  - name: a
+    type: ci
+    url: https://github.com/owner/repo/commit/abc123
+    depends_on: [b]
+  - name: b
+    type: ci
+    url: https://github.com/owner/repo/commit/abc123
+    depends_on: [a]
+`,
+			wantErr: "dependency cycle: a -> b -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			manifest, err := parseWaitManifest(strings.NewReader(tt.manifest))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, time.Hour, manifest.Timeout)
+			require.Len(t, manifest.Waits, 2)
+			require.Equal(t, 30*time.Minute, manifest.Waits[0].Timeout)
+			require.Equal(t, []string{"merge"}, manifest.Waits[1].DependsOn)
+		})
+	}
+}
+
+func TestWaitSpecExpand(t *testing.T) {
+	t.Parallel()
+
+	w := waitSpec{
+		Ref:    "${merge.commit}",
+		Checks: []string{"build-${merge.commit}"},
+	}
+
+	expanded, err := w.expand(map[string]map[string]string{"merge": {"commit": "abc123"}})
+	require.NoError(t, err)
+	require.Equal(t, "abc123", expanded.Ref)
+	require.Equal(t, []string{"build-abc123"}, expanded.Checks)
+	require.Equal(t, []string{"build-${merge.commit}"}, w.Checks, "the original should be unchanged")
+
+	_, err = w.expand(map[string]map[string]string{"merge": {}})
+	require.EqualError(t, err, `${merge.commit}: "merge" has no output "commit"`+"\n"+`${merge.commit}: "merge" has no output "commit"`)
+}
+
+func TestApplyManifest(t *testing.T) {
+	t.Parallel()
+
+	manifest, err := parseWaitManifest(strings.NewReader(`
+waits:
+  - name: merge
+    type: pr
+    url: https://github.com/owner/repo/pull/1
+  - name: ci
+    type: ci
+    depends_on: [merge]
+    owner: owner
+    repo: repo
+    ref: ${merge.commit}
+  - name: artifact
+    type: artifact
+    owner: owner
+    repo: repo
+    artifact: coverage
+    sha: ${merge.commit}
+    depends_on: [merge, ci]
+  - name: deploy
+    type: deployment
+    owner: owner
+    repo: repo
+    sha: ${merge.commit}
+    environment: production
+    depends_on: [merge]
+`))
+	require.NoError(t, err)
+
+	client := &fakeApplyClient{
+		fakeGithubClientPRCheck: &fakeGithubClientPRCheck{MergedCommit: "abc123", CIStatus: github.CIStatusPassed},
+		fakeArtifactClient:      &fakeArtifactClient{artifact: &github.Artifact{ID: 1, Name: "coverage", RunID: 2}},
+		fakeCodeScanningClient:  &fakeCodeScanningClient{},
+		fakeDeploymentClient: &fakeDeploymentClient{
+			deployment: &github.Deployment{ID: 3, Environment: "production", State: github.DeploymentStateSuccess},
+		},
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+
+	table := &mockTableWriter{}
+	err = applyManifest(context.Background(), client, cfg, manifest, table)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 0, exitErr.ExitCode())
+
+	require.Equal(t, []string{"abc123"}, client.ciStatusRefs)
+	require.Equal(t, 1, client.findForCommitCalls)
+	require.Equal(t, "abc123", client.sha)
+	require.Equal(t, "production", client.environment)
+
+	require.Equal(t, []string{"Wait", "State", "Duration", "Message"}, table.headers)
+	require.Len(t, table.rows, 4)
+	for _, row := range table.rows {
+		require.Equal(t, utils.NodeSucceeded, row[1], "wait %s", row[0])
+	}
+}
+
+func TestApplyManifestSkipsDependents(t *testing.T) {
+	t.Parallel()
+
+	manifest, err := parseWaitManifest(strings.NewReader(`
+waits:
+  - name: merge
+    type: pr
+    url: https://github.com/owner/repo/pull/1
+  - name: ci
+    type: ci
+    depends_on: [merge]
+    owner: owner
+    repo: repo
+    ref: ${merge.commit}
+`))
+	require.NoError(t, err)
+
+	client := &fakeApplyClient{
+		fakeGithubClientPRCheck: &fakeGithubClientPRCheck{Closed: true},
+		fakeArtifactClient:      &fakeArtifactClient{},
+		fakeCodeScanningClient:  &fakeCodeScanningClient{},
+		fakeDeploymentClient:    &fakeDeploymentClient{},
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+
+	table := &mockTableWriter{}
+	err = applyManifest(context.Background(), client, cfg, manifest, table)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 1, exitErr.ExitCode())
+	require.Empty(t, client.ciStatusRefs)
+
+	require.Equal(t, [][]string{
+		{"merge", utils.NodeFailed, table.rows[0][2], "PR is closed"},
+		{"ci", utils.NodeSkipped, "", "merge failed"},
+	}, table.rows)
+}
@@ -0,0 +1,211 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/logging"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/urfave/cli/v3"
+)
+
+type artifactConfig struct {
+	owner string
+	repo  string
+
+	name        string
+	sha         string
+	runID       int64
+	downloadDir string
+}
+
+func parseArtifactArguments(ctx context.Context, cmd *cli.Command) (artifactConfig, error) {
+	if cmd.NArg() != 2 {
+		// See parseCIArguments for why we go through the parent command.
+		lineage := cmd.Lineage()
+		parent := lineage[1]
+		err := cli.ShowCommandHelp(ctx, parent, "artifact")
+		if err != nil {
+			return artifactConfig{}, err
+		}
+
+		return artifactConfig{}, cli.Exit("invalid number of arguments", utils.ExitUsage)
+	}
+
+	sha := cmd.String("sha")
+	runID := cmd.Int64("run")
+
+	if (sha == "") == (runID == 0) {
+		return artifactConfig{}, cli.Exit("exactly one of --sha or --run must be provided", utils.ExitUsage)
+	}
+
+	return artifactConfig{
+		owner:       cmd.Args().Get(0),
+		repo:        cmd.Args().Get(1),
+		name:        cmd.String("name"),
+		sha:         sha,
+		runID:       runID,
+		downloadDir: cmd.String("download"),
+	}, nil
+}
+
+type findAndDownloadArtifact interface {
+	github.FindArtifact
+	github.DownloadArtifact
+}
+
+type artifactCheck struct {
+	artifactConfig
+	githubClient findAndDownloadArtifact
+	logger       *slog.Logger
+
+	// set once the artifact is found
+	found *github.Artifact
+}
+
+func (a *artifactCheck) find(ctx context.Context) (*github.Artifact, bool, error) {
+	if a.runID != 0 {
+		return a.githubClient.FindArtifactForRun(ctx, a.owner, a.repo, a.runID, a.name)
+	}
+
+	return a.githubClient.FindArtifactForCommit(ctx, a.owner, a.repo, a.sha, a.name)
+}
+
+func (a *artifactCheck) Check(ctx context.Context) error {
+	artifact, pending, err := a.find(ctx)
+	if err != nil {
+		return err
+	}
+
+	if artifact == nil {
+		if !pending {
+			return cli.Exit(fmt.Sprintf("Artifact %s was not uploaded by any workflow run", a.name), utils.ExitFailed)
+		}
+
+		a.logger.InfoContext(ctx, "artifact not uploaded yet")
+		return nil
+	}
+
+	logger := a.logger.With("artifact_id", artifact.ID, "run_id", artifact.RunID)
+
+	if artifact.Expired {
+		return cli.Exit(fmt.Sprintf("Artifact %s has expired", a.name), utils.ExitFailed)
+	}
+
+	logger.InfoContext(ctx, "artifact found", "size_in_bytes", artifact.SizeInBytes)
+
+	if a.downloadDir != "" {
+		logger.InfoContext(ctx, "downloading artifact", "dir", a.downloadDir)
+		if err := a.download(ctx, artifact); err != nil {
+			return err
+		}
+	}
+
+	a.found = artifact
+	return cli.Exit("Artifact found", utils.ExitSuccess)
+}
+
+// download fetches the artifact's zip archive into a temporary file, and
+// extracts it into the download directory.
+func (a *artifactCheck) download(ctx context.Context, artifact *github.Artifact) error {
+	archive, err := a.githubClient.DownloadArtifact(ctx, a.owner, a.repo, artifact.ID)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	tmp, err := os.CreateTemp("", "wait-for-github-artifact-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, archive); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to download artifact: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+
+	if err := utils.ExtractZip(tmp.Name(), a.downloadDir); err != nil {
+		return fmt.Errorf("failed to extract artifact: %w", err)
+	}
+
+	return nil
+}
+
+func waitForArtifact(timeoutCtx context.Context, githubClient findAndDownloadArtifact, cfg *config, artifactConf *artifactConfig) error {
+	logger := cfg.logger.With(logging.OwnerAttr(artifactConf.owner), logging.RepoAttr(artifactConf.repo), logging.NameAttr(artifactConf.name))
+	logger.InfoContext(timeoutCtx, "waiting for artifact", "sha", artifactConf.sha, "run_id", artifactConf.runID)
+
+	check := &artifactCheck{
+		artifactConfig: *artifactConf,
+		githubClient:   githubClient,
+		logger:         logger,
+	}
+
+	return utils.RunUntilCancelledOrTimeout(timeoutCtx, cfg.logger, check, cfg.pollStrategy())
+}
+
+func artifactCommand(cfg *config) *cli.Command {
+	return &cli.Command{
+		Name:      "artifact",
+		Usage:     "Wait for a GitHub Actions artifact to be uploaded, and optionally download it",
+		ArgsUsage: "<owner> <repo>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "name",
+				Usage:    "Name of the artifact to wait for",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "sha",
+				Usage: "Commit SHA whose workflow runs should upload the artifact. Mutually exclusive with --run.",
+			},
+			&cli.Int64Flag{
+				Name:  "run",
+				Usage: "ID of the workflow run which should upload the artifact. Mutually exclusive with --sha.",
+			},
+			&cli.StringFlag{
+				Name: "download",
+				Usage: "Directory to download and extract the artifact into. " +
+					"By default, the artifact is not downloaded.",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			artifactConf, err := parseArtifactArguments(ctx, cmd)
+			if err != nil {
+				return err
+			}
+
+			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo)
+			if err != nil {
+				return err
+			}
+
+			return waitForArtifact(ctx, githubClient, cfg, &artifactConf)
+		},
+	}
+}
@@ -0,0 +1,308 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// fakeArtifactClient implements the findAndDownloadArtifact interface
+type fakeArtifactClient struct {
+	artifact *github.Artifact
+	pending  bool
+	err      error
+
+	archive     []byte
+	downloadErr error
+
+	findForCommitCalls int
+	findForRunCalls    int
+	downloadCalls      int
+}
+
+func (f *fakeArtifactClient) FindArtifactForCommit(ctx context.Context, owner, repo, commitHash, name string) (*github.Artifact, bool, error) {
+	f.findForCommitCalls++
+	return f.artifact, f.pending, f.err
+}
+
+func (f *fakeArtifactClient) FindArtifactForRun(ctx context.Context, owner, repo string, runID int64, name string) (*github.Artifact, bool, error) {
+	f.findForRunCalls++
+	return f.artifact, f.pending, f.err
+}
+
+func (f *fakeArtifactClient) DownloadArtifact(ctx context.Context, owner, repo string, artifactID int64) (io.ReadCloser, error) {
+	f.downloadCalls++
+	if f.downloadErr != nil {
+		return nil, f.downloadErr
+	}
+	return io.NopCloser(bytes.NewReader(f.archive)), nil
+}
+
+func testZipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, contents := range files {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestArtifactCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		client           fakeArtifactClient
+		runID            int64
+		expectedExitCode *int
+		expectErr        bool
+		expectRunLookup  bool
+	}{
+		{
+			name: "artifact found",
+			client: fakeArtifactClient{
+				artifact: &github.Artifact{ID: 1, Name: "coverage", RunID: 2},
+			},
+			expectedExitCode: &zero,
+		},
+		{
+			name: "artifact found for run",
+			client: fakeArtifactClient{
+				artifact: &github.Artifact{ID: 1, Name: "coverage", RunID: 2},
+			},
+			runID:            2,
+			expectedExitCode: &zero,
+			expectRunLookup:  true,
+		},
+		{
+			name: "artifact not found, runs pending",
+			client: fakeArtifactClient{
+				pending: true,
+			},
+		},
+		{
+			name:             "artifact not found, runs complete",
+			client:           fakeArtifactClient{},
+			expectedExitCode: &one,
+		},
+		{
+			name: "artifact expired",
+			client: fakeArtifactClient{
+				artifact: &github.Artifact{ID: 1, Name: "coverage", RunID: 2, Expired: true},
+			},
+			expectedExitCode: &one,
+		},
+		{
+			name: "error finding artifact",
+			client: fakeArtifactClient{
+				err: fmt.Errorf("an error occurred"),
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			check := &artifactCheck{
+				artifactConfig: artifactConfig{
+					owner: "owner",
+					repo:  "repo",
+					name:  "coverage",
+					sha:   "abc123",
+					runID: tt.runID,
+				},
+				githubClient: &tt.client,
+				logger:       testLogger,
+			}
+
+			err := check.Check(context.Background())
+
+			switch {
+			case tt.expectErr:
+				require.Error(t, err)
+			case tt.expectedExitCode != nil:
+				var exitErr cli.ExitCoder
+				require.ErrorAs(t, err, &exitErr)
+				require.Equal(t, *tt.expectedExitCode, exitErr.ExitCode())
+			default:
+				require.NoError(t, err)
+			}
+
+			if tt.expectRunLookup {
+				require.Equal(t, 1, tt.client.findForRunCalls)
+				require.Equal(t, 0, tt.client.findForCommitCalls)
+			} else {
+				require.Equal(t, 0, tt.client.findForRunCalls)
+				require.Equal(t, 1, tt.client.findForCommitCalls)
+			}
+			require.Equal(t, 0, tt.client.downloadCalls, "should not download without --download")
+		})
+	}
+}
+
+func TestArtifactCheckDownload(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "coverage")
+	client := &fakeArtifactClient{
+		artifact: &github.Artifact{ID: 1, Name: "coverage", RunID: 2},
+		archive:  testZipArchive(t, map[string]string{"coverage.txt": "mode: set"}),
+	}
+
+	check := &artifactCheck{
+		artifactConfig: artifactConfig{
+			owner:       "owner",
+			repo:        "repo",
+			name:        "coverage",
+			sha:         "abc123",
+			downloadDir: dir,
+		},
+		githubClient: client,
+		logger:       testLogger,
+	}
+
+	err := check.Check(context.Background())
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 0, exitErr.ExitCode())
+	require.Equal(t, 1, client.downloadCalls)
+
+	contents, err := os.ReadFile(filepath.Join(dir, "coverage.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "mode: set", string(contents))
+}
+
+func TestArtifactCheckDownloadError(t *testing.T) {
+	t.Parallel()
+
+	check := &artifactCheck{
+		artifactConfig: artifactConfig{
+			owner:       "owner",
+			repo:        "repo",
+			name:        "coverage",
+			sha:         "abc123",
+			downloadDir: t.TempDir(),
+		},
+		githubClient: &fakeArtifactClient{
+			artifact:    &github.Artifact{ID: 1, Name: "coverage", RunID: 2},
+			downloadErr: fmt.Errorf("download failed"),
+		},
+		logger: testLogger,
+	}
+
+	err := check.Check(context.Background())
+	require.ErrorContains(t, err, "download failed")
+}
+
+func TestParseArtifactArguments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    artifactConfig
+		wantErr string
+	}{
+		{
+			name: "sha",
+			args: []string{"--name", "coverage", "--sha", "abc123", "owner", "repo"},
+			want: artifactConfig{
+				owner: "owner",
+				repo:  "repo",
+				name:  "coverage",
+				sha:   "abc123",
+			},
+		},
+		{
+			name: "run with download",
+			args: []string{"--name", "coverage", "--run", "42", "--download", "out", "owner", "repo"},
+			want: artifactConfig{
+				owner:       "owner",
+				repo:        "repo",
+				name:        "coverage",
+				runID:       42,
+				downloadDir: "out",
+			},
+		},
+		{
+			name:    "neither sha nor run",
+			args:    []string{"--name", "coverage", "owner", "repo"},
+			wantErr: "exactly one of --sha or --run must be provided",
+		},
+		{
+			name:    "both sha and run",
+			args:    []string{"--name", "coverage", "--sha", "abc123", "--run", "42", "owner", "repo"},
+			wantErr: "exactly one of --sha or --run must be provided",
+		},
+		{
+			name:    "invalid number of arguments",
+			args:    []string{"--name", "coverage", "--sha", "abc123", "owner"},
+			wantErr: "invalid number of arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg config
+			artifactCmd := artifactCommand(&cfg)
+			artifactCmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+				got, err := parseArtifactArguments(ctx, cmd)
+				if tt.wantErr != "" {
+					require.EqualError(t, err, tt.wantErr)
+					return nil
+				}
+
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+				return nil
+			}
+
+			rootCmd := &cli.Command{
+				Name:      "root",
+				Commands:  []*cli.Command{artifactCmd},
+				Writer:    io.Discard,
+				ErrWriter: io.Discard,
+			}
+
+			args := append([]string{"root", "artifact"}, tt.args...)
+			require.NoError(t, rootCmd.Run(t.Context(), args))
+		})
+	}
+}
@@ -18,9 +18,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/grafana/wait-for-github/internal/github"
 	"github.com/grafana/wait-for-github/internal/logging"
@@ -39,6 +44,17 @@ type ciConfig struct {
 	checks        []string
 	excludes      []string
 	actionRetries int
+	resultFile    string
+	writer        fileWriter
+	// failureLogLines is how many lines of the logs of failed jobs to show,
+	// or 0 to not show them.
+	failureLogLines int
+	// commentPR is the PR to comment the result on, or 0 to not comment.
+	commentPR int
+	// reportAs is the name of the gate to report the result as, if any, and
+	// reportAsType whether it's a check run or a commit status.
+	reportAs     string
+	reportAsType string
 }
 
 var (
@@ -86,17 +102,10 @@ func parseCIArguments(ctx context.Context, cmd *cli.Command, logger *slog.Logger
 	switch nArgs := cmd.NArg(); nArgs {
 	// If a single argument is provided, it is expected to be either a commit URL or PR URL
 	case 1:
-		url := args.Get(0)
-		// Try for a PR URL
-		owner, repo, ref = extractRefFromPrURL(url)
-		if len(ref) == 0 {
-			// Try for a commit URL
-			owner, repo, ref = extractRefFromCommitURL(url)
-		}
-
-		// Neither URL parsed
-		if len(ref) == 0 {
-			return ciConfig{}, ErrInvalidURL{url}
+		var err error
+		owner, repo, ref, err = parseCIURL(args.Get(0))
+		if err != nil {
+			return ciConfig{}, err
 		}
 
 	// If three arguments are provided, they are expected to be owner, repo, and ref
@@ -116,17 +125,110 @@ func parseCIArguments(ctx context.Context, cmd *cli.Command, logger *slog.Logger
 			return ciConfig{}, err
 		}
 
-		return ciConfig{}, cli.Exit("invalid number of arguments", 1)
+		return ciConfig{}, cli.Exit("invalid number of arguments", utils.ExitUsage)
+	}
+
+	return newCIConfig(cmd, owner, repo, ref), nil
+}
+
+// parseCIURL parses a commit or PR URL.
+func parseCIURL(url string) (owner, repo, ref string, err error) {
+	// Try for a PR URL
+	owner, repo, ref = extractRefFromPrURL(url)
+	if len(ref) == 0 {
+		// Try for a commit URL
+		owner, repo, ref = extractRefFromCommitURL(url)
+	}
+
+	// Neither URL parsed
+	if len(ref) == 0 {
+		return "", "", "", ErrInvalidURL{url}
+	}
+
+	return owner, repo, ref, nil
+}
+
+// name returns how the commit is referred to in results and notifications.
+func (c ciConfig) name() string {
+	return fmt.Sprintf("%s/%s@%s", c.owner, c.repo, c.ref)
+}
+
+func newCIConfig(cmd *cli.Command, owner, repo, ref string) ciConfig {
+	// not all commands which wait for CI have --show-failure-logs
+	failureLogLines, _ := cmd.Value("show-failure-logs").(int)
+
+	commentPR := 0
+	if cmd.Bool("comment") {
+		commentPR = prFromRef(ref)
+	}
+
+	// the gate is pending until the wait is over, so it mustn't be waited for
+	excludes := cmd.StringSlice("exclude")
+	reportAs, reportAsType := cmd.String("report-as"), ""
+	if reportAs != "" {
+		excludes = append(slices.Clone(excludes), reportAs)
+		reportAsType = cmd.String("report-as-type")
 	}
 
 	return ciConfig{
-		owner:         owner,
-		repo:          repo,
-		ref:           ref,
-		checks:        cmd.StringSlice("check"),
-		excludes:      cmd.StringSlice("exclude"),
-		actionRetries: cmd.Int("action-retries"),
-	}, nil
+		owner:           owner,
+		repo:            repo,
+		ref:             ref,
+		checks:          cmd.StringSlice("check"),
+		excludes:        excludes,
+		actionRetries:   cmd.Int("action-retries"),
+		resultFile:      cmd.String("result-file"),
+		writer:          osFileWriter{},
+		failureLogLines: failureLogLines,
+		commentPR:       commentPR,
+		reportAs:        reportAs,
+		reportAsType:    reportAsType,
+	}
+}
+
+// parseCITargets returns a ciConfig for each commit or PR to wait for.
+func parseCITargets(ctx context.Context, cmd *cli.Command, logger *slog.Logger) ([]ciConfig, error) {
+	urls := targetURLs(cmd)
+	if urls == nil {
+		ciConf, err := parseCIArguments(ctx, cmd, logger, "ci")
+		if err != nil {
+			return nil, err
+		}
+
+		if cmd.Bool("comment") && ciConf.commentPR == 0 {
+			return nil, cli.Exit("--comment can only be used when waiting for CI on a PR", utils.ExitUsage)
+		}
+
+		return []ciConfig{ciConf}, nil
+	}
+
+	if cmd.String("result-file") != "" {
+		return nil, cli.Exit("--result-file can only be used when waiting for a single commit", utils.ExitUsage)
+	}
+
+	if cmd.IsSet("show-failure-logs") {
+		return nil, cli.Exit("--show-failure-logs can only be used when waiting for a single commit", utils.ExitUsage)
+	}
+
+	if cmd.Bool("comment") {
+		return nil, cli.Exit("--comment can only be used when waiting for a single commit", utils.ExitUsage)
+	}
+
+	if cmd.String("report-as") != "" {
+		return nil, cli.Exit("--report-as can only be used when waiting for a single commit", utils.ExitUsage)
+	}
+
+	ciConfs := make([]ciConfig, 0, len(urls))
+	for _, url := range urls {
+		owner, repo, ref, err := parseCIURL(url)
+		if err != nil {
+			return nil, err
+		}
+
+		ciConfs = append(ciConfs, newCIConfig(cmd, owner, repo, ref))
+	}
+
+	return ciConfs, nil
 }
 
 type checkCIStatusWithRerun interface {
@@ -134,15 +236,21 @@ type checkCIStatusWithRerun interface {
 	github.RerunFailedWorkflows
 }
 
+type waitForCIClient interface {
+	checkCIStatusWithRerun
+	ciResultClient
+	github.ReportGate
+}
+
 func handleCIStatus(logger *slog.Logger, status github.CIStatus, url string) cli.ExitCoder {
 	switch status {
 	case github.CIStatusUnknown:
 		logger.Info("CI status is unknown")
 	case github.CIStatusPending:
 	case github.CIStatusPassed:
-		return cli.Exit("CI successful", 0)
+		return cli.Exit("CI successful", utils.ExitSuccess)
 	case github.CIStatusFailed:
-		return cli.Exit(fmt.Sprintf("CI failed. Please check CI on the following commit: %s", url), 1)
+		return cli.Exit(fmt.Sprintf("CI failed. Please check CI on the following commit: %s", url), utils.ExitFailed)
 	}
 
 	logger.Info("CI is not finished yet")
@@ -158,6 +266,26 @@ type checkAllCI struct {
 	logger        *slog.Logger
 	actionRetries int
 	retriesDone   int
+	checksGrace   utils.ChecksGracePeriod
+
+	// the last overall status seen
+	status github.CIStatus
+}
+
+// ciOutcome is where waiting for CI got to.
+type ciOutcome struct {
+	status github.CIStatus
+	reruns int
+}
+
+// ciCheck is a check which waits for CI, and knows how far it got.
+type ciCheck interface {
+	utils.Check
+	outcome() ciOutcome
+}
+
+func (ci *checkAllCI) outcome() ciOutcome {
+	return ciOutcome{status: ci.status, reruns: ci.retriesDone}
 }
 
 func (ci *checkAllCI) Check(ctx context.Context) error {
@@ -166,10 +294,15 @@ func (ci *checkAllCI) Check(ctx context.Context) error {
 		return err
 	}
 
+	status = ci.checksGrace.Resolve(ctx, ci.logger, status)
+	ci.status = status
+
 	if status == github.CIStatusFailed {
 		var shouldContinue bool
 		shouldContinue, ci.retriesDone = utils.TryRerunFailedWorkflows(ctx, ci.githubClient, ci.logger, ci.owner, ci.repo, ci.ref, ci.actionRetries, ci.retriesDone)
 		if shouldContinue {
+			// not failed until the reruns have finished
+			ci.status = github.CIStatusPending
 			return nil
 		}
 	}
@@ -188,12 +321,15 @@ func (ci *checkSpecificCI) Check(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	ci.status = status
 
 	if status == github.CIStatusFailed {
 		ci.logger.InfoContext(ctx, "CI check failed, not waiting for other checks", "failed_checks", strings.Join(interestingChecks, ", "))
 		var shouldContinue bool
 		shouldContinue, ci.retriesDone = utils.TryRerunFailedWorkflows(ctx, ci.githubClient, ci.logger, ci.owner, ci.repo, ci.ref, ci.actionRetries, ci.retriesDone)
 		if shouldContinue {
+			// not failed until the reruns have finished
+			ci.status = github.CIStatusPending
 			return nil
 		}
 	}
@@ -207,55 +343,142 @@ func (ci *checkSpecificCI) Check(ctx context.Context) error {
 	return handleCIStatus(ci.logger, status, urlFor(ci.owner, ci.repo, ci.ref))
 }
 
-func checkCIStatus(timeoutCtx context.Context, githubClient checkCIStatusWithRerun, cfg *config, ciConf *ciConfig) error {
-	logger := cfg.logger.With(logging.OwnerAttr(ciConf.owner), logging.RepoAttr(ciConf.repo), logging.RefAttr(ciConf.ref))
-	logger.InfoContext(timeoutCtx, "checking CI status")
-
+// newCICheck returns the check to wait for CI on a commit with.
+func newCICheck(ctx context.Context, githubClient checkCIStatusWithRerun, cfg *config, ciConf *ciConfig, logger *slog.Logger) ciCheck {
 	all := &checkAllCI{
 		githubClient:  githubClient,
 		owner:         ciConf.owner,
 		repo:          ciConf.repo,
 		ref:           ciConf.ref,
 		excludes:      ciConf.excludes,
-		logger:        cfg.logger,
+		logger:        logger,
 		actionRetries: ciConf.actionRetries,
+		status:        github.CIStatusPending,
+		checksGrace: utils.ChecksGracePeriod{
+			Period:        cfg.checksGracePeriod,
+			RequireChecks: cfg.requireChecks,
+		},
 	}
 
-	specific := &checkSpecificCI{
-		checkAllCI: all,
-		checks:     ciConf.checks,
+	if len(ciConf.checks) > 0 {
+		logger.InfoContext(ctx, "checking CI status for checks", "checks", strings.Join(ciConf.checks, ", "))
+		return &checkSpecificCI{
+			checkAllCI: all,
+			checks:     ciConf.checks,
+		}
 	}
 
-	if len(ciConf.checks) > 0 {
-		logger.InfoContext(timeoutCtx, "checking CI status for checks", "checks", strings.Join(ciConf.checks, ", "))
-		return utils.RunUntilCancelledOrTimeout(timeoutCtx, cfg.logger, specific, cfg.recheckInterval)
+	return all
+}
+
+func checkCIStatus(timeoutCtx context.Context, githubClient waitForCIClient, cfg *config, ciConf *ciConfig, out io.Writer) error {
+	logger := cfg.logger.With(logging.OwnerAttr(ciConf.owner), logging.RepoAttr(ciConf.repo), logging.RefAttr(ciConf.ref))
+	logger.InfoContext(timeoutCtx, "checking CI status")
+
+	check := newCICheck(timeoutCtx, githubClient, cfg, ciConf, logger)
+
+	var gate *ciGate
+	if ciConf.reportAs != "" {
+		sha, err := githubClient.ResolveRef(timeoutCtx, ciConf.owner, ciConf.repo, ciConf.ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve ref to report gate on: %w", err)
+		}
+
+		// report the gate before waiting, so that it shows up straight away
+		// and a lack of permission to report it is found out early
+		gate = &ciGate{client: githubClient, logger: logger, ciConf: ciConf, sha: sha}
+		if err := gate.report(timeoutCtx, github.CIStatusPending); err != nil {
+			return err
+		}
+		check = gatedCheck{ciCheck: check, gate: gate}
+	}
+
+	start := time.Now()
+	err := utils.RunUntilCancelledOrTimeout(timeoutCtx, cfg.logger, check, cfg.pollStrategy())
+
+	if gate != nil {
+		if gateErr := gate.finish(timeoutCtx, err); gateErr != nil {
+			return gateErr
+		}
+	}
+
+	// only report a result if the wait ended, not if it couldn't be carried out
+	var exitErr cli.ExitCoder
+	if (ciConf.resultFile != "" || ciConf.commentPR != 0 || cfg.actions != nil || check.outcome().status == github.CIStatusFailed) && errors.As(err, &exitErr) {
+		if reportErr := reportCIResult(timeoutCtx, githubClient, cfg, logger, out, ciConf, check.outcome(), time.Since(start)); reportErr != nil {
+			// a wait which didn't succeed keeps its exit code, so that
+			// failing to report why doesn't hide that CI failed
+			if exitErr.ExitCode() != utils.ExitSuccess {
+				logger.ErrorContext(timeoutCtx, "failed to report CI result", "error", reportErr)
+				return err
+			}
+
+			return reportErr
+		}
+	}
+
+	return err
+}
+
+// checkCIStatusForTargets waits for CI on several commits at once, and
+// reports the result for each of them.
+func checkCIStatusForTargets(timeoutCtx context.Context, githubClient checkCIStatusWithRerun, cfg *config, ciConfs []ciConfig, mode string, table tableWriter) error {
+	targets := make([]utils.Target, 0, len(ciConfs))
+	for _, ciConf := range ciConfs {
+		logger := cfg.logger.With(logging.OwnerAttr(ciConf.owner), logging.RepoAttr(ciConf.repo), logging.RefAttr(ciConf.ref))
+		logger.InfoContext(timeoutCtx, "checking CI status")
+
+		targets = append(targets, utils.Target{
+			Name:  ciConf.name(),
+			Check: newCICheck(timeoutCtx, githubClient, cfg, &ciConf, logger),
+		})
 	}
 
-	return utils.RunUntilCancelledOrTimeout(timeoutCtx, cfg.logger, all, cfg.recheckInterval)
+	results, exitErr := utils.RunTargetsUntilCancelledOrTimeout(timeoutCtx, cfg.logger, targets, cfg.pollStrategy(), mode)
+	if err := renderTargetResults(results, table); err != nil {
+		return err
+	}
+
+	return exitErr
 }
 
 func ciCommand(cfg *config) *cli.Command {
 	return &cli.Command{
 		Name:      "ci",
 		Usage:     "Wait for CI to be finished",
-		ArgsUsage: "<https://github.com/OWNER/REPO/commit|pull/HASH|PRNumber|owner> [<repo> <ref>]",
+		ArgsUsage: "<https://github.com/OWNER/REPO/commit|pull/HASH|PRNumber|owner> [<repo> <ref> | <url>...]",
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			ciConf, err := parseCIArguments(ctx, cmd, cfg.logger, "ci")
+			ciConfs, err := parseCITargets(ctx, cmd, cfg.logger)
+			if err != nil {
+				return err
+			}
+
+			names := make([]string, 0, len(ciConfs))
+			for _, ciConf := range ciConfs {
+				names = append(names, ciConf.name())
+			}
+			cfg.summary.target = strings.Join(names, ", ")
+
+			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo)
 			if err != nil {
 				return err
 			}
 
-			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo, cfg.pendingRecheckTime)
+			if len(ciConfs) == 1 {
+				return checkCIStatus(ctx, githubClient, cfg, &ciConfs[0], os.Stdout)
+			}
+
+			table, err := newTableWriter(os.Stdout)
 			if err != nil {
 				return err
 			}
 
-			return checkCIStatus(ctx, githubClient, cfg, &ciConf)
+			return checkCIStatusForTargets(ctx, githubClient, cfg, ciConfs, cmd.String("mode"), table)
 		},
 		Commands: []*cli.Command{
 			ciListCommand(cfg),
 		},
-		Flags: []cli.Flag{
+		Flags: append(targetFlags("A commit or PR URL to wait for CI on."),
 			&cli.StringSliceFlag{
 				Name: "check",
 				Aliases: []string{
@@ -286,7 +509,47 @@ func ciCommand(cfg *config) *cli.Command {
 					cli.EnvVar("GITHUB_ACTION_RETRIES"),
 				),
 			},
-		},
+			&cli.StringFlag{
+				Name:  "result-file",
+				Usage: "Path to a file to write the result of waiting to, as JSON. This includes the outcome of every check.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("GITHUB_CI_RESULT_FILE"),
+				),
+			},
+			&cli.GenericFlag{
+				Name: "show-failure-logs",
+				Usage: fmt.Sprintf("If CI fails, show the last lines of the failed step of each failed GitHub Actions job, "+
+					"and the output of other failed check runs. Optionally takes the number of lines, %d by default.", defaultFailureLogLines),
+				Value: new(logLines),
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("GITHUB_CI_SHOW_FAILURE_LOGS"),
+				),
+			},
+			commentFlag("the PR, if waiting for CI on one"),
+			&cli.StringFlag{
+				Name: "report-as",
+				Usage: "Report the result as a check run or commit status with this name on the commit, " +
+					"updated while waiting, so that branch protection can require it instead of each check. " +
+					"It's excluded from the checks waited for.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("GITHUB_CI_REPORT_AS"),
+				),
+			},
+			&cli.StringFlag{
+				Name:  "report-as-type",
+				Usage: "Whether --report-as reports a check run, which needs a GitHub App, or a commit status (check-run, status).",
+				Value: gateTypeCheckRun,
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("GITHUB_CI_REPORT_AS_TYPE"),
+				),
+				Validator: func(s string) error {
+					if !slices.Contains(gateTypes(), s) {
+						return fmt.Errorf("invalid report type %q: must be one of %s", s, strings.Join(gateTypes(), ", "))
+					}
+					return nil
+				},
+			},
+		),
 	}
 }
 
@@ -18,22 +18,44 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/grafana/wait-for-github/internal/ansi"
 	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
 	"github.com/olekukonko/tablewriter"
 	"github.com/olekukonko/tablewriter/renderer"
 	"github.com/olekukonko/tablewriter/tw"
 	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 	"golang.org/x/text/transform"
+	"gopkg.in/yaml.v3"
 )
 
+// The formats `ci list` can write checks in. Only the table has colors.
+const (
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	outputCSV      = "csv"
+	outputMarkdown = "markdown"
+)
+
+func outputFormats() []string {
+	return []string{outputTable, outputJSON, outputYAML, outputCSV, outputMarkdown}
+}
+
 type checkListConfig struct {
 	ciConfig
 	githubClient github.GetDetailedCIStatus
@@ -99,17 +121,26 @@ func listChecks(ctx context.Context, cfg *checkListConfig, table tableWriter) er
 		return nil
 	}
 
-	table.Header([]string{"Name", "Type", "Status"})
+	table.Header([]string{"Name", "Type", "Status", "Duration", "Link"})
+
+	now := time.Now()
 
 	var data [][]string
 	for _, check := range checks {
 		caser := ansi.NewANSITransformer(cases.Title(language.English))
 		checkOutcomeString, _, _ := transform.String(caser, check.Outcome().String())
 
+		duration := ""
+		if d, ok := checkElapsed(check, now); ok {
+			duration = d.Round(time.Second).String()
+		}
+
 		data = append(data, []string{
 			check.String(),
 			check.Type(),
 			checkOutcomeString,
+			duration,
+			newCheckRecord(check).DetailsURL,
 		})
 	}
 
@@ -120,32 +151,251 @@ func listChecks(ctx context.Context, cfg *checkListConfig, table tableWriter) er
 	return table.Render()
 }
 
+// checkRecord is a check in the machine-readable output formats.
+type checkRecord struct {
+	Name     string `json:"name" yaml:"name"`
+	Workflow string `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	Type     string `json:"type" yaml:"type"`
+	// Status is the overall outcome, as used to decide whether CI passed.
+	Status string `json:"status" yaml:"status"`
+	// Conclusion is the check run's conclusion, or the commit status' state,
+	// as reported by GitHub.
+	Conclusion  string     `json:"conclusion,omitempty" yaml:"conclusion,omitempty"`
+	App         string     `json:"app,omitempty" yaml:"app,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty" yaml:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" yaml:"completed_at,omitempty"`
+	DetailsURL  string     `json:"details_url,omitempty" yaml:"details_url,omitempty"`
+	DatabaseID  int64      `json:"database_id,omitempty" yaml:"database_id,omitempty"`
+	RunAttempt  int        `json:"run_attempt,omitempty" yaml:"run_attempt,omitempty"`
+}
+
+// checkRunDetails returns check as a CheckRunDetails, if it's a check run.
+func checkRunDetails(check github.CICheckStatus) (github.CheckRunDetails, bool) {
+	switch c := check.(type) {
+	case github.CheckRunDetails:
+		return c, true
+	case github.CheckRun:
+		return github.CheckRunDetails{CheckRun: c}, true
+	}
+
+	return github.CheckRunDetails{}, false
+}
+
+// checkElapsed returns how long a check has been running, or how long it took
+// if it has finished. It returns false if that isn't known.
+func checkElapsed(check github.CICheckStatus, now time.Time) (time.Duration, bool) {
+	if c, ok := checkRunDetails(check); ok {
+		if c.StartedAt == nil {
+			return 0, false
+		}
+		if c.CompletedAt != nil {
+			return c.CompletedAt.Sub(*c.StartedAt), true
+		}
+		return now.Sub(*c.StartedAt), true
+	}
+
+	if c, ok := check.(github.StatusContext); ok {
+		// statuses don't record when they finished
+		if c.CreatedAt == nil || c.Outcome() != github.CIStatusUnknown {
+			return 0, false
+		}
+		return now.Sub(*c.CreatedAt), true
+	}
+
+	return 0, false
+}
+
+func newCheckRecord(check github.CICheckStatus) checkRecord {
+	record := checkRecord{
+		Type:   check.Type(),
+		Status: check.Outcome().Name(),
+	}
+
+	if c, ok := checkRunDetails(check); ok {
+		record.Name = c.Name
+		record.Workflow = c.CheckSuite.WorkflowRun.Workflow.Name
+		record.Conclusion = strings.ToLower(c.Conclusion)
+		record.App = c.CheckSuite.App.Name
+		record.StartedAt = c.StartedAt
+		record.CompletedAt = c.CompletedAt
+		record.DetailsURL = c.DetailsURL
+		record.DatabaseID = c.DatabaseID
+		record.RunAttempt = c.RunAttempt
+
+		return record
+	}
+
+	if c, ok := check.(github.StatusContext); ok {
+		record.Name = c.Context
+		record.Conclusion = strings.ToLower(c.State)
+		record.StartedAt = c.CreatedAt
+		record.DetailsURL = c.TargetURL
+
+		return record
+	}
+
+	record.Name = check.String()
+
+	return record
+}
+
+// row returns the record's fields in the order of checkRecordColumns.
+func (r checkRecord) row() []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	formatInt := func(i int64) string {
+		if i == 0 {
+			return ""
+		}
+		return strconv.FormatInt(i, 10)
+	}
+
+	return []string{
+		r.Name,
+		r.Workflow,
+		r.Type,
+		r.Status,
+		r.Conclusion,
+		r.App,
+		formatTime(r.StartedAt),
+		formatTime(r.CompletedAt),
+		r.DetailsURL,
+		formatInt(r.DatabaseID),
+		formatInt(int64(r.RunAttempt)),
+	}
+}
+
+var checkRecordColumns = []string{"name", "workflow", "type", "status", "conclusion", "app", "started_at", "completed_at", "details_url", "database_id", "run_attempt"}
+
+// writeChecks writes the checks in one of the machine-readable formats.
+func writeChecks(ctx context.Context, cfg *checkListConfig, format string, w io.Writer) error {
+	checks, err := cfg.githubClient.GetDetailedCIStatus(ctx, cfg.owner, cfg.repo, cfg.ref)
+	if err != nil {
+		return err
+	}
+
+	records := make([]checkRecord, 0, len(checks))
+	for _, check := range checks {
+		records = append(records, newCheckRecord(check))
+	}
+
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case outputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(records); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case outputCSV:
+		writer := csv.NewWriter(w)
+		_ = writer.Write(checkRecordColumns)
+		for _, record := range records {
+			_ = writer.Write(record.row())
+		}
+		writer.Flush()
+		return writer.Error()
+	case outputMarkdown:
+		return writeMarkdownTable(w, checkRecordColumns, records)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// markdownEscaper makes text safe to put in a markdown table cell.
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+func writeMarkdownTable(w io.Writer, columns []string, records []checkRecord) error {
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(columns)) + "\n")
+	for _, record := range records {
+		cells := record.row()
+		for i, cell := range cells {
+			cells[i] = markdownEscaper.Replace(cell)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
 func ciListCommand(cfg *config) *cli.Command {
 	return &cli.Command{
 		Name:      "list",
 		Usage:     "List all CI checks and their status",
 		ArgsUsage: "<https://github.com/OWNER/REPO/commit|pull/HASH|PRNumber|owner> [<repo> <ref>]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage: "Format to list the checks in. Valid formats are: " +
+					strings.Join(outputFormats(), ", ") + ".",
+				Value: outputTable,
+				Validator: func(s string) error {
+					if !slices.Contains(outputFormats(), s) {
+						return fmt.Errorf("invalid output format %q: must be one of %s", s, strings.Join(outputFormats(), ", "))
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:    "watch",
+				Aliases: []string{"w"},
+				Usage: "Redraw the table on every recheck until CI has finished, " +
+					"and then exit like the ci command would.",
+			},
+		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			ciConf, err := parseCIArguments(ctx, cmd, cfg.logger, "list")
 			if err != nil {
 				return err
 			}
 
-			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo, cfg.pendingRecheckTime)
+			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo)
 			if err != nil {
 				return err
 			}
 
+			listConf := &checkListConfig{
+				ciConfig:     ciConf,
+				githubClient: githubClient,
+			}
+
 			w := os.Stdout
+			format := cmd.String("output")
+
+			if cmd.Bool("watch") {
+				if format != outputTable {
+					return cli.Exit("--watch can only be used with the table output", utils.ExitUsage)
+				}
+
+				// watching is a wait like any other
+				watch := waitAction(cfg, func(ctx context.Context, cmd *cli.Command) error {
+					return watchCIChecks(ctx, githubClient, cfg, &ciConf, w, term.IsTerminal(int(w.Fd())))
+				})
+				return watch(ctx, cmd)
+			}
+
+			if format != outputTable {
+				return writeChecks(ctx, listConf, format, w)
+			}
+
 			table, err := newTableWriter(w)
 			if err != nil {
 				return err
 			}
 
-			return listChecks(ctx, &checkListConfig{
-				ciConfig:     ciConf,
-				githubClient: githubClient,
-			}, table)
+			return listChecks(ctx, listConf, table)
 		},
 	}
 }
@@ -17,9 +17,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/grafana/wait-for-github/internal/github"
@@ -139,6 +141,9 @@ func TestListChecks(t *testing.T) {
 }
 
 func TestListChecks_TableRendering(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	completed := started.Add(150 * time.Second)
+
 	tests := []struct {
 		name        string
 		checks      []github.CICheckStatus
@@ -154,9 +159,9 @@ func TestListChecks_TableRendering(t *testing.T) {
 					Conclusion: "SUCCESS",
 				},
 			},
-			wantHeaders: []string{"Name", "Type", "Status"},
+			wantHeaders: []string{"Name", "Type", "Status", "Duration", "Link"},
 			wantRows: [][]string{
-				{"test", "Check Run", "Passed"},
+				{"test", "Check Run", "Passed", "", ""},
 			},
 		},
 		{
@@ -168,9 +173,9 @@ func TestListChecks_TableRendering(t *testing.T) {
 					Conclusion: "FAILURE",
 				},
 			},
-			wantHeaders: []string{"Name", "Type", "Status"},
+			wantHeaders: []string{"Name", "Type", "Status", "Duration", "Link"},
 			wantRows: [][]string{
-				{"test", "Check Run", "Failed"},
+				{"test", "Check Run", "Failed", "", ""},
 			},
 		},
 		{
@@ -184,9 +189,7 @@ func TestListChecks_TableRendering(t *testing.T) {
 						App: github.AppInfo{
 							Name: "",
 						},
-						WorkflowRun: struct {
-							Workflow github.WorkflowInfo
-						}{
+						WorkflowRun: github.WorkflowRunInfo{
 							Workflow: github.WorkflowInfo{
 								Name: "CI",
 							},
@@ -203,11 +206,11 @@ func TestListChecks_TableRendering(t *testing.T) {
 					Conclusion: "SKIPPED",
 				},
 			},
-			wantHeaders: []string{"Name", "Type", "Status"},
+			wantHeaders: []string{"Name", "Type", "Status", "Duration", "Link"},
 			wantRows: [][]string{
-				{"CI / build", "Check Run", "Passed"},
-				{"deploy", "Status", "Unknown"},
-				{"test", "Check Run", "Skipped"},
+				{"CI / build", "Check Run", "Passed", "", ""},
+				{"deploy", "Status", "Unknown", "", ""},
+				{"test", "Check Run", "Skipped", "", ""},
 			},
 		},
 		{
@@ -223,10 +226,36 @@ func TestListChecks_TableRendering(t *testing.T) {
 					State:   "",
 				},
 			},
-			wantHeaders: []string{"Name", "Type", "Status"},
+			wantHeaders: []string{"Name", "Type", "Status", "Duration", "Link"},
+			wantRows: [][]string{
+				{"", "Check Run", "Pending", "", ""},
+				{"", "Status", "Unknown", "", ""},
+			},
+		},
+		{
+			name: "durations and links",
+			checks: []github.CICheckStatus{
+				github.CheckRunDetails{
+					CheckRun: github.CheckRun{
+						Name:        "build",
+						Status:      "COMPLETED",
+						Conclusion:  "SUCCESS",
+						StartedAt:   &started,
+						CompletedAt: &completed,
+						DetailsURL:  "https://github.com/owner/repo/actions/runs/1/job/2",
+					},
+					RunAttempt: 2,
+				},
+				github.StatusContext{
+					Context:   "deploy",
+					State:     "SUCCESS",
+					TargetURL: "https://deploy.example.com/1",
+				},
+			},
+			wantHeaders: []string{"Name", "Type", "Status", "Duration", "Link"},
 			wantRows: [][]string{
-				{"", "Check Run", "Pending"},
-				{"", "Status", "Unknown"},
+				{"build", "Check Run", "Passed", "2m30s", "https://github.com/owner/repo/actions/runs/1/job/2"},
+				{"deploy", "Status", "Passed", "", "https://deploy.example.com/1"},
 			},
 		},
 		{
@@ -286,9 +315,7 @@ func TestListChecksColour(t *testing.T) {
 				App: github.AppInfo{
 					Name: "",
 				},
-				WorkflowRun: struct {
-					Workflow github.WorkflowInfo
-				}{
+				WorkflowRun: github.WorkflowRunInfo{
 					Workflow: github.WorkflowInfo{
 						Name: "CI",
 					},
@@ -324,10 +351,119 @@ func TestListChecksColour(t *testing.T) {
 	require.Equal(t, len(checks), len(table.rows))
 
 	expectedRows := [][]string{
-		{"CI / \x1b[1mbuild\x1b[22m", "Check Run", "\x1b[32mPassed\x1b[0m"},
-		{"\x1b[1mdeploy\x1b[22m", "Status", "\x1b[37mUnknown\x1b[0m"},
-		{"\x1b[1mtest\x1b[22m", "Check Run", "\x1b[90mSkipped\x1b[0m"},
+		{"CI / \x1b[1mbuild\x1b[22m", "Check Run", "\x1b[32mPassed\x1b[0m", "", ""},
+		{"\x1b[1mdeploy\x1b[22m", "Status", "\x1b[37mUnknown\x1b[0m", "", ""},
+		{"\x1b[1mtest\x1b[22m", "Check Run", "\x1b[90mSkipped\x1b[0m", "", ""},
 	}
 
 	require.Equal(t, expectedRows, table.rows)
 }
+
+func TestWriteChecks(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	completed := time.Date(2026, 1, 2, 3, 2, 30, 0, time.UTC)
+
+	cfg := &checkListConfig{
+		ciConfig: ciConfig{owner: "owner", repo: "repo", ref: "ref"},
+		githubClient: &FakeListCIStatusChecker{
+			checks: []github.CICheckStatus{
+				github.CheckRunDetails{CheckRun: github.CheckRun{
+					DatabaseID:  42,
+					Name:        "lint|vet",
+					Status:      "COMPLETED",
+					Conclusion:  "FAILURE",
+					StartedAt:   &started,
+					CompletedAt: &completed,
+					DetailsURL:  "https://example.com/1",
+					CheckSuite: github.CheckSuiteInfo{
+						App: github.AppInfo{Name: "GitHub Actions"},
+						WorkflowRun: github.WorkflowRunInfo{
+							Workflow: github.WorkflowInfo{Name: "CI"},
+						},
+					},
+				}, RunAttempt: 2},
+				github.StatusContext{
+					Context: "deploy",
+					State:   "PENDING",
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: outputJSON,
+			want: `[
+  {
+    "name": "lint|vet",
+    "workflow": "CI",
+    "type": "Action",
+    "status": "failed",
+    "conclusion": "failure",
+    "app": "GitHub Actions",
+    "started_at": "2026-01-02T03:00:00Z",
+    "completed_at": "2026-01-02T03:02:30Z",
+    "details_url": "https://example.com/1",
+    "database_id": 42,
+    "run_attempt": 2
+  },
+  {
+    "name": "deploy",
+    "type": "Status",
+    "status": "unknown",
+    "conclusion": "pending"
+  }
+]
+`,
+		},
+		{
+			format: outputYAML,
+			want: `- name: lint|vet
+  workflow: CI
+  type: Action
+  status: failed
+  conclusion: failure
+  app: GitHub Actions
+  started_at: 2026-01-02T03:00:00Z
+  completed_at: 2026-01-02T03:02:30Z
+  details_url: https://example.com/1
+  database_id: 42
+  run_attempt: 2
+- name: deploy
+  type: Status
+  status: unknown
+  conclusion: pending
+`,
+		},
+		{
+			format: outputCSV,
+			want: `name,workflow,type,status,conclusion,app,started_at,completed_at,details_url,database_id,run_attempt
+lint|vet,CI,Action,failed,failure,GitHub Actions,2026-01-02T03:00:00Z,2026-01-02T03:02:30Z,https://example.com/1,42,2
+deploy,,Status,unknown,pending,,,,,,
+`,
+		},
+		{
+			format: outputMarkdown,
+			want: `| name | workflow | type | status | conclusion | app | started_at | completed_at | details_url | database_id | run_attempt |
+| --- | --- | --- | --- | --- | --- | --- | --- | --- | --- | --- |
+| lint\|vet | CI | Action | failed | failure | GitHub Actions | 2026-01-02T03:00:00Z | 2026-01-02T03:02:30Z | https://example.com/1 | 42 | 2 |
+| deploy |  | Status | unknown | pending |  |  |  |  |  |  |
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			require.NoError(t, writeChecks(t.Context(), cfg, tt.format, &buf))
+			require.Equal(t, tt.want, buf.String())
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+)
+
+// reportTimeout bounds how long fetching the final state of the checks can
+// take. The wait itself may have timed out already, so this can't use its
+// context.
+const reportTimeout = 30 * time.Second
+
+type ciResultClient interface {
+	github.GetDetailedCIStatus
+	github.ResolveRef
+	github.GetFailureDetails
+	github.GetCheckRunAnnotations
+	github.UpsertPRComment
+}
+
+// ciResult is what `ci --result-file` writes once it has finished waiting.
+type ciResult struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Ref   string `json:"ref"`
+	SHA   string `json:"sha"`
+	// Status is the overall status when the wait ended. It's pending if the
+	// wait timed out or was interrupted.
+	Status string        `json:"status"`
+	Checks []checkRecord `json:"checks"`
+	// Excluded are the checks which were left out of the overall status.
+	Excluded        []string `json:"excluded"`
+	Reruns          int      `json:"reruns"`
+	DurationSeconds float64  `json:"duration_seconds"`
+}
+
+func newCIResult(ciConf *ciConfig, sha string, checks []github.CICheckStatus, outcome ciOutcome, duration time.Duration) ciResult {
+	records := make([]checkRecord, 0, len(checks))
+	for _, check := range checks {
+		records = append(records, newCheckRecord(check))
+	}
+
+	// excludes are ignored when waiting for specific checks
+	excluded := []string{}
+	if len(ciConf.checks) == 0 {
+		excluded = append(excluded, ciConf.excludes...)
+	}
+
+	return ciResult{
+		Owner:           ciConf.owner,
+		Repo:            ciConf.repo,
+		Ref:             ciConf.ref,
+		SHA:             sha,
+		Status:          outcome.status.Name(),
+		Checks:          records,
+		Excluded:        excluded,
+		Reruns:          outcome.reruns,
+		DurationSeconds: duration.Round(time.Second).Seconds(),
+	}
+}
+
+// reportCIResult shows why CI failed if it did, and writes the result of
+// waiting for CI to ciConf.resultFile, the PR being waited for if asked to
+// comment on it, and the GitHub Actions step if there is one.
+func reportCIResult(ctx context.Context, client ciResultClient, cfg *config, logger *slog.Logger, out io.Writer, ciConf *ciConfig, outcome ciOutcome, duration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reportTimeout)
+	defer cancel()
+
+	sha, err := client.ResolveRef(ctx, ciConf.owner, ciConf.repo, ciConf.ref)
+	if err != nil {
+		return fmt.Errorf("failed to get CI result: %w", err)
+	}
+
+	checks, err := client.GetDetailedCIStatus(ctx, ciConf.owner, ciConf.repo, sha)
+	if err != nil {
+		return fmt.Errorf("failed to get CI result: %w", err)
+	}
+
+	if outcome.status == github.CIStatusFailed {
+		if ciConf.failureLogLines > 0 {
+			showFailureDetails(ctx, client, logger, out, ciConf, checks, cfg.actions != nil)
+		}
+
+		annotations := failedCheckAnnotations(ctx, client, logger, ciConf.owner, ciConf.repo, checks, ciConf.checks, ciConf.excludes)
+		showAnnotations(out, annotations, cfg.actions != nil)
+	}
+
+	result := newCIResult(ciConf, sha, checks, outcome, duration)
+	cfg.summary.failedChecks = failedChecks(result.Checks, result.Excluded)
+
+	if ciConf.resultFile != "" {
+		if err := writeCIResult(ctx, logger, ciConf, result); err != nil {
+			return err
+		}
+	}
+
+	if ciConf.commentPR != 0 {
+		comment := resultComment{
+			command:  "ci",
+			key:      commentKey(ciConf.checks, ciConf.excludes),
+			status:   result.Status,
+			sha:      sha,
+			records:  result.Checks,
+			excludes: result.Excluded,
+			reruns:   result.Reruns,
+		}
+		if err := postResultComment(ctx, client, ciConf.owner, ciConf.repo, ciConf.commentPR, comment); err != nil {
+			return err
+		}
+	}
+
+	if cfg.actions != nil {
+		return writeCIStep(cfg.actions, result)
+	}
+
+	return nil
+}
+
+func writeCIResult(ctx context.Context, logger *slog.Logger, ciConf *ciConfig, result ciResult) error {
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal CI result to json: %w", err)
+	}
+
+	logger.DebugContext(ctx, "writing CI result to file", "file", ciConf.resultFile)
+	if err := ciConf.writer.WriteFile(ciConf.resultFile, jsonResult, 0644); err != nil {
+		return fmt.Errorf("failed to write CI result to file: %w", err)
+	}
+
+	return nil
+}
@@ -18,21 +18,38 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
 	"github.com/stretchr/testify/require"
 	"github.com/urfave/cli/v3"
 )
 
-// FakeCIStatusChecker implements the checkCIStatusWithRerun interface.
+// FakeCIStatusChecker implements the waitForCIClient interface.
 type FakeCIStatusChecker struct {
 	status           github.CIStatus
+	checks           []github.CICheckStatus
+	sha              string
 	err              error
+	resolveErr       error
 	RerunCount       int
 	RerunCalledCount int
 	RerunError       error
+	stepLogs         map[int64]github.FailedStepLog
+	checkRunOutputs  map[int64]github.CheckRunOutput
+	annotations      map[int64][]github.CheckRunAnnotation
+	// comments are the bodies of the comments posted, by PR
+	comments map[int]string
+	// gates are the states the gate was reported as, in order
+	gates []github.Gate
 }
 
 func (c *FakeCIStatusChecker) GetCIStatus(ctx context.Context, owner, repo string, commitHash string, excludes []string) (github.CIStatus, error) {
@@ -44,7 +61,14 @@ func (c *FakeCIStatusChecker) GetCIStatusForChecks(ctx context.Context, owner, r
 }
 
 func (c *FakeCIStatusChecker) GetDetailedCIStatus(ctx context.Context, owner, repo string, commitHash string) ([]github.CICheckStatus, error) {
-	return nil, c.err
+	return c.checks, c.err
+}
+
+func (c *FakeCIStatusChecker) ResolveRef(ctx context.Context, owner, repo, ref string) (string, error) {
+	if c.resolveErr != nil {
+		return "", c.resolveErr
+	}
+	return c.sha, c.err
 }
 
 func (c *FakeCIStatusChecker) RerunFailedWorkflowsForCommit(ctx context.Context, owner, repo, commitHash string) (int, bool, error) {
@@ -52,6 +76,46 @@ func (c *FakeCIStatusChecker) RerunFailedWorkflowsForCommit(ctx context.Context,
 	return c.RerunCount, false, c.RerunError
 }
 
+func (c *FakeCIStatusChecker) GetFailedStepLog(ctx context.Context, owner, repo string, jobID int64, maxLines int) (github.FailedStepLog, error) {
+	log, ok := c.stepLogs[jobID]
+	if !ok {
+		return github.FailedStepLog{}, fmt.Errorf("no logs for job %d", jobID)
+	}
+
+	return log, nil
+}
+
+func (c *FakeCIStatusChecker) GetCheckRunOutput(ctx context.Context, owner, repo string, checkRunID int64) (github.CheckRunOutput, error) {
+	output, ok := c.checkRunOutputs[checkRunID]
+	if !ok {
+		return github.CheckRunOutput{}, fmt.Errorf("no output for check run %d", checkRunID)
+	}
+
+	return output, nil
+}
+
+func (c *FakeCIStatusChecker) GetCheckRunAnnotations(ctx context.Context, owner, repo string, checkRunID int64) ([]github.CheckRunAnnotation, error) {
+	return c.annotations[checkRunID], nil
+}
+
+func (c *FakeCIStatusChecker) UpsertPRComment(ctx context.Context, owner, repo string, pr int, marker, body string) error {
+	if c.comments == nil {
+		c.comments = map[int]string{}
+	}
+	c.comments[pr] = body
+	return nil
+}
+
+func (c *FakeCIStatusChecker) ReportGateCheckRun(ctx context.Context, owner, repo, sha string, checkRunID int64, gate github.Gate) (int64, error) {
+	c.gates = append(c.gates, gate)
+	return 1, nil
+}
+
+func (c *FakeCIStatusChecker) ReportGateStatus(ctx context.Context, owner, repo, sha string, gate github.Gate) error {
+	c.gates = append(c.gates, gate)
+	return nil
+}
+
 func TestHandleCIStatus(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -163,7 +227,7 @@ func TestCheckCIStatus(t *testing.T) {
 			status:           github.CIStatusPending,
 			err:              nil,
 			recheckInterval:  1,
-			expectedExitCode: &one,
+			expectedExitCode: &exitTimeout,
 		},
 		{
 			name:             "Specific checks pending",
@@ -171,7 +235,7 @@ func TestCheckCIStatus(t *testing.T) {
 			status:           github.CIStatusPending,
 			err:              nil,
 			recheckInterval:  1,
-			expectedExitCode: &one,
+			expectedExitCode: &exitTimeout,
 		},
 	}
 
@@ -195,7 +259,7 @@ func TestCheckCIStatus(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.Background())
 			cancel()
 
-			err := checkCIStatus(ctx, fakeCIStatusChecker, cfg, ciConf)
+			err := checkCIStatus(ctx, fakeCIStatusChecker, cfg, ciConf, io.Discard)
 
 			var exitErr cli.ExitCoder
 			require.ErrorAs(t, err, &exitErr)
@@ -215,27 +279,30 @@ func TestParseCIArguments(t *testing.T) {
 			name: "Valid commit URL",
 			args: []string{"https://github.com/owner/repo/commit/abc123"},
 			want: ciConfig{
-				owner: "owner",
-				repo:  "repo",
-				ref:   "abc123",
+				owner:  "owner",
+				repo:   "repo",
+				ref:    "abc123",
+				writer: osFileWriter{},
 			},
 		},
 		{
 			name: "Valid PR URL",
 			args: []string{"https://github.com/owner/repo/pull/1234"},
 			want: ciConfig{
-				owner: "owner",
-				repo:  "repo",
-				ref:   "refs/pull/1234/head",
+				owner:  "owner",
+				repo:   "repo",
+				ref:    "refs/pull/1234/head",
+				writer: osFileWriter{},
 			},
 		},
 		{
 			name: "Valid arguments owner, repo, ref",
 			args: []string{"owner", "repo", "abc123"},
 			want: ciConfig{
-				owner: "owner",
-				repo:  "repo",
-				ref:   "abc123",
+				owner:  "owner",
+				repo:   "repo",
+				ref:    "abc123",
+				writer: osFileWriter{},
 			},
 		},
 		{
@@ -305,6 +372,34 @@ func (c *UnknownCIStatusChecker) RerunFailedWorkflowsForCommit(ctx context.Conte
 	return 0, false, nil
 }
 
+func (c *UnknownCIStatusChecker) ResolveRef(ctx context.Context, owner, repo, ref string) (string, error) {
+	return ref, nil
+}
+
+func (c *UnknownCIStatusChecker) GetFailedStepLog(ctx context.Context, owner, repo string, jobID int64, maxLines int) (github.FailedStepLog, error) {
+	return github.FailedStepLog{}, nil
+}
+
+func (c *UnknownCIStatusChecker) GetCheckRunOutput(ctx context.Context, owner, repo string, checkRunID int64) (github.CheckRunOutput, error) {
+	return github.CheckRunOutput{}, nil
+}
+
+func (c *UnknownCIStatusChecker) GetCheckRunAnnotations(ctx context.Context, owner, repo string, checkRunID int64) ([]github.CheckRunAnnotation, error) {
+	return nil, nil
+}
+
+func (c *UnknownCIStatusChecker) UpsertPRComment(ctx context.Context, owner, repo string, pr int, marker, body string) error {
+	return nil
+}
+
+func (c *UnknownCIStatusChecker) ReportGateCheckRun(ctx context.Context, owner, repo, sha string, checkRunID int64, gate github.Gate) (int64, error) {
+	return 0, nil
+}
+
+func (c *UnknownCIStatusChecker) ReportGateStatus(ctx context.Context, owner, repo, sha string, gate github.Gate) error {
+	return nil
+}
+
 func TestUnknownCIStatusRetries(t *testing.T) {
 	t.Parallel()
 
@@ -321,7 +416,7 @@ func TestUnknownCIStatusRetries(t *testing.T) {
 
 	ctx := context.Background()
 
-	err := checkCIStatus(ctx, fakeCIStatusChecker, cfg, ciConf)
+	err := checkCIStatus(ctx, fakeCIStatusChecker, cfg, ciConf, io.Discard)
 
 	var exitErr cli.ExitCoder
 	require.ErrorAs(t, err, &exitErr)
@@ -330,13 +425,162 @@ func TestUnknownCIStatusRetries(t *testing.T) {
 
 	fakeCIStatusChecker.calls = 0
 	ciConf.checks = []string{"check1", "check2"}
-	err = checkCIStatus(ctx, fakeCIStatusChecker, cfg, ciConf)
+	err = checkCIStatus(ctx, fakeCIStatusChecker, cfg, ciConf, io.Discard)
 
 	require.ErrorAs(t, err, &exitErr)
 	require.Equal(t, zero, exitErr.ExitCode())
 	require.Equal(t, 2, fakeCIStatusChecker.calls)
 }
 
+// TestCheckAllCINoChecks tests how a commit without any checks or statuses is
+// handled, depending on the grace period and --require-checks.
+func TestCheckAllCINoChecks(t *testing.T) {
+	tests := []struct {
+		name             string
+		gracePeriod      time.Duration
+		requireChecks    bool
+		expectedExitCode *int
+	}{
+		{
+			name:             "assumed successful",
+			expectedExitCode: &zero,
+		},
+		{
+			name:             "checks required",
+			requireChecks:    true,
+			expectedExitCode: &one,
+		},
+		{
+			name:          "within grace period",
+			gracePeriod:   time.Hour,
+			requireChecks: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			check := &checkAllCI{
+				githubClient: &FakeCIStatusChecker{status: github.CIStatusNoChecks},
+				owner:        "owner",
+				repo:         "repo",
+				ref:          "ref",
+				logger:       testLogger,
+				checksGrace: utils.ChecksGracePeriod{
+					Period:        tt.gracePeriod,
+					RequireChecks: tt.requireChecks,
+				},
+			}
+
+			err := check.Check(context.Background())
+
+			if tt.expectedExitCode == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var exitErr cli.ExitCoder
+			require.ErrorAs(t, err, &exitErr)
+			require.Equal(t, *tt.expectedExitCode, exitErr.ExitCode())
+		})
+	}
+}
+
+// TestCheckAllCIExcludes checks how excluded checks affect the wait against a
+// real client, with and without a gate from --report-as.
+func TestCheckAllCIExcludes(t *testing.T) {
+	t.Parallel()
+
+	const (
+		passed = `{"__typename": "CheckRun", "name": "build", "status": "COMPLETED", "conclusion": "SUCCESS"}`
+		gate   = `{"__typename": "CheckRun", "name": "wfg/gate", "status": "IN_PROGRESS"}`
+	)
+
+	tests := []struct {
+		name     string
+		state    string
+		nodes    []string
+		excludes []string
+		reportAs string
+		// expectedExitCode is nil if the wait should go on
+		expectedExitCode *int
+	}{
+		{
+			name:     "excluded check pending",
+			state:    "PENDING",
+			nodes:    []string{passed, `{"__typename": "CheckRun", "name": "deploy", "status": "IN_PROGRESS"}`},
+			excludes: []string{"deploy"},
+		},
+		{
+			name:             "excluded check failed",
+			state:            "FAILURE",
+			nodes:            []string{passed, `{"__typename": "CheckRun", "name": "deploy", "status": "COMPLETED", "conclusion": "FAILURE"}`},
+			excludes:         []string{"deploy"},
+			expectedExitCode: &zero,
+		},
+		{
+			name:             "only the gate pending",
+			state:            "PENDING",
+			nodes:            []string{passed, gate},
+			excludes:         []string{"wfg/gate"},
+			reportAs:         "wfg/gate",
+			expectedExitCode: &zero,
+		},
+		{
+			name:     "excluded check and the gate pending",
+			state:    "PENDING",
+			nodes:    []string{passed, `{"__typename": "StatusContext", "context": "deploy", "state": "PENDING"}`, gate},
+			excludes: []string{"deploy", "wfg/gate"},
+			reportAs: "wfg/gate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/api/graphql", r.URL.Path)
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"data": {"repository": {"object": {"oid": "abc123", "statusCheckRollup": {"state": %q, "contexts": {
+					"checkRunCount": %d, "nodes": [%s], "pageInfo": {"hasNextPage": false}}}}}}}`,
+					tt.state, len(tt.nodes), strings.Join(tt.nodes, ", "))
+			}))
+			defer server.Close()
+
+			client, err := github.AuthenticateWithToken(context.Background(), testLogger, "token", server.URL)
+			require.NoError(t, err)
+
+			check := &checkAllCI{
+				githubClient: client,
+				owner:        "owner",
+				repo:         "repo",
+				ref:          "ref",
+				excludes:     tt.excludes,
+				logger:       testLogger,
+			}
+
+			ctx := context.Background()
+			if tt.reportAs != "" {
+				ctx = github.WithGate(ctx, tt.reportAs)
+			}
+
+			err = check.Check(ctx)
+
+			if tt.expectedExitCode == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var exitErr cli.ExitCoder
+			require.ErrorAs(t, err, &exitErr)
+			require.Equal(t, *tt.expectedExitCode, exitErr.ExitCode())
+		})
+	}
+}
+
 func TestUrlFor(t *testing.T) {
 	t.Parallel()
 
@@ -425,7 +669,7 @@ func TestCheckCIActionRetries(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 1)
 			cancel()
 
-			err := checkCIStatus(ctx, fakeCIStatusChecker, cfg, ciConf)
+			err := checkCIStatus(ctx, fakeCIStatusChecker, cfg, ciConf, io.Discard)
 
 			if tt.expectedExitCode != nil {
 				var exitErr cli.ExitCoder
@@ -439,3 +683,140 @@ func TestCheckCIActionRetries(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteCIResultFile(t *testing.T) {
+	t.Parallel()
+
+	writer := &fakeFileWriter{}
+	client := &FakeCIStatusChecker{
+		status:     github.CIStatusFailed,
+		sha:        "abc123",
+		RerunCount: 1,
+		checks: []github.CICheckStatus{
+			github.CheckRun{Name: "build", Status: "COMPLETED", Conclusion: "SUCCESS"},
+			github.CheckRun{Name: "test", Status: "COMPLETED", Conclusion: "FAILURE"},
+		},
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{
+		owner:         "owner",
+		repo:          "repo",
+		ref:           "main",
+		excludes:      []string{"lint"},
+		actionRetries: 1,
+		resultFile:    "result.json",
+		writer:        writer,
+	}
+
+	err := checkCIStatus(context.Background(), client, cfg, ciConf, io.Discard)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, one, exitErr.ExitCode())
+
+	var got ciResult
+	require.NoError(t, json.Unmarshal(writer.data, &got))
+
+	require.Equal(t, "result.json", writer.filename)
+	require.Equal(t, ciResult{
+		Owner:  "owner",
+		Repo:   "repo",
+		Ref:    "main",
+		SHA:    "abc123",
+		Status: "failed",
+		Checks: []checkRecord{
+			{Name: "build", Type: "Check Run", Status: "passed", Conclusion: "success"},
+			{Name: "test", Type: "Check Run", Status: "failed", Conclusion: "failure"},
+		},
+		Excluded: []string{"lint"},
+		Reruns:   1,
+	}, got)
+}
+
+// TestWriteCIResultFileRerunTimeout tests that CI which failed, but whose
+// workflows were rerun, is pending rather than failed if the wait times out
+// before the reruns finish.
+func TestWriteCIResultFileRerunTimeout(t *testing.T) {
+	t.Parallel()
+
+	for _, checks := range [][]string{nil, {"test"}} {
+		writer := &fakeFileWriter{}
+		client := &FakeCIStatusChecker{
+			status:     github.CIStatusFailed,
+			RerunCount: 1,
+		}
+		cfg := &config{
+			recheckInterval: time.Hour,
+			logger:          testLogger,
+		}
+		ciConf := &ciConfig{
+			owner:         "owner",
+			repo:          "repo",
+			ref:           "main",
+			checks:        checks,
+			actionRetries: 1,
+			resultFile:    "result.json",
+			writer:        writer,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := checkCIStatus(ctx, client, cfg, ciConf, io.Discard)
+		cancel()
+
+		var exitErr cli.ExitCoder
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, utils.ExitTimeout, exitErr.ExitCode())
+
+		var got ciResult
+		require.NoError(t, json.Unmarshal(writer.data, &got))
+		require.Equal(t, "pending", got.Status)
+		require.Equal(t, 1, got.Reruns)
+	}
+}
+
+func TestWriteCIResultFileError(t *testing.T) {
+	t.Parallel()
+
+	client := &FakeCIStatusChecker{status: github.CIStatusPassed}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{
+		owner:      "owner",
+		repo:       "repo",
+		ref:        "main",
+		resultFile: "result.json",
+		writer:     erroringFileWriter,
+	}
+
+	err := checkCIStatus(context.Background(), client, cfg, ciConf, io.Discard)
+	require.ErrorContains(t, err, "failed to write CI result to file")
+}
+
+func TestReportCIResultErrorKeepsFailedExit(t *testing.T) {
+	t.Parallel()
+
+	client := &FakeCIStatusChecker{
+		status:     github.CIStatusFailed,
+		resolveErr: fmt.Errorf("an error occurred"),
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{
+		owner: "owner",
+		repo:  "repo",
+		ref:   "main",
+	}
+
+	err := checkCIStatus(context.Background(), client, cfg, ciConf, io.Discard)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, utils.ExitFailed, exitErr.ExitCode())
+}
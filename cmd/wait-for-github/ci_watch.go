@@ -0,0 +1,177 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/grafana/wait-for-github/internal/ansi"
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/logging"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/transform"
+)
+
+var highlight = color.New(color.ReverseVideo).SprintFunc()
+
+type watchCIClient interface {
+	checkCIStatusWithRerun
+	github.GetDetailedCIStatus
+}
+
+// watchChecks is a check which waits for CI like `ci` does, and redraws the
+// list of checks each time it's run.
+type watchChecks struct {
+	ciCheck ciCheck
+	client  github.GetDetailedCIStatus
+	ciConf  ciConfig
+
+	out io.Writer
+	// inPlace redraws over the previous table, rather than below it. This
+	// only makes sense on a terminal.
+	inPlace      bool
+	linesWritten int
+	previous     map[string]github.CIStatus
+	now          func() time.Time
+}
+
+func (w *watchChecks) Check(ctx context.Context) error {
+	err := w.ciCheck.Check(ctx)
+
+	// leave other errors, like rate limits, to RunUntilCancelledOrTimeout
+	var exitErr cli.ExitCoder
+	if err != nil && !errors.As(err, &exitErr) {
+		return err
+	}
+
+	if renderErr := w.render(ctx); renderErr != nil {
+		return renderErr
+	}
+
+	return err
+}
+
+func (w *watchChecks) render(ctx context.Context) error {
+	checks, err := w.client.GetDetailedCIStatus(ctx, w.ciConf.owner, w.ciConf.repo, w.ciConf.ref)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	table, err := newTableWriter(&buf)
+	if err != nil {
+		return err
+	}
+
+	table.Header([]string{"Name", "Type", "Status", "Elapsed"})
+
+	rows := watchRows(checks, w.previous, w.now())
+	if len(rows) == 0 {
+		rows = [][]string{{"No CI checks found", "", "", ""}}
+	}
+	if err := table.Bulk(rows); err != nil {
+		return fmt.Errorf("failed to write table: %w", err)
+	}
+	if err := table.Render(); err != nil {
+		return fmt.Errorf("failed to render table: %w", err)
+	}
+
+	fmt.Fprintf(&buf, "Overall status: %s\n", titleCase(w.ciCheck.outcome().status.String()))
+
+	if w.inPlace && w.linesWritten > 0 {
+		// move the cursor back to the start of the previous table, and
+		// clear everything below it
+		fmt.Fprintf(w.out, "\x1b[%dA\x1b[J", w.linesWritten)
+	}
+
+	w.linesWritten = bytes.Count(buf.Bytes(), []byte("\n"))
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	w.previous = make(map[string]github.CIStatus, len(checks))
+	for _, check := range checks {
+		w.previous[checkKey(check)] = check.Outcome()
+	}
+
+	return nil
+}
+
+// watchRows returns a table row for each check. The statuses of checks whose
+// outcome changed since previous are highlighted.
+func watchRows(checks []github.CICheckStatus, previous map[string]github.CIStatus, now time.Time) [][]string {
+	var rows [][]string
+	for _, check := range checks {
+		status := titleCase(check.Outcome().String())
+		if before, ok := previous[checkKey(check)]; ok && before != check.Outcome() {
+			status = highlight(status)
+		}
+
+		elapsed := ""
+		if d, ok := checkElapsed(check, now); ok {
+			elapsed = d.Round(time.Second).String()
+		}
+
+		rows = append(rows, []string{check.String(), check.Type(), status, elapsed})
+	}
+
+	return rows
+}
+
+func checkKey(check github.CICheckStatus) string {
+	return check.Type() + "\x00" + check.String()
+}
+
+func titleCase(s string) string {
+	caser := ansi.NewANSITransformer(cases.Title(language.English))
+	out, _, _ := transform.String(caser, s)
+	return out
+}
+
+// watchCIChecks redraws the list of checks on every recheck until CI has
+// finished, and then exits like `ci` would.
+func watchCIChecks(ctx context.Context, githubClient watchCIClient, cfg *config, ciConf *ciConfig, out io.Writer, inPlace bool) error {
+	base := cfg.logger
+	if inPlace {
+		// informational logs would scroll the table out of place
+		opts := cfg.logOptions
+		opts.Level = slog.LevelWarn
+		base = logging.SetupLogger(opts)
+	}
+	logger := base.With(logging.OwnerAttr(ciConf.owner), logging.RepoAttr(ciConf.repo), logging.RefAttr(ciConf.ref))
+
+	watch := &watchChecks{
+		ciCheck: newCICheck(ctx, githubClient, cfg, ciConf, logger),
+		client:  githubClient,
+		ciConf:  *ciConf,
+		out:     out,
+		inPlace: inPlace,
+		now:     time.Now,
+	}
+
+	return utils.RunUntilCancelledOrTimeout(ctx, logger, watch, cfg.pollStrategy())
+}
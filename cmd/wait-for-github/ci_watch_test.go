@@ -0,0 +1,130 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// fakeWatchClient returns the next of its statuses and checks on each call,
+// and then keeps returning the last ones.
+type fakeWatchClient struct {
+	FakeCIStatusChecker
+	statuses []github.CIStatus
+	checks   [][]github.CICheckStatus
+	calls    int
+}
+
+func (c *fakeWatchClient) GetCIStatus(ctx context.Context, owner, repo string, commitHash string, excludes []string) (github.CIStatus, error) {
+	c.calls++
+	return c.statuses[min(c.calls, len(c.statuses))-1], nil
+}
+
+func (c *fakeWatchClient) GetDetailedCIStatus(ctx context.Context, owner, repo string, commitHash string) ([]github.CICheckStatus, error) {
+	return c.checks[min(c.calls, len(c.checks))-1], nil
+}
+
+func TestWatchCIChecks(t *testing.T) {
+	t.Parallel()
+
+	started := time.Now().Add(-time.Minute)
+	completed := started.Add(30 * time.Second)
+
+	client := &fakeWatchClient{
+		statuses: []github.CIStatus{github.CIStatusPending, github.CIStatusPassed},
+		checks: [][]github.CICheckStatus{
+			{github.CheckRun{Name: "build", Status: "IN_PROGRESS", StartedAt: &started}},
+			{github.CheckRun{Name: "build", Status: "COMPLETED", Conclusion: "SUCCESS", StartedAt: &started, CompletedAt: &completed}},
+		},
+	}
+	cfg := &config{recheckInterval: time.Millisecond, logger: testLogger}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main"}
+
+	var out bytes.Buffer
+	err := watchCIChecks(t.Context(), client, cfg, ciConf, &out, true)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 0, exitErr.ExitCode())
+	require.Equal(t, "CI successful", exitErr.Error())
+
+	frames := strings.Split(out.String(), "\x1b[6A\x1b[J")
+	require.Len(t, frames, 2, "the second table should be drawn over the first")
+	require.Contains(t, frames[0], "Pending")
+	require.Contains(t, frames[0], "Overall status: Pending")
+	require.Contains(t, frames[1], "Passed")
+	require.Contains(t, frames[1], "30s")
+	require.Contains(t, frames[1], "Overall status: Passed")
+}
+
+// TestWatchCIChecksGracePeriod tests that the overall status shown is the one
+// `ci` acts on, so a commit without checks yet is pending during the grace
+// period rather than shown as having no checks.
+func TestWatchCIChecksGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeWatchClient{
+		statuses: []github.CIStatus{github.CIStatusNoChecks, github.CIStatusPassed},
+		checks:   [][]github.CICheckStatus{{}},
+	}
+	cfg := &config{recheckInterval: time.Millisecond, checksGracePeriod: time.Hour, logger: testLogger}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main"}
+
+	var out bytes.Buffer
+	err := watchCIChecks(t.Context(), client, cfg, ciConf, &out, false)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 0, exitErr.ExitCode())
+
+	require.Contains(t, out.String(), "Overall status: Pending")
+	require.NotContains(t, out.String(), "No Checks")
+}
+
+func TestWatchRows(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 3, 10, 0, 0, time.UTC)
+	started := now.Add(-90 * time.Second)
+	created := now.Add(-time.Minute)
+
+	checks := []github.CICheckStatus{
+		github.CheckRun{Name: "build", Status: "IN_PROGRESS", StartedAt: &started},
+		github.CheckRun{Name: "lint", Status: "COMPLETED", Conclusion: "FAILURE"},
+		github.StatusContext{Context: "deploy", State: "PENDING", CreatedAt: &created},
+	}
+	previous := map[string]github.CIStatus{
+		checkKey(checks[0]): github.CIStatusPending,
+		checkKey(checks[1]): github.CIStatusPending,
+	}
+
+	rows := watchRows(checks, previous, now)
+
+	require.Equal(t, []string{"build", "Check Run", "Pending", "1m30s"}, rows[0])
+	require.Equal(t, "lint", rows[1][0])
+	require.Contains(t, rows[1][2], "Failed")
+	require.Empty(t, rows[1][3])
+	require.Equal(t, []string{"deploy", "Status", "Unknown", "1m0s"}, rows[2])
+}
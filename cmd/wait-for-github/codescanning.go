@@ -0,0 +1,316 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/logging"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// severityNone disables failing on code scanning alerts
+const severityNone = "none"
+
+var (
+	// Code scanning analyses pull requests on their merge ref, not their head
+	prHeadRefRegexp  = regexp.MustCompile(`^refs/pull/(\d+)/head$`)
+	prMergeRefRegexp = regexp.MustCompile(`^refs/pull/(\d+)/merge$`)
+)
+
+type codeScanningConfig struct {
+	ciConfig
+
+	tools          []string
+	failOnSeverity string
+}
+
+// codeScanningRef returns the ref which code scanning analyses are reported
+// against for ref.
+func codeScanningRef(ref string) string {
+	return prHeadRefRegexp.ReplaceAllString(ref, "refs/pull/$1/merge")
+}
+
+type codeScanningCheck struct {
+	codeScanningConfig
+	githubClient github.GetCodeScanningResults
+	table        tableWriter
+	logger       *slog.Logger
+}
+
+// missingTools returns the tools from wanted which don't have an analysis yet.
+// If no tools are wanted, any analysis will do.
+func missingTools(analyses []github.CodeScanningAnalysis, wanted []string) []string {
+	if len(wanted) == 0 {
+		if len(analyses) == 0 {
+			return []string{"any"}
+		}
+		return nil
+	}
+
+	var missing []string
+	for _, tool := range wanted {
+		found := slices.ContainsFunc(analyses, func(a github.CodeScanningAnalysis) bool {
+			return strings.EqualFold(a.Tool, tool)
+		})
+		if !found {
+			missing = append(missing, tool)
+		}
+	}
+
+	return missing
+}
+
+func (cs *codeScanningCheck) Check(ctx context.Context) error {
+	ref := codeScanningRef(cs.ref)
+
+	analyses, err := cs.githubClient.GetCodeScanningAnalyses(ctx, cs.owner, cs.repo, ref)
+	if err != nil {
+		return err
+	}
+
+	if missing := missingTools(analyses, cs.tools); len(missing) > 0 {
+		cs.logger.InfoContext(ctx, "code scanning analyses are not finished yet", "waiting_for", strings.Join(missing, ", "))
+		return nil
+	}
+
+	for _, analysis := range analyses {
+		if analysis.Error != "" {
+			cs.logger.ErrorContext(ctx, "code scanning analysis failed", "tool", analysis.Tool, "category", analysis.Category, "error", analysis.Error)
+			return cli.Exit(fmt.Sprintf("Code scanning analysis by %s failed. Please check the following commit: %s", analysis.Tool, urlFor(cs.owner, cs.repo, cs.ref)), utils.ExitFailed)
+		}
+	}
+
+	// A commit SHA isn't a ref that alerts can be listed for, so use the ref
+	// that the analyses were reported against instead.
+	if !strings.HasPrefix(ref, "refs/") {
+		ref = analyses[0].Ref
+	}
+
+	alerts, err := cs.githubClient.GetCodeScanningAlerts(ctx, cs.owner, cs.repo, ref)
+	if err != nil {
+		return err
+	}
+
+	if len(cs.tools) > 0 {
+		alerts = slices.DeleteFunc(alerts, func(a github.CodeScanningAlert) bool {
+			return !slices.ContainsFunc(cs.tools, func(tool string) bool {
+				return strings.EqualFold(a.Tool, tool)
+			})
+		})
+	}
+
+	// a PR only fails on the alerts it adds, not those already on the
+	// branch it's to be merged into
+	kind := "open"
+	if match := prMergeRefRegexp.FindStringSubmatch(ref); match != nil {
+		pr, _ := strconv.Atoi(match[1])
+		if alerts, err = cs.newAlerts(ctx, pr, alerts); err != nil {
+			return err
+		}
+		kind = "new"
+	}
+
+	if err := renderAlertSummary(alerts, kind, cs.table); err != nil {
+		return err
+	}
+
+	if cs.failOnSeverity == severityNone {
+		return cli.Exit("Code scanning finished", utils.ExitSuccess)
+	}
+
+	failing := 0
+	for _, alert := range alerts {
+		if !github.SeverityAtLeast(alert.Severity, cs.failOnSeverity) {
+			continue
+		}
+
+		failing++
+		cs.logger.InfoContext(ctx, "code scanning alert",
+			"number", alert.Number,
+			"tool", alert.Tool,
+			"rule", alert.RuleID,
+			"severity", alert.Severity,
+			"location", alert.Path+":"+strconv.Itoa(alert.Line),
+			"url", alert.URL,
+		)
+	}
+
+	if failing > 0 {
+		return cli.Exit(fmt.Sprintf("Found %d %s code scanning alerts with severity %s or higher", failing, kind, cs.failOnSeverity), utils.ExitFailed)
+	}
+
+	return cli.Exit("Code scanning passed", utils.ExitSuccess)
+}
+
+// newAlerts returns the alerts of the PR which aren't open on its base branch
+// too. An alert has the same number wherever it's found.
+func (cs *codeScanningCheck) newAlerts(ctx context.Context, pr int, alerts []github.CodeScanningAlert) ([]github.CodeScanningAlert, error) {
+	baseRef, err := cs.githubClient.GetPRBaseRef(ctx, cs.owner, cs.repo, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	baseAlerts, err := cs.githubClient.GetCodeScanningAlerts(ctx, cs.owner, cs.repo, baseRef)
+	if err != nil {
+		return nil, err
+	}
+
+	onBase := make(map[int]bool, len(baseAlerts))
+	for _, alert := range baseAlerts {
+		onBase[alert.Number] = true
+	}
+
+	return slices.DeleteFunc(alerts, func(a github.CodeScanningAlert) bool {
+		return onBase[a.Number]
+	}), nil
+}
+
+// renderAlertSummary writes a table counting the alerts by severity. kind says
+// which alerts they are, open or new.
+func renderAlertSummary(alerts []github.CodeScanningAlert, kind string, table tableWriter) error {
+	caser := cases.Title(language.English)
+
+	if len(alerts) == 0 {
+		table.Header([]string{"Status"})
+
+		if err := table.Bulk([]string{fmt.Sprintf("No %s code scanning alerts", kind)}); err != nil {
+			return fmt.Errorf("failed to write table: %w", err)
+		}
+
+		if err := table.Render(); err != nil {
+			return fmt.Errorf("failed to render table: %w", err)
+		}
+
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, alert := range alerts {
+		counts[alert.Severity]++
+	}
+
+	table.Header([]string{"Severity", caser.String(kind) + " Alerts"})
+
+	var data [][]string
+	for _, severity := range github.Severities() {
+		if counts[severity] == 0 {
+			continue
+		}
+
+		data = append(data, []string{caser.String(severity), strconv.Itoa(counts[severity])})
+		delete(counts, severity)
+	}
+
+	// Severities we don't know about go at the end
+	for _, severity := range slices.Sorted(maps.Keys(counts)) {
+		name := severity
+		if name == "" {
+			name = "Unknown"
+		}
+		data = append(data, []string{caser.String(name), strconv.Itoa(counts[severity])})
+	}
+
+	if err := table.Bulk(data); err != nil {
+		return fmt.Errorf("failed to write table: %w", err)
+	}
+
+	return table.Render()
+}
+
+func checkCodeScanning(timeoutCtx context.Context, githubClient github.GetCodeScanningResults, cfg *config, csConf *codeScanningConfig, table tableWriter) error {
+	logger := cfg.logger.With(logging.OwnerAttr(csConf.owner), logging.RepoAttr(csConf.repo), logging.RefAttr(csConf.ref))
+	logger.InfoContext(timeoutCtx, "waiting for code scanning analyses", "tools", strings.Join(csConf.tools, ", "))
+
+	check := &codeScanningCheck{
+		codeScanningConfig: *csConf,
+		githubClient:       githubClient,
+		table:              table,
+		logger:             logger,
+	}
+
+	return utils.RunUntilCancelledOrTimeout(timeoutCtx, cfg.logger, check, cfg.pollStrategy())
+}
+
+func codeScanningCommand(cfg *config) *cli.Command {
+	validSeverities := append(github.Severities(), severityNone)
+
+	return &cli.Command{
+		Name:      "code-scanning",
+		Usage:     "Wait for code scanning analyses to finish, and check for open alerts, or new ones on a PR",
+		ArgsUsage: "<https://github.com/OWNER/REPO/commit|pull/HASH|PRNumber|owner> [<repo> <ref>]",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name: "tool",
+				Usage: "Wait for an analysis by a specific code scanning tool, e.g. CodeQL. " +
+					"By default, any analysis of the commit will do.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("GITHUB_CODE_SCANNING_TOOLS"),
+				),
+			},
+			&cli.StringFlag{
+				Name: "fail-on-severity",
+				Usage: fmt.Sprintf("Fail if there are open alerts with this severity or higher, or for a PR, new alerts it adds. Valid severities are: %s.",
+					strings.Join(validSeverities, ", ")),
+				Value: github.SeverityHigh,
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("GITHUB_CODE_SCANNING_FAIL_ON_SEVERITY"),
+				),
+				Validator: func(s string) error {
+					if !slices.Contains(validSeverities, s) {
+						return fmt.Errorf("invalid severity %q: must be one of %s", s, strings.Join(validSeverities, ", "))
+					}
+					return nil
+				},
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ciConf, err := parseCIArguments(ctx, cmd, cfg.logger, "code-scanning")
+			if err != nil {
+				return err
+			}
+
+			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo)
+			if err != nil {
+				return err
+			}
+
+			table, err := newTableWriter(os.Stdout)
+			if err != nil {
+				return err
+			}
+
+			return checkCodeScanning(ctx, githubClient, cfg, &codeScanningConfig{
+				ciConfig:       ciConf,
+				tools:          cmd.StringSlice("tool"),
+				failOnSeverity: cmd.String("fail-on-severity"),
+			}, table)
+		},
+	}
+}
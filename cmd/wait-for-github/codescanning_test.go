@@ -0,0 +1,320 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// fakeCodeScanningClient implements the GetCodeScanningResults interface
+type fakeCodeScanningClient struct {
+	analyses    []github.CodeScanningAnalysis
+	alerts      []github.CodeScanningAlert
+	analysesErr error
+	alertsErr   error
+
+	// baseAlerts are the alerts on refs/heads/main, which PRs are based on
+	baseAlerts []github.CodeScanningAlert
+
+	analysesRef string
+	// alertsRefs are the refs alerts were listed for, in order
+	alertsRefs []string
+}
+
+func (f *fakeCodeScanningClient) GetCodeScanningAnalyses(ctx context.Context, owner, repo, ref string) ([]github.CodeScanningAnalysis, error) {
+	f.analysesRef = ref
+	return f.analyses, f.analysesErr
+}
+
+func (f *fakeCodeScanningClient) GetCodeScanningAlerts(ctx context.Context, owner, repo, ref string) ([]github.CodeScanningAlert, error) {
+	f.alertsRefs = append(f.alertsRefs, ref)
+	if ref == "refs/heads/main" && f.baseAlerts != nil {
+		return f.baseAlerts, f.alertsErr
+	}
+	return slices.Clone(f.alerts), f.alertsErr
+}
+
+func (f *fakeCodeScanningClient) GetPRBaseRef(ctx context.Context, owner, repo string, pr int) (string, error) {
+	return "refs/heads/main", nil
+}
+
+func TestCodeScanningRef(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "refs/pull/12/merge", codeScanningRef("refs/pull/12/head"))
+	require.Equal(t, "refs/heads/main", codeScanningRef("refs/heads/main"))
+	require.Equal(t, "abc123", codeScanningRef("abc123"))
+}
+
+func TestCodeScanningCheck(t *testing.T) {
+	t.Parallel()
+
+	codeQL := github.CodeScanningAnalysis{ID: 1, Tool: "CodeQL", Ref: "refs/heads/main", CommitSHA: "abc123"}
+	trivy := github.CodeScanningAnalysis{ID: 2, Tool: "Trivy", Ref: "refs/heads/main", CommitSHA: "abc123"}
+
+	tests := []struct {
+		name             string
+		client           fakeCodeScanningClient
+		tools            []string
+		failOnSeverity   string
+		expectedExitCode *int
+		expectErr        bool
+		wantHeaders      []string
+		wantRows         [][]string
+	}{
+		{
+			name:           "no analyses yet",
+			client:         fakeCodeScanningClient{},
+			failOnSeverity: github.SeverityHigh,
+		},
+		{
+			name: "waiting for a specific tool",
+			client: fakeCodeScanningClient{
+				analyses: []github.CodeScanningAnalysis{trivy},
+			},
+			tools:          []string{"CodeQL"},
+			failOnSeverity: github.SeverityHigh,
+		},
+		{
+			name: "analysis failed",
+			client: fakeCodeScanningClient{
+				analyses: []github.CodeScanningAnalysis{{Tool: "CodeQL", Error: "SARIF processing failed"}},
+			},
+			failOnSeverity:   github.SeverityHigh,
+			expectedExitCode: &one,
+		},
+		{
+			name: "no alerts",
+			client: fakeCodeScanningClient{
+				analyses: []github.CodeScanningAnalysis{codeQL},
+			},
+			failOnSeverity:   github.SeverityHigh,
+			expectedExitCode: &zero,
+			wantHeaders:      []string{"Status"},
+			wantRows:         [][]string{{"No open code scanning alerts"}},
+		},
+		{
+			name: "alerts below threshold",
+			client: fakeCodeScanningClient{
+				analyses: []github.CodeScanningAnalysis{codeQL},
+				alerts: []github.CodeScanningAlert{
+					{Number: 1, Tool: "CodeQL", Severity: github.SeverityMedium},
+					{Number: 2, Tool: "CodeQL", Severity: github.SeverityNote},
+					{Number: 3, Tool: "CodeQL", Severity: github.SeverityMedium},
+				},
+			},
+			failOnSeverity:   github.SeverityHigh,
+			expectedExitCode: &zero,
+			wantHeaders:      []string{"Severity", "Open Alerts"},
+			wantRows:         [][]string{{"Medium", "2"}, {"Note", "1"}},
+		},
+		{
+			name: "alerts at threshold",
+			client: fakeCodeScanningClient{
+				analyses: []github.CodeScanningAnalysis{codeQL},
+				alerts: []github.CodeScanningAlert{
+					{Number: 1, Tool: "CodeQL", Severity: github.SeverityCritical},
+					{Number: 2, Tool: "CodeQL", Severity: github.SeverityError},
+				},
+			},
+			failOnSeverity:   github.SeverityHigh,
+			expectedExitCode: &one,
+			wantHeaders:      []string{"Severity", "Open Alerts"},
+			wantRows:         [][]string{{"Critical", "1"}, {"Error", "1"}},
+		},
+		{
+			name: "alerts from other tools are ignored",
+			client: fakeCodeScanningClient{
+				analyses: []github.CodeScanningAnalysis{codeQL, trivy},
+				alerts: []github.CodeScanningAlert{
+					{Number: 1, Tool: "Trivy", Severity: github.SeverityCritical},
+				},
+			},
+			tools:            []string{"codeql"},
+			failOnSeverity:   github.SeverityHigh,
+			expectedExitCode: &zero,
+			wantHeaders:      []string{"Status"},
+			wantRows:         [][]string{{"No open code scanning alerts"}},
+		},
+		{
+			name: "failing disabled",
+			client: fakeCodeScanningClient{
+				analyses: []github.CodeScanningAnalysis{codeQL},
+				alerts: []github.CodeScanningAlert{
+					{Number: 1, Tool: "CodeQL", Severity: github.SeverityCritical},
+				},
+			},
+			failOnSeverity:   severityNone,
+			expectedExitCode: &zero,
+			wantHeaders:      []string{"Severity", "Open Alerts"},
+			wantRows:         [][]string{{"Critical", "1"}},
+		},
+		{
+			name: "error listing analyses",
+			client: fakeCodeScanningClient{
+				analysesErr: fmt.Errorf("an error occurred"),
+			},
+			failOnSeverity: github.SeverityHigh,
+			expectErr:      true,
+		},
+		{
+			name: "error listing alerts",
+			client: fakeCodeScanningClient{
+				analyses:  []github.CodeScanningAnalysis{codeQL},
+				alertsErr: fmt.Errorf("an error occurred"),
+			},
+			failOnSeverity: github.SeverityHigh,
+			expectErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			table := &mockTableWriter{}
+			check := &codeScanningCheck{
+				codeScanningConfig: codeScanningConfig{
+					ciConfig: ciConfig{
+						owner: "owner",
+						repo:  "repo",
+						ref:   "abc123",
+					},
+					tools:          tt.tools,
+					failOnSeverity: tt.failOnSeverity,
+				},
+				githubClient: &tt.client,
+				table:        table,
+				logger:       testLogger,
+			}
+
+			err := check.Check(context.Background())
+
+			switch {
+			case tt.expectErr:
+				require.Error(t, err)
+			case tt.expectedExitCode != nil:
+				var exitErr cli.ExitCoder
+				require.ErrorAs(t, err, &exitErr)
+				require.Equal(t, *tt.expectedExitCode, exitErr.ExitCode())
+			default:
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.wantHeaders, table.headers)
+			require.Equal(t, tt.wantRows, table.rows)
+		})
+	}
+}
+
+// TestCodeScanningCheckAlertRef tests that alerts are listed for the ref the
+// analyses were reported against when waiting on a commit SHA.
+func TestCodeScanningCheckAlertRef(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeCodeScanningClient{
+		analyses: []github.CodeScanningAnalysis{{Tool: "CodeQL", Ref: "refs/heads/main", CommitSHA: "abc123"}},
+	}
+
+	check := &codeScanningCheck{
+		codeScanningConfig: codeScanningConfig{
+			ciConfig:       ciConfig{owner: "owner", repo: "repo", ref: "abc123"},
+			failOnSeverity: github.SeverityHigh,
+		},
+		githubClient: client,
+		table:        &mockTableWriter{},
+		logger:       testLogger,
+	}
+
+	_ = check.Check(context.Background())
+	require.Equal(t, "abc123", client.analysesRef)
+	require.Equal(t, []string{"refs/heads/main"}, client.alertsRefs)
+
+	client.alertsRefs = nil
+	check.ref = "refs/pull/12/head"
+	_ = check.Check(context.Background())
+	require.Equal(t, "refs/pull/12/merge", client.analysesRef)
+	require.Equal(t, []string{"refs/pull/12/merge", "refs/heads/main"}, client.alertsRefs)
+}
+
+// TestCodeScanningCheckNewAlerts tests that a PR only fails on the alerts it
+// adds, not on those already open on its base branch.
+func TestCodeScanningCheckNewAlerts(t *testing.T) {
+	t.Parallel()
+
+	existing := github.CodeScanningAlert{Number: 1, Tool: "CodeQL", Severity: github.SeverityCritical}
+	added := github.CodeScanningAlert{Number: 2, Tool: "CodeQL", Severity: github.SeverityHigh}
+
+	tests := []struct {
+		name             string
+		alerts           []github.CodeScanningAlert
+		expectedExitCode int
+		wantHeaders      []string
+		wantRows         [][]string
+	}{
+		{
+			name:             "only alerts on the base branch",
+			alerts:           []github.CodeScanningAlert{existing},
+			expectedExitCode: zero,
+			wantHeaders:      []string{"Status"},
+			wantRows:         [][]string{{"No new code scanning alerts"}},
+		},
+		{
+			name:             "new alert",
+			alerts:           []github.CodeScanningAlert{existing, added},
+			expectedExitCode: one,
+			wantHeaders:      []string{"Severity", "New Alerts"},
+			wantRows:         [][]string{{"High", "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			table := &mockTableWriter{}
+			check := &codeScanningCheck{
+				codeScanningConfig: codeScanningConfig{
+					ciConfig:       ciConfig{owner: "owner", repo: "repo", ref: "refs/pull/12/head"},
+					failOnSeverity: github.SeverityHigh,
+				},
+				githubClient: &fakeCodeScanningClient{
+					analyses:   []github.CodeScanningAnalysis{{Tool: "CodeQL", Ref: "refs/pull/12/merge", CommitSHA: "abc123"}},
+					alerts:     tt.alerts,
+					baseAlerts: []github.CodeScanningAlert{existing},
+				},
+				table:  table,
+				logger: testLogger,
+			}
+
+			err := check.Check(context.Background())
+
+			var exitErr cli.ExitCoder
+			require.ErrorAs(t, err, &exitErr)
+			require.Equal(t, tt.expectedExitCode, exitErr.ExitCode())
+			require.Equal(t, tt.wantHeaders, table.headers)
+			require.Equal(t, tt.wantRows, table.rows)
+		})
+	}
+}
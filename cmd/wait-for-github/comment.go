@@ -0,0 +1,156 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	// the ref a PR URL given to `ci` is turned into
+	pullRefRegexp = regexp.MustCompile(`^refs/pull/(\d+)/head$`)
+)
+
+// prFromRef returns the number of the PR a ref is the head of, or 0 if it
+// isn't one.
+func prFromRef(ref string) int {
+	match := pullRefRegexp.FindStringSubmatch(ref)
+	if match == nil {
+		return 0
+	}
+
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// commentMarker is hidden in the comment, so that the next run for the same
+// command and key updates it rather than adding another one.
+func commentMarker(command, key string) string {
+	if key == "" {
+		return fmt.Sprintf("<!-- wait-for-github:%s -->", command)
+	}
+
+	return fmt.Sprintf("<!-- wait-for-github:%s:%s -->", command, key)
+}
+
+// commentKey returns a key telling apart runs of the same command which wait
+// for different checks on the same PR, e.g. from different jobs, so that they
+// don't overwrite each other's comment. It's empty when waiting for all of
+// them.
+func commentKey(checks, excludes []string) string {
+	if len(checks) == 0 && len(excludes) == 0 {
+		return ""
+	}
+
+	checks, excludes = slices.Sorted(slices.Values(checks)), slices.Sorted(slices.Values(excludes))
+	sum := sha256.Sum256([]byte(strings.Join(checks, "\n") + "\x00" + strings.Join(excludes, "\n")))
+
+	return hex.EncodeToString(sum[:4])
+}
+
+// resultComment is what `--comment` posts on the PR.
+type resultComment struct {
+	command string
+	// key is from commentKey
+	key     string
+	status  string
+	sha     string
+	records []checkRecord
+	// excludes are the checks which don't affect the result
+	excludes   []string
+	reruns     int
+	mergeError error
+}
+
+// body returns the comment in markdown.
+func (c resultComment) body() string {
+	var b strings.Builder
+	b.WriteString(commentMarker(c.command, c.key) + "\n")
+	fmt.Fprintf(&b, "### wait-for-github `%s`: %s\n\n", c.command, c.status)
+
+	if c.sha != "" {
+		fmt.Fprintf(&b, "Commit: %s\n\n", c.sha)
+	}
+
+	var failed []checkRecord
+	for _, record := range c.records {
+		if record.Status == github.CIStatusFailed.Name() && !slices.Contains(c.excludes, record.Name) {
+			failed = append(failed, record)
+		}
+	}
+
+	if len(failed) > 0 {
+		b.WriteString("| Failed check | Conclusion | Details |\n")
+		b.WriteString("| --- | --- | --- |\n")
+		for _, record := range failed {
+			name := record.Name
+			if record.Workflow != "" {
+				name = record.Workflow + " / " + name
+			}
+
+			details := ""
+			if record.DetailsURL != "" {
+				details = fmt.Sprintf("[Details](%s)", record.DetailsURL)
+			}
+
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownEscaper.Replace(name), record.Conclusion, details)
+		}
+		b.WriteString("\n")
+	}
+
+	if c.reruns > 0 {
+		fmt.Fprintf(&b, "Failed workflows were rerun %d time(s).\n\n", c.reruns)
+	}
+
+	if c.mergeError != nil {
+		fmt.Fprintf(&b, "Auto-merge failed:\n\n```\n%s\n```\n", c.mergeError)
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// postResultComment posts the comment on the PR, or updates the one a
+// previous run posted.
+func postResultComment(ctx context.Context, client github.UpsertPRComment, owner, repo string, pr int, comment resultComment) error {
+	return client.UpsertPRComment(ctx, owner, repo, pr, commentMarker(comment.command, comment.key), comment.body())
+}
+
+// commentFlag returns the --comment flag, for commands waiting on target.
+func commentFlag(target string) *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name: "comment",
+		Usage: fmt.Sprintf("Once done waiting, post a comment summarising the result on %s, "+
+			"or update the one a previous run posted.", target),
+		Sources: cli.NewValueSourceChain(
+			cli.EnvVar("GITHUB_PR_COMMENT"),
+		),
+	}
+}
@@ -0,0 +1,146 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPRFromRef(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 12, prFromRef("refs/pull/12/head"))
+	require.Equal(t, 0, prFromRef("refs/pull/12/merge"))
+	require.Equal(t, 0, prFromRef("abc123"))
+}
+
+func TestResultCommentBody(t *testing.T) {
+	t.Parallel()
+
+	comment := resultComment{
+		command: "pr",
+		status:  prStatusFailed,
+		sha:     "abc123",
+		records: []checkRecord{
+			{Name: "build", Status: github.CIStatusPassed.Name(), Conclusion: "SUCCESS"},
+			{Name: "lint | vet", Workflow: "CI", Status: github.CIStatusFailed.Name(), Conclusion: "FAILURE", DetailsURL: "https://example.com/lint"},
+			{Name: "flaky", Status: github.CIStatusFailed.Name(), Conclusion: "FAILURE"},
+		},
+		excludes:   []string{"flaky"},
+		reruns:     2,
+		mergeError: errors.New("failed to merge PR: 405 Base branch was modified"),
+	}
+
+	require.Equal(t, "<!-- wait-for-github:pr -->\n"+
+		"### wait-for-github `pr`: failed\n\n"+
+		"Commit: abc123\n\n"+
+		"| Failed check | Conclusion | Details |\n"+
+		"| --- | --- | --- |\n"+
+		"| CI / lint \\| vet | FAILURE | [Details](https://example.com/lint) |\n\n"+
+		"Failed workflows were rerun 2 time(s).\n\n"+
+		"Auto-merge failed:\n\n"+
+		"```\nfailed to merge PR: 405 Base branch was modified\n```\n", comment.body())
+
+	comment = resultComment{command: "ci", status: "passed", sha: "abc123"}
+	require.Equal(t, "<!-- wait-for-github:ci -->\n"+
+		"### wait-for-github `ci`: passed\n\n"+
+		"Commit: abc123\n", comment.body())
+}
+
+func TestCommentKey(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, commentKey(nil, nil))
+	require.Equal(t, "<!-- wait-for-github:ci -->", commentMarker("ci", commentKey(nil, nil)))
+
+	key := commentKey([]string{"build", "lint"}, []string{"flaky"})
+	require.Len(t, key, 8)
+	require.Equal(t, key, commentKey([]string{"lint", "build"}, []string{"flaky"}), "the order shouldn't matter")
+	require.NotEqual(t, key, commentKey([]string{"build"}, []string{"flaky"}))
+	require.NotEqual(t, key, commentKey([]string{"build", "lint", "flaky"}, nil))
+	require.Equal(t, "<!-- wait-for-github:ci:"+key+" -->", commentMarker("ci", key))
+}
+
+func TestCIComment(t *testing.T) {
+	t.Parallel()
+
+	client := &FakeCIStatusChecker{
+		status: github.CIStatusFailed,
+		sha:    "abc123",
+		checks: annotatedChecks,
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "refs/pull/12/head", excludes: []string{"flaky"}, commentPR: 12}
+
+	var out bytes.Buffer
+	require.Error(t, checkCIStatus(context.Background(), client, cfg, ciConf, &out))
+	require.Contains(t, client.comments, 12)
+	require.True(t, strings.HasPrefix(client.comments[12], commentMarker("ci", commentKey(nil, []string{"flaky"}))+"\n"))
+	require.Contains(t, client.comments[12], "### wait-for-github `ci`: failed")
+	require.Contains(t, client.comments[12], "| lint | failure |")
+	require.NotContains(t, client.comments[12], "flaky")
+}
+
+func TestPRCommentMergeError(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeGithubClientPRCheck{
+		HeadSHA:      "abc123",
+		CIStatus:     github.CIStatusPassed,
+		mergePRError: github.ErrMergeConflict,
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	prConf := &prConfig{owner: "owner", repo: "repo", pr: 1, autoMerge: true, autoMergeMethod: "squash", comment: true}
+
+	var out bytes.Buffer
+	require.Error(t, checkPRMerged(context.Background(), client, cfg, prConf, &out))
+	require.Contains(t, client.Comments[1], "### wait-for-github `pr`: conflict")
+	require.Contains(t, client.Comments[1], "Auto-merge failed:\n\n```\n"+github.ErrMergeConflict.Error()+"\n```")
+}
+
+func TestNoCommentUnlessAsked(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeGithubClientPRCheck{
+		HeadSHA:  "abc123",
+		CIStatus: github.CIStatusFailed,
+		Checks:   annotatedChecks,
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	prConf := &prConfig{owner: "owner", repo: "repo", pr: 1}
+
+	var out bytes.Buffer
+	require.Error(t, checkPRMerged(context.Background(), client, cfg, prConf, &out))
+	require.Empty(t, client.Comments)
+}
@@ -17,16 +17,60 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"time"
 
+	"github.com/grafana/wait-for-github/internal/actions"
 	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/logging"
+	"github.com/grafana/wait-for-github/internal/notify"
+	"github.com/grafana/wait-for-github/internal/utils"
 )
 
 type config struct {
 	github.AuthInfo
-	recheckInterval    time.Duration
-	pendingRecheckTime time.Duration
-	globalTimeout      time.Duration
-	logger             *slog.Logger
+	recheckInterval   time.Duration
+	checksGracePeriod time.Duration
+	requireChecks     bool
+	useRepoConfig     bool
+	globalTimeout     time.Duration
+	logger            *slog.Logger
+	// logOptions are what logger was set up with.
+	logOptions logging.Options
+	// actions is the GitHub Actions step being run, if any, to write outputs
+	// and a job summary to.
+	actions *actions.Step
+
+	// fastRecheckPeriod, maxRecheckInterval and recheckJitter make the
+	// recheck interval adapt to how often things change.
+	fastRecheckPeriod  time.Duration
+	maxRecheckInterval time.Duration
+	recheckJitter      float64
+
+	// notify are where to send a notification to when the wait ends, for the
+	// outcomes in notifyOn. summary is what goes in it, which commands fill
+	// in as they go.
+	notify   []notify.Sink
+	notifyOn []notify.Outcome
+	summary  waitSummary
+
+	// shutdownTracing sends the spans which haven't been sent yet, if traces
+	// are being exported.
+	shutdownTracing func(context.Context) error
+
+	// configFile and profile are what was loaded with --config and
+	// --profile, for applying to the command being run.
+	configFile *configFile
+	profile    profile
+}
+
+// pollStrategy returns how often to recheck GitHub.
+func (cfg *config) pollStrategy() utils.PollStrategy {
+	return utils.PollStrategy{
+		Interval:    cfg.recheckInterval,
+		FastPeriod:  cfg.fastRecheckPeriod,
+		MaxInterval: cfg.maxRecheckInterval,
+		Jitter:      cfg.recheckJitter,
+	}
 }
@@ -0,0 +1,251 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// profile holds values for flags, keyed by the flag's name. Values are scalars
+// or, for flags which can be given multiple times, lists.
+type profile map[string]any
+
+// configFile is the contents of the config file.
+type configFile struct {
+	// DefaultProfile is used when no profile is selected with --profile.
+	DefaultProfile string             `yaml:"default_profile"`
+	Profiles       map[string]profile `yaml:"profiles"`
+	// Repos holds overrides for command flags, keyed by `owner/repo`.
+	Repos map[string]profile `yaml:"repos"`
+}
+
+// defaultConfigPath returns where the config file is looked for when --config
+// isn't given, following the XDG base directory specification.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "wait-for-github", "config.yaml")
+}
+
+// loadConfigFile reads the config file at path. If the file doesn't exist and
+// mustExist is false, an empty config is returned.
+func loadConfigFile(path string, mustExist bool) (*configFile, error) {
+	var file configFile
+	if path == "" {
+		return &file, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) && !mustExist {
+		return &file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return &file, nil
+}
+
+// profile returns the profile called name, or the default profile if name is
+// empty. It returns nil if neither is set.
+func (f *configFile) profile(name string) (profile, error) {
+	if name == "" {
+		name = f.DefaultProfile
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	p, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found in config file", name)
+	}
+
+	return p, nil
+}
+
+// repo returns the overrides for owner/repo, or nil if there are none.
+// Repository names are case-insensitive, like on GitHub.
+func (f *configFile) repo(ownerRepo string) profile {
+	for name, p := range f.Repos {
+		if strings.EqualFold(name, ownerRepo) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// validate checks that every key in the file is the name of a flag of cmd or
+// one of its subcommands, so that typos don't go unnoticed. Repository
+// overrides are applied by the subcommands, after cmd's own flags have been
+// used, so they can only have the subcommands' flags.
+func (f *configFile) validate(cmd *cli.Command) error {
+	known := flagNames(cmd)
+
+	commandFlags := make(map[string]bool)
+	for _, sub := range cmd.Commands {
+		maps.Copy(commandFlags, flagNames(sub))
+	}
+
+	// the keys of p which aren't in known, in order
+	check := func(p profile, known map[string]bool) []string {
+		var unknown []string
+		for key := range p {
+			if !known[key] {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+
+		return unknown
+	}
+
+	for name, p := range f.Profiles {
+		if unknown := check(p, known); len(unknown) > 0 {
+			return fmt.Errorf("profile %q has unknown options: %s", name, strings.Join(unknown, ", "))
+		}
+	}
+	for name, p := range f.Repos {
+		if unknown := check(p, known); len(unknown) > 0 {
+			return fmt.Errorf("repo %q has unknown options: %s", name, strings.Join(unknown, ", "))
+		}
+		if global := check(p, commandFlags); len(global) > 0 {
+			return fmt.Errorf("repo %q has global options, which can only be set in a profile: %s", name, strings.Join(global, ", "))
+		}
+	}
+
+	return nil
+}
+
+func flagNames(cmd *cli.Command) map[string]bool {
+	names := make(map[string]bool)
+	for _, flag := range cmd.Flags {
+		for _, name := range flag.Names() {
+			names[name] = true
+		}
+	}
+	for _, sub := range cmd.Commands {
+		for name := range flagNames(sub) {
+			names[name] = true
+		}
+	}
+
+	return names
+}
+
+// applyProfile sets cmd's flags from p, except for the flags which were given
+// on the command line or in the environment, or were set by an earlier call.
+// This is what gives flags and environment variables precedence over the
+// config file. Keys which aren't flags of cmd are for other commands, and are
+// skipped.
+func applyProfile(cmd *cli.Command, p profile) error {
+	for _, flag := range cmd.Flags {
+		names := flag.Names()
+		idx := slices.IndexFunc(names, func(name string) bool {
+			_, ok := p[name]
+			return ok
+		})
+		if idx < 0 || cmd.IsSet(names[0]) {
+			continue
+		}
+
+		value := p[names[idx]]
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+
+		for _, v := range values {
+			if err := cmd.Set(names[0], fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("invalid value %v for %s in config file: %w", v, names[0], err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// targetRepo returns the `owner/repo` that cmd's arguments refer to, so that
+// the repository's overrides can be applied. It returns false if there isn't
+// exactly one repository.
+func targetRepo(cmd *cli.Command) (string, bool) {
+	args := cmd.Args().Slice()
+
+	urls := targetURLs(cmd)
+	if urls == nil {
+		if len(args) >= 2 && !looksLikeURL(args[0]) {
+			return args[0] + "/" + args[1], true
+		}
+		urls = args
+	}
+
+	var repos []string
+	for _, u := range urls {
+		if !looksLikeURL(u) {
+			continue
+		}
+
+		parsed, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+
+		parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		if len(parts) < 2 {
+			continue
+		}
+
+		repo := parts[0] + "/" + parts[1]
+		if !slices.ContainsFunc(repos, func(r string) bool { return strings.EqualFold(r, repo) }) {
+			repos = append(repos, repo)
+		}
+	}
+
+	if len(repos) != 1 {
+		return "", false
+	}
+
+	return repos[0], true
+}
@@ -0,0 +1,194 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+const testConfigFile = `
+default_profile: default
+profiles:
+  default:
+    recheck-interval: 1m
+    exclude: [profile-check]
+    action-retries: 2
+  ghes:
+    github-url: https://github.example.com
+    recheck-interval: 5m
+repos:
+  Owner/Special:
+    exclude: [repo-check-1, repo-check-2]
+`
+
+func TestConfigFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0o600))
+
+	type result struct {
+		recheckInterval time.Duration
+		githubURL       string
+		excludes        []string
+		actionRetries   int
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    result
+		wantErr string
+	}{
+		{
+			name: "default profile",
+			args: []string{"--config", path, "ci", "owner", "repo", "main"},
+			want: result{recheckInterval: time.Minute, excludes: []string{"profile-check"}, actionRetries: 2},
+		},
+		{
+			name: "selected profile",
+			args: []string{"--config", path, "--profile", "ghes", "ci", "owner", "repo", "main"},
+			want: result{recheckInterval: 5 * time.Minute, githubURL: "https://github.example.com", excludes: []string{}},
+		},
+		{
+			name: "flags take precedence",
+			args: []string{"--config", path, "--recheck-interval", "10s", "ci", "--exclude", "flag-check", "--action-retries", "0", "owner", "repo", "main"},
+			want: result{recheckInterval: 10 * time.Second, excludes: []string{"flag-check"}},
+		},
+		{
+			name: "repository overrides take precedence over the profile",
+			args: []string{"--config", path, "ci", "https://github.com/owner/special/commit/abc123"},
+			want: result{recheckInterval: time.Minute, excludes: []string{"repo-check-1", "repo-check-2"}, actionRetries: 2},
+		},
+		{
+			name:    "unknown profile",
+			args:    []string{"--config", path, "--profile", "missing", "ci", "owner", "repo", "main"},
+			wantErr: `profile "missing" not found in config file`,
+		},
+		{
+			name:    "missing config file",
+			args:    []string{"--config", filepath.Join(t.TempDir(), "missing.yaml"), "ci", "owner", "repo", "main"},
+			wantErr: "failed to open config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got result
+			rootCmd := root()
+			rootCmd.Writer = io.Discard
+			rootCmd.ErrWriter = io.Discard
+			for _, cmd := range rootCmd.Commands {
+				cmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+					got = result{
+						recheckInterval: cmd.Duration("recheck-interval"),
+						githubURL:       cmd.String("github-url"),
+						excludes:        cmd.StringSlice("exclude"),
+						actionRetries:   cmd.Int("action-retries"),
+					}
+					return nil
+				}
+			}
+
+			args := append([]string{"wait-for-github", "--github-token", "ghp_0123456789"}, tt.args...)
+			err := rootCmd.Run(t.Context(), args)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigFileUnknownOption(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("profiles:\n  default:\n    recheck-intervl: 1m\n"), 0o600))
+
+	file, err := loadConfigFile(path, true)
+	require.NoError(t, err)
+	require.EqualError(t, file.validate(root()), `profile "default" has unknown options: recheck-intervl`)
+}
+
+func TestLoadConfigFileMissingDefault(t *testing.T) {
+	t.Parallel()
+
+	file, err := loadConfigFile(filepath.Join(t.TempDir(), "config.yaml"), false)
+	require.NoError(t, err)
+	require.Empty(t, file.Profiles)
+}
+
+func TestTargetRepo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		args   []string
+		want   string
+		wantOK bool
+	}{
+		{name: "owner and repo", args: []string{"owner", "repo", "main"}, want: "owner/repo", wantOK: true},
+		{name: "URL", args: []string{"https://github.com/owner/repo/pull/1"}, want: "owner/repo", wantOK: true},
+		{name: "URLs for the same repository", args: []string{"https://github.com/owner/repo/pull/1", "https://github.com/Owner/Repo/pull/2"}, want: "owner/repo", wantOK: true},
+		{name: "URLs for different repositories", args: []string{"https://github.com/owner/repo/pull/1", "https://github.com/owner/other/pull/2"}},
+		{name: "no repository", args: []string{"manifest.yaml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := &cli.Command{
+				Name:  "test",
+				Flags: targetFlags("A target."),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					got, ok := targetRepo(cmd)
+					require.Equal(t, tt.want, got)
+					require.Equal(t, tt.wantOK, ok)
+					return nil
+				},
+			}
+
+			require.NoError(t, cmd.Run(t.Context(), append([]string{"test"}, tt.args...)))
+		})
+	}
+}
+
+func TestConfigFileRepoGlobalOption(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("repos:\n  grafana/loki:\n    timeout: 1h\n    exclude: [slow-check]\n    recheck-interval: 1m\n"), 0o600))
+
+	file, err := loadConfigFile(path, true)
+	require.NoError(t, err)
+	require.EqualError(t, file.validate(root()), `repo "grafana/loki" has global options, which can only be set in a profile: recheck-interval, timeout`)
+}
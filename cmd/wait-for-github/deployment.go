@@ -0,0 +1,76 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/urfave/cli/v3"
+)
+
+type deploymentConfig struct {
+	owner string
+	repo  string
+
+	sha         string
+	environment string
+}
+
+type deploymentCheck struct {
+	deploymentConfig
+	githubClient github.GetDeploymentStatus
+	logger       *slog.Logger
+
+	// set once the deployment has succeeded
+	found *github.Deployment
+}
+
+func (d *deploymentCheck) Check(ctx context.Context) error {
+	deployment, err := d.githubClient.GetDeploymentStatus(ctx, d.owner, d.repo, d.sha, d.environment)
+	if err != nil {
+		return err
+	}
+
+	if deployment == nil {
+		d.logger.InfoContext(ctx, "commit not deployed yet")
+		return nil
+	}
+
+	logger := d.logger.With("deployment_id", deployment.ID)
+
+	switch deployment.State {
+	case github.DeploymentStateSuccess:
+		logger.InfoContext(ctx, "deployment succeeded", "url", deployment.EnvironmentURL)
+		d.found = deployment
+		return cli.Exit(fmt.Sprintf("Deployment to %s succeeded", d.environment), utils.ExitSuccess)
+
+	case github.DeploymentStateFailure, github.DeploymentStateError:
+		return cli.Exit(fmt.Sprintf("Deployment to %s failed", d.environment), utils.ExitFailed)
+
+	// a newer deployment has replaced this one, so the commit is no longer
+	// what's deployed there
+	case github.DeploymentStateInactive:
+		return cli.Exit(fmt.Sprintf("Deployment to %s is inactive", d.environment), utils.ExitFailed)
+	}
+
+	logger.InfoContext(ctx, "deployment not finished yet", "state", deployment.State)
+	return nil
+}
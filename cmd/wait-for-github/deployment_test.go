@@ -0,0 +1,130 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// fakeDeploymentClient implements the GetDeploymentStatus interface
+type fakeDeploymentClient struct {
+	deployment *github.Deployment
+	err        error
+
+	// the commit and environment which were asked for
+	sha         string
+	environment string
+}
+
+func (f *fakeDeploymentClient) GetDeploymentStatus(ctx context.Context, owner, repo, sha, environment string) (*github.Deployment, error) {
+	f.sha = sha
+	f.environment = environment
+	return f.deployment, f.err
+}
+
+func TestDeploymentCheck(t *testing.T) {
+	t.Parallel()
+
+	deployment := func(state string) *github.Deployment {
+		return &github.Deployment{ID: 1, Environment: "production", State: state}
+	}
+
+	tests := []struct {
+		name             string
+		client           fakeDeploymentClient
+		expectedExitCode *int
+		expectErr        bool
+	}{
+		{
+			name:   "not deployed yet",
+			client: fakeDeploymentClient{},
+		},
+		{
+			name:   "deployment pending",
+			client: fakeDeploymentClient{deployment: deployment(github.DeploymentStatePending)},
+		},
+		{
+			name:   "deployment in progress",
+			client: fakeDeploymentClient{deployment: deployment(github.DeploymentStateInProgress)},
+		},
+		{
+			name:             "deployment succeeded",
+			client:           fakeDeploymentClient{deployment: deployment(github.DeploymentStateSuccess)},
+			expectedExitCode: &zero,
+		},
+		{
+			name:             "deployment failed",
+			client:           fakeDeploymentClient{deployment: deployment(github.DeploymentStateFailure)},
+			expectedExitCode: &one,
+		},
+		{
+			name:             "deployment errored",
+			client:           fakeDeploymentClient{deployment: deployment(github.DeploymentStateError)},
+			expectedExitCode: &one,
+		},
+		{
+			name:             "deployment inactive",
+			client:           fakeDeploymentClient{deployment: deployment(github.DeploymentStateInactive)},
+			expectedExitCode: &one,
+		},
+		{
+			name:      "error getting deployment",
+			client:    fakeDeploymentClient{err: fmt.Errorf("an error occurred")},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			check := &deploymentCheck{
+				deploymentConfig: deploymentConfig{
+					owner:       "owner",
+					repo:        "repo",
+					sha:         "abc123",
+					environment: "production",
+				},
+				githubClient: &tt.client,
+				logger:       testLogger,
+			}
+
+			err := check.Check(context.Background())
+
+			switch {
+			case tt.expectErr:
+				require.Error(t, err)
+			case tt.expectedExitCode != nil:
+				var exitErr cli.ExitCoder
+				require.ErrorAs(t, err, &exitErr)
+				require.Equal(t, *tt.expectedExitCode, exitErr.ExitCode())
+			default:
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, "abc123", tt.client.sha)
+			require.Equal(t, "production", tt.client.environment)
+			require.Equal(t, tt.expectedExitCode == &zero, check.found != nil, "the deployment should only be kept once it has succeeded")
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/wait-for-github/internal/actions"
+	"github.com/grafana/wait-for-github/internal/github"
+)
+
+// defaultFailureLogLines is how many lines --show-failure-logs shows when it's
+// given without a number.
+const defaultFailureLogLines = 50
+
+// logLines is the value of --show-failure-logs. It can be given on its own,
+// like a boolean flag, or with a number of lines.
+type logLines int
+
+func (l *logLines) Set(value string) error {
+	if b, err := strconv.ParseBool(value); err == nil {
+		*l = 0
+		if b {
+			*l = defaultFailureLogLines
+		}
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid number of lines %q", value)
+	}
+
+	*l = logLines(n)
+	return nil
+}
+
+func (l *logLines) String() string {
+	return strconv.Itoa(int(*l))
+}
+
+func (l *logLines) Get() any {
+	return int(*l)
+}
+
+func (l *logLines) IsBoolFlag() bool {
+	return true
+}
+
+// showFailureDetails writes why each failed check failed: the end of the log
+// of the failed step for GitHub Actions jobs, and the output for other check
+// runs. Details which can't be fetched are skipped, as they're only a
+// convenience. In GitHub Actions, workflow commands are stopped while they're
+// written, as they come from other workflows and apps.
+func showFailureDetails(ctx context.Context, client github.GetFailureDetails, logger *slog.Logger, out io.Writer, ciConf *ciConfig, checks []github.CICheckStatus, inActions bool) {
+	failed := failedCheckRuns(checks, ciConf.checks, ciConf.excludes)
+	if len(failed) == 0 {
+		return
+	}
+
+	if inActions {
+		stop, start, err := actions.StopCommands()
+		if err != nil {
+			logger.WarnContext(ctx, "not showing details of failed checks", "error", err)
+			return
+		}
+
+		fmt.Fprintln(out, stop)
+		defer fmt.Fprintln(out, start)
+	}
+
+	for _, run := range failed {
+		name := checkRunName(run)
+
+		var err error
+		if run.CheckSuite.WorkflowRun.DatabaseID != 0 {
+			err = showJobLog(ctx, client, out, ciConf, name, run.DatabaseID)
+		} else {
+			err = showCheckRunOutput(ctx, client, out, ciConf, name, run.DatabaseID)
+		}
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get details of failed check", "check", name, "error", err)
+		}
+	}
+}
+
+// failedCheckRuns returns the failed check runs the overall status depends on:
+// the ones waited for if there are any, and otherwise the ones not excluded.
+func failedCheckRuns(checks []github.CICheckStatus, waitedFor, excludes []string) []github.CheckRunDetails {
+	var failed []github.CheckRunDetails
+	for _, check := range checks {
+		run, ok := checkRunDetails(check)
+		if !ok || check.Outcome() != github.CIStatusFailed {
+			continue
+		}
+
+		if len(waitedFor) > 0 && !slices.Contains(waitedFor, run.Name) {
+			continue
+		}
+		if len(waitedFor) == 0 && slices.Contains(excludes, run.Name) {
+			continue
+		}
+
+		failed = append(failed, run)
+	}
+
+	return failed
+}
+
+// checkRunName returns the name of the check run, prefixed by its workflow if
+// it has one, without any formatting.
+func checkRunName(run github.CheckRunDetails) string {
+	if workflow := run.CheckSuite.WorkflowRun.Workflow.Name; workflow != "" {
+		return workflow + " / " + run.Name
+	}
+
+	return run.Name
+}
+
+func showJobLog(ctx context.Context, client github.GetFailureDetails, out io.Writer, ciConf *ciConfig, name string, jobID int64) error {
+	log, err := client.GetFailedStepLog(ctx, ciConf.owner, ciConf.repo, jobID, ciConf.failureLogLines)
+	if err != nil {
+		return err
+	}
+
+	if log.Step != "" {
+		fmt.Fprintf(out, "==> %s: step %q failed\n", name, log.Step)
+	} else {
+		fmt.Fprintf(out, "==> %s: failed\n", name)
+	}
+
+	for _, line := range log.Lines {
+		fmt.Fprintln(out, line)
+	}
+	fmt.Fprintln(out)
+
+	return nil
+}
+
+func showCheckRunOutput(ctx context.Context, client github.GetFailureDetails, out io.Writer, ciConf *ciConfig, name string, checkRunID int64) error {
+	output, err := client.GetCheckRunOutput(ctx, ciConf.owner, ciConf.repo, checkRunID)
+	if err != nil {
+		return err
+	}
+
+	if output.Title != "" {
+		fmt.Fprintf(out, "==> %s: %s\n", name, output.Title)
+	} else {
+		fmt.Fprintf(out, "==> %s: failed\n", name)
+	}
+
+	if summary := strings.TrimSpace(output.Summary); summary != "" {
+		fmt.Fprintln(out, summary)
+	}
+	fmt.Fprintln(out)
+
+	return nil
+}
@@ -0,0 +1,172 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/actions"
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLines(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    int
+		wantErr string
+	}{
+		{value: "true", want: defaultFailureLogLines},
+		{value: "false", want: 0},
+		{value: "20", want: 20},
+		{value: "-1", wantErr: `invalid number of lines "-1"`},
+		{value: "lots", wantErr: `invalid number of lines "lots"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			var l logLines
+			err := l.Set(tt.value)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, l.Get())
+		})
+	}
+}
+
+func TestShowFailureLogs(t *testing.T) {
+	t.Parallel()
+
+	actionsSuite := github.CheckSuiteInfo{
+		App:         github.AppInfo{Name: "GitHub Actions"},
+		WorkflowRun: github.WorkflowRunInfo{DatabaseID: 1, Workflow: github.WorkflowInfo{Name: "CI"}},
+	}
+	client := &FakeCIStatusChecker{
+		status: github.CIStatusFailed,
+		sha:    "abc123",
+		checks: []github.CICheckStatus{
+			github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 10, Name: "build", Status: "COMPLETED", Conclusion: "SUCCESS", CheckSuite: actionsSuite}},
+			github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 11, Name: "test", Status: "COMPLETED", Conclusion: "FAILURE", CheckSuite: actionsSuite}},
+			github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 12, Name: "flaky", Status: "COMPLETED", Conclusion: "FAILURE", CheckSuite: actionsSuite}},
+			github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 20, Name: "lint", Status: "COMPLETED", Conclusion: "FAILURE"}},
+			github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 21, Name: "gone", Status: "COMPLETED", Conclusion: "FAILURE"}},
+			github.StatusContext{Context: "ci/jenkins", State: "FAILURE"},
+		},
+		stepLogs: map[int64]github.FailedStepLog{
+			11: {Job: "test", Step: "Run tests", Lines: []string{"--- FAIL: TestSomething", "FAIL"}},
+			12: {Job: "flaky", Lines: []string{"should not be shown"}},
+		},
+		checkRunOutputs: map[int64]github.CheckRunOutput{
+			20: {Title: "2 problems", Summary: "Lint found 2 problems\n"},
+		},
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{
+		owner:           "owner",
+		repo:            "repo",
+		ref:             "main",
+		excludes:        []string{"flaky"},
+		failureLogLines: 2,
+	}
+
+	var out bytes.Buffer
+	err := checkCIStatus(context.Background(), client, cfg, ciConf, &out)
+	require.Error(t, err)
+
+	// the details of "gone" can't be fetched, so are left out
+	require.Equal(t, `==> CI / test: step "Run tests" failed
+--- FAIL: TestSomething
+FAIL
+
+==> lint: 2 problems
+Lint found 2 problems
+
+`, out.String())
+}
+
+// TestShowFailureLogsStopsCommands tests that, in GitHub Actions, workflow
+// commands in the logs of failed checks aren't acted on.
+func TestShowFailureLogsStopsCommands(t *testing.T) {
+	t.Parallel()
+
+	client := &FakeCIStatusChecker{
+		status: github.CIStatusFailed,
+		checks: []github.CICheckStatus{
+			github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 20, Name: "lint", Status: "COMPLETED", Conclusion: "FAILURE"}},
+		},
+		checkRunOutputs: map[int64]github.CheckRunOutput{
+			20: {Title: "1 problem", Summary: "::add-mask::something\n::error::injected"},
+		},
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+		actions:         &actions.Step{},
+	}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main", failureLogLines: 50}
+
+	var out bytes.Buffer
+	require.Error(t, checkCIStatus(context.Background(), client, cfg, ciConf, &out))
+
+	lines := strings.Split(out.String(), "\n")
+	require.Regexp(t, `^::stop-commands::[0-9a-f]{32}$`, lines[0])
+	token := strings.TrimPrefix(lines[0], "::stop-commands::")
+
+	require.Equal(t, []string{
+		"==> lint: 1 problem",
+		"::add-mask::something",
+		"::error::injected",
+		"",
+		"::" + token + "::",
+	}, lines[1:6])
+}
+
+func TestShowFailureLogsOnlyOnFailure(t *testing.T) {
+	t.Parallel()
+
+	client := &FakeCIStatusChecker{
+		status: github.CIStatusPassed,
+		checks: []github.CICheckStatus{
+			github.CheckRunDetails{CheckRun: github.CheckRun{DatabaseID: 20, Name: "lint", Status: "COMPLETED", Conclusion: "FAILURE"}},
+		},
+		checkRunOutputs: map[int64]github.CheckRunOutput{20: {Title: "should not be shown"}},
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main", failureLogLines: 50}
+
+	var out bytes.Buffer
+	require.Error(t, checkCIStatus(context.Background(), client, cfg, ciConf, &out))
+	require.Empty(t, out.String())
+}
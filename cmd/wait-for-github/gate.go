@@ -0,0 +1,232 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/urfave/cli/v3"
+)
+
+// What `ci --report-as` reports the gate as.
+const (
+	gateTypeCheckRun = "check-run"
+	gateTypeStatus   = "status"
+)
+
+// gateTypes returns the valid values for --report-as-type.
+func gateTypes() []string {
+	return []string{gateTypeCheckRun, gateTypeStatus}
+}
+
+type gateClient interface {
+	github.ReportGate
+	github.GetDetailedCIStatus
+}
+
+// ciGate is the check run or commit status `ci --report-as` keeps up to date
+// with the checks it's waiting for.
+type ciGate struct {
+	client gateClient
+	logger *slog.Logger
+	ciConf *ciConfig
+	sha    string
+
+	checkRunID int64
+	// last is what was last reported, so that unchanged polls don't report
+	// the same again
+	last *github.Gate
+	// seen is the status of each check when the gate was last refreshed
+	// while waiting
+	seen map[string]github.CIStatus
+}
+
+// report sets the gate to status, with the checks which are pending or
+// failed on its summary.
+func (g *ciGate) report(ctx context.Context, status github.CIStatus) error {
+	checks, err := g.client.GetDetailedCIStatus(ctx, g.ciConf.owner, g.ciConf.repo, g.sha)
+	if err != nil {
+		return fmt.Errorf("failed to get checks for gate: %w", err)
+	}
+
+	pending, failed := gateRecords(checks, g.ciConf.checks, g.ciConf.excludes)
+	gate := github.Gate{
+		Name:    g.ciConf.reportAs,
+		Status:  status,
+		Title:   gateTitle(status, pending, failed),
+		Summary: gateSummary(pending, failed),
+	}
+
+	if g.last != nil && *g.last == gate {
+		return nil
+	}
+
+	switch g.ciConf.reportAsType {
+	case gateTypeStatus:
+		err = g.client.ReportGateStatus(ctx, g.ciConf.owner, g.ciConf.repo, g.sha, gate)
+	default:
+		g.checkRunID, err = g.client.ReportGateCheckRun(ctx, g.ciConf.owner, g.ciConf.repo, g.sha, g.checkRunID, gate)
+	}
+	if err != nil {
+		return err
+	}
+
+	g.logger.DebugContext(ctx, "reported gate", "gate", gate.Name, "status", status.Name())
+	g.last = &gate
+	return nil
+}
+
+// gatedCheck updates the gate each time CI is checked, for as long as the
+// wait goes on.
+type gatedCheck struct {
+	ciCheck
+	gate *ciGate
+}
+
+func (c gatedCheck) Check(ctx context.Context) error {
+	var states github.CheckStates
+	err := c.ciCheck.Check(github.WithGate(github.WithCheckStates(ctx, &states), c.gate.ciConf.reportAs))
+	if err != nil {
+		return err
+	}
+
+	// getting the checks for the summary takes more queries, so it's only
+	// refreshed once any of them change
+	statuses := states.Statuses()
+	if maps.Equal(statuses, c.gate.seen) {
+		return nil
+	}
+
+	// failing to update the gate while waiting isn't worth stopping for, as
+	// it's updated again on the next poll and at the end
+	if gateErr := c.gate.report(ctx, github.CIStatusPending); gateErr != nil {
+		c.gate.logger.WarnContext(ctx, "failed to update gate", "error", gateErr)
+		return nil
+	}
+
+	c.gate.seen = statuses
+	return nil
+}
+
+// gateRecords returns the checks the overall status depends on which are
+// still pending, and those which failed.
+func gateRecords(checks []github.CICheckStatus, waitedFor, excludes []string) (pending, failed []checkRecord) {
+	for _, check := range checks {
+		record := newCheckRecord(check)
+
+		if len(waitedFor) > 0 && !slices.Contains(waitedFor, record.Name) {
+			continue
+		}
+		if len(waitedFor) == 0 && slices.Contains(excludes, record.Name) {
+			continue
+		}
+
+		switch {
+		case record.Status == github.CIStatusFailed.Name():
+			failed = append(failed, record)
+		case record.Status == github.CIStatusPending.Name(),
+			// statuses which haven't finished have no outcome yet
+			record.Type == "Status" && record.Status == github.CIStatusUnknown.Name():
+			pending = append(pending, record)
+		}
+	}
+
+	return pending, failed
+}
+
+func gateTitle(status github.CIStatus, pending, failed []checkRecord) string {
+	switch status {
+	case github.CIStatusPassed:
+		return "All checks passed"
+	case github.CIStatusFailed:
+		return fmt.Sprintf("%s failed", countChecks(len(failed)))
+	case github.CIStatusPending:
+		if len(pending) == 0 {
+			return "Waiting for CI"
+		}
+		return fmt.Sprintf("Waiting for %s", countChecks(len(pending)))
+	default:
+		return "Stopped waiting before CI finished"
+	}
+}
+
+func countChecks(n int) string {
+	if n == 1 {
+		return "1 check"
+	}
+	return fmt.Sprintf("%d checks", n)
+}
+
+// gateSummary returns a markdown table of the pending and failed checks.
+func gateSummary(pending, failed []checkRecord) string {
+	if len(pending) == 0 && len(failed) == 0 {
+		return "No checks are pending or failed."
+	}
+
+	var b strings.Builder
+	b.WriteString("| Check | Status | Details |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, record := range slices.Concat(failed, pending) {
+		name := record.Name
+		if record.Workflow != "" {
+			name = record.Workflow + " / " + name
+		}
+
+		details := ""
+		if record.DetailsURL != "" {
+			details = fmt.Sprintf("[Details](%s)", record.DetailsURL)
+		}
+
+		status := github.CIStatusFailed.Name()
+		if record.Status != status {
+			status = github.CIStatusPending.Name()
+		}
+
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownEscaper.Replace(name), status, details)
+	}
+
+	return b.String()
+}
+
+// finish sets the gate to how the wait ended: passed or failed if CI
+// finished, and an error otherwise. The wait may have timed out, so this
+// can't use its context.
+func (g *ciGate) finish(ctx context.Context, waitErr error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reportTimeout)
+	defer cancel()
+
+	status := github.CIStatusUnknown
+	var exitErr cli.ExitCoder
+	if errors.As(waitErr, &exitErr) {
+		switch exitErr.ExitCode() {
+		case utils.ExitSuccess:
+			status = github.CIStatusPassed
+		case utils.ExitFailed:
+			status = github.CIStatusFailed
+		}
+	}
+
+	return g.report(ctx, status)
+}
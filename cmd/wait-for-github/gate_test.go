@@ -0,0 +1,166 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+var gatedChecks = []github.CICheckStatus{
+	github.CheckRunDetails{CheckRun: github.CheckRun{Name: "build", Status: "COMPLETED", Conclusion: "SUCCESS"}},
+	github.CheckRunDetails{CheckRun: github.CheckRun{Name: "test", Status: "COMPLETED", Conclusion: "FAILURE", DetailsURL: "https://example.com/test",
+		CheckSuite: github.CheckSuiteInfo{WorkflowRun: github.WorkflowRunInfo{Workflow: github.WorkflowInfo{Name: "CI"}}}}},
+	github.CheckRunDetails{CheckRun: github.CheckRun{Name: "lint", Status: "IN_PROGRESS"}},
+	github.StatusContext{Context: "deploy", State: "PENDING"},
+	github.CheckRunDetails{CheckRun: github.CheckRun{Name: "wfg/gate", Status: "IN_PROGRESS"}},
+}
+
+func TestGateSummary(t *testing.T) {
+	t.Parallel()
+
+	pending, failed := gateRecords(gatedChecks, nil, []string{"wfg/gate"})
+	require.Equal(t, "Waiting for 2 checks", gateTitle(github.CIStatusPending, pending, failed))
+	require.Equal(t, "1 check failed", gateTitle(github.CIStatusFailed, pending, failed))
+	require.Equal(t, "| Check | Status | Details |\n"+
+		"| --- | --- | --- |\n"+
+		"| CI / test | failed | [Details](https://example.com/test) |\n"+
+		"| lint | pending |  |\n"+
+		"| deploy | pending |  |\n", gateSummary(pending, failed))
+
+	pending, failed = gateRecords(gatedChecks, []string{"build"}, []string{"wfg/gate"})
+	require.Empty(t, pending)
+	require.Empty(t, failed)
+	require.Equal(t, "All checks passed", gateTitle(github.CIStatusPassed, pending, failed))
+	require.Equal(t, "No checks are pending or failed.", gateSummary(pending, failed))
+}
+
+func TestCIGate(t *testing.T) {
+	t.Parallel()
+
+	client := &FakeCIStatusChecker{
+		status: github.CIStatusFailed,
+		sha:    "abc123",
+		checks: gatedChecks,
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main", excludes: []string{"wfg/gate"}, reportAs: "wfg/gate", reportAsType: gateTypeCheckRun}
+
+	var out bytes.Buffer
+	require.Error(t, checkCIStatus(context.Background(), client, cfg, ciConf, &out))
+	require.Len(t, client.gates, 2)
+	require.Equal(t, github.CIStatusPending, client.gates[0].Status)
+	require.Equal(t, "wfg/gate", client.gates[1].Name)
+	require.Equal(t, github.CIStatusFailed, client.gates[1].Status)
+	require.Equal(t, "1 check failed", client.gates[1].Title)
+}
+
+func TestCIGateTimeout(t *testing.T) {
+	t.Parallel()
+
+	client := &FakeCIStatusChecker{
+		status: github.CIStatusPending,
+		sha:    "abc123",
+		checks: gatedChecks,
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+	}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main", excludes: []string{"wfg/gate"}, reportAs: "wfg/gate", reportAsType: gateTypeStatus}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var out bytes.Buffer
+	err := checkCIStatus(ctx, client, cfg, ciConf, &out)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, utils.ExitTimeout, exitErr.ExitCode())
+
+	// nothing changed while waiting, so the gate was only reported at the
+	// start and the end
+	require.Len(t, client.gates, 2)
+	require.Equal(t, github.CIStatusPending, client.gates[0].Status)
+	require.Equal(t, github.CIStatusUnknown, client.gates[1].Status)
+	require.Equal(t, "Stopped waiting before CI finished", client.gates[1].Title)
+}
+
+// countingGateClient counts how many times the checks are got for the gate.
+type countingGateClient struct {
+	*FakeCIStatusChecker
+	detailed int
+}
+
+func (c *countingGateClient) GetDetailedCIStatus(ctx context.Context, owner, repo string, commitHash string) ([]github.CICheckStatus, error) {
+	c.detailed++
+	return c.FakeCIStatusChecker.GetDetailedCIStatus(ctx, owner, repo, commitHash)
+}
+
+func TestGatedCheckRefreshesOnChange(t *testing.T) {
+	t.Parallel()
+
+	var build atomic.Value
+	build.Store(`"status": "IN_PROGRESS"`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"data": {"repository": {"object": {"oid": "abc123", "statusCheckRollup": {"state": "PENDING", "contexts": {
+			"checkRunCount": 3, "nodes": [
+				{"__typename": "CheckRun", "name": "build", %s},
+				{"__typename": "CheckRun", "name": "lint", "status": "IN_PROGRESS"},
+				{"__typename": "CheckRun", "name": "wfg/gate", "status": "IN_PROGRESS"}
+			], "pageInfo": {"hasNextPage": false}}}}}}}`, build.Load())
+	}))
+	defer server.Close()
+
+	client, err := github.AuthenticateWithToken(context.Background(), testLogger, "token", server.URL)
+	require.NoError(t, err)
+
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main", excludes: []string{"wfg/gate"}, reportAs: "wfg/gate", reportAsType: gateTypeCheckRun}
+	gateClient := &countingGateClient{FakeCIStatusChecker: &FakeCIStatusChecker{checks: gatedChecks}}
+	check := gatedCheck{
+		ciCheck: &checkAllCI{githubClient: client, owner: "owner", repo: "repo", ref: "main", excludes: ciConf.excludes, logger: testLogger},
+		gate:    &ciGate{client: gateClient, logger: testLogger, ciConf: ciConf, sha: "abc123"},
+	}
+
+	require.NoError(t, check.Check(context.Background()))
+	require.Equal(t, 1, gateClient.detailed)
+
+	// nothing changed, so the summary is the same
+	require.NoError(t, check.Check(context.Background()))
+	require.Equal(t, 1, gateClient.detailed)
+
+	build.Store(`"status": "COMPLETED", "conclusion": "SUCCESS"`)
+	require.NoError(t, check.Check(context.Background()))
+	require.Equal(t, 2, gateClient.detailed)
+}
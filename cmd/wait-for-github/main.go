@@ -18,13 +18,35 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
 )
 
 func main() {
+	// errors which are cli.ExitCoders have already been handled by the time
+	// Run returns
 	if err := root().Run(context.Background(), os.Args); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode returns the code to exit with for errors which aren't one of the
+// outcomes of waiting.
+func exitCode(err error) int {
+	if github.IsAuthError(err) {
+		return utils.ExitAuth
 	}
+
+	var invalidURL ErrInvalidURL
+	var invalidPRURL ErrInvalidPRURL
+	if errors.As(err, &invalidURL) || errors.As(err, &invalidPRURL) {
+		return utils.ExitUsage
+	}
+
+	return utils.ExitFailed
 }
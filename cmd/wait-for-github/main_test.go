@@ -0,0 +1,49 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "auth error", err: errors.New(`failed to query GitHub: non-200 OK status code: 401 Unauthorized body: "{}"`), want: utils.ExitAuth},
+		{name: "invalid URL", err: ErrInvalidURL{"https://invalid_url"}, want: utils.ExitUsage},
+		{name: "invalid PR URL", err: fmt.Errorf("parsing: %w", ErrInvalidPRURL{"https://invalid_url"}), want: utils.ExitUsage},
+		{name: "other error", err: errors.New("connection refused"), want: utils.ExitFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, exitCode(tt.err))
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/notify"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/urfave/cli/v3"
+)
+
+// notifyTimeout bounds how long sending notifications can take. The wait may
+// have timed out already, so this can't use its context.
+const notifyTimeout = 30 * time.Second
+
+// waitSummary is what a command waited for, for notifications.
+type waitSummary struct {
+	// target is what was waited for, e.g. owner/repo#1. If it's not set, the
+	// command's arguments are used.
+	target       string
+	failedChecks []string
+}
+
+// parseNotifyFlags returns the sinks and outcomes given with --notify and
+// --notify-on.
+func parseNotifyFlags(cmd *cli.Command) ([]notify.Sink, []notify.Outcome, error) {
+	client := &http.Client{}
+
+	var sinks []notify.Sink
+	for _, spec := range cmd.StringSlice("notify") {
+		sink, err := notify.ParseSink(spec, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	var outcomes []notify.Outcome
+	for _, outcome := range cmd.StringSlice("notify-on") {
+		if !slices.Contains(notify.Outcomes(), outcome) {
+			return nil, nil, fmt.Errorf("invalid outcome %q: must be one of %s", outcome, strings.Join(notify.Outcomes(), ", "))
+		}
+		outcomes = append(outcomes, notify.Outcome(outcome))
+	}
+
+	return sinks, outcomes, nil
+}
+
+// waitOutcome returns how the wait which the command returned err from ended.
+// It returns false if the command didn't get to wait, or was interrupted.
+func waitOutcome(err error) (notify.Outcome, bool) {
+	code := utils.ExitSuccess
+	if err != nil {
+		var exitErr cli.ExitCoder
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else {
+			code = exitCode(err)
+		}
+	}
+
+	switch code {
+	case utils.ExitSuccess:
+		return notify.OutcomeSuccess, true
+	case utils.ExitTimeout:
+		return notify.OutcomeTimeout, true
+	case utils.ExitUsage, utils.ExitInterrupted:
+		return "", false
+	default:
+		return notify.OutcomeFailure, true
+	}
+}
+
+// sendNotifications tells the --notify sinks how the command's wait ended.
+// Notifications which can't be sent are logged, but don't change how the
+// command exits.
+func sendNotifications(ctx context.Context, cfg *config, cmd *cli.Command, err error, duration time.Duration) {
+	if len(cfg.notify) == 0 {
+		return
+	}
+
+	outcome, ok := waitOutcome(err)
+	if !ok || !slices.Contains(cfg.notifyOn, outcome) {
+		return
+	}
+
+	target := cfg.summary.target
+	if target == "" {
+		target = strings.Join(cmd.Args().Slice(), " ")
+	}
+
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+
+	event := notify.Event{
+		Command:      cmd.Name,
+		Target:       target,
+		Outcome:      outcome,
+		Message:      message,
+		FailedChecks: cfg.summary.failedChecks,
+		Duration:     duration,
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+
+	for _, sink := range cfg.notify {
+		if err := sink.Notify(ctx, event); err != nil {
+			cfg.logger.WarnContext(ctx, "failed to send notification", "outcome", outcome, "error", err)
+		}
+	}
+}
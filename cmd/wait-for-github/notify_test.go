@@ -0,0 +1,143 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/notify"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestWaitOutcome(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		err    error
+		want   notify.Outcome
+		wantOK bool
+	}{
+		{name: "no error", err: nil, want: notify.OutcomeSuccess, wantOK: true},
+		{name: "success", err: cli.Exit("CI successful", utils.ExitSuccess), want: notify.OutcomeSuccess, wantOK: true},
+		{name: "failed", err: cli.Exit("CI failed", utils.ExitFailed), want: notify.OutcomeFailure, wantOK: true},
+		{name: "closed", err: cli.Exit("PR is closed", utils.ExitClosed), want: notify.OutcomeFailure, wantOK: true},
+		{name: "timeout", err: cli.Exit("timed out", utils.ExitTimeout), want: notify.OutcomeTimeout, wantOK: true},
+		{name: "other error", err: errors.New("boom"), want: notify.OutcomeFailure, wantOK: true},
+		{name: "usage", err: cli.Exit("invalid number of arguments", utils.ExitUsage)},
+		{name: "interrupted", err: cli.Exit("interrupted", utils.ExitInterrupted)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := waitOutcome(tt.err)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseNotifyFlags(t *testing.T) {
+	t.Parallel()
+
+	parse := func(args ...string) ([]notify.Sink, []notify.Outcome, error) {
+		var (
+			sinks    []notify.Sink
+			outcomes []notify.Outcome
+			err      error
+		)
+
+		cmd := &cli.Command{
+			Name: "root",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{Name: "notify"},
+				&cli.StringSliceFlag{Name: "notify-on", Value: notify.Outcomes()},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				sinks, outcomes, err = parseNotifyFlags(cmd)
+				return nil
+			},
+		}
+		require.NoError(t, cmd.Run(t.Context(), append([]string{"root"}, args...)))
+
+		return sinks, outcomes, err
+	}
+
+	sinks, outcomes, err := parse()
+	require.NoError(t, err)
+	require.Empty(t, sinks)
+	require.Equal(t, []notify.Outcome{notify.OutcomeSuccess, notify.OutcomeFailure, notify.OutcomeTimeout}, outcomes)
+
+	sinks, outcomes, err = parse("--notify", "webhook=https://example.com/hook", "--notify", "slack=https://hooks.slack.com/services/x", "--notify-on", "failure,timeout")
+	require.NoError(t, err)
+	require.Len(t, sinks, 2)
+	require.IsType(t, notify.Webhook{}, sinks[0])
+	require.IsType(t, notify.Slack{}, sinks[1])
+	require.Equal(t, []notify.Outcome{notify.OutcomeFailure, notify.OutcomeTimeout}, outcomes)
+
+	_, _, err = parse("--notify-on", "merged")
+	require.EqualError(t, err, `invalid outcome "merged": must be one of success, failure, timeout`)
+}
+
+func TestSendNotifications(t *testing.T) {
+	t.Parallel()
+
+	requests := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- string(body)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config{
+		logger:   testLogger,
+		notify:   []notify.Sink{notify.Webhook{URL: server.URL, Client: server.Client()}},
+		notifyOn: []notify.Outcome{notify.OutcomeFailure, notify.OutcomeTimeout},
+		summary:  waitSummary{target: "owner/repo#1", failedChecks: []string{"test"}},
+	}
+	cmd := &cli.Command{Name: "pr"}
+
+	sendNotifications(context.Background(), cfg, cmd, cli.Exit("CI failed", utils.ExitFailed), 2*time.Minute)
+	require.JSONEq(t, `{
+		"command": "pr",
+		"target": "owner/repo#1",
+		"outcome": "failure",
+		"message": "CI failed",
+		"failed_checks": ["test"],
+		"duration_seconds": 120
+	}`, <-requests)
+
+	// the context being cancelled, e.g. by the timeout, doesn't stop it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sendNotifications(ctx, cfg, cmd, cli.Exit("timed out", utils.ExitTimeout), time.Minute)
+	require.Contains(t, <-requests, `"outcome":"timeout"`)
+
+	// success isn't in notifyOn
+	sendNotifications(context.Background(), cfg, cmd, cli.Exit("PR is merged", utils.ExitSuccess), time.Minute)
+	require.Empty(t, requests)
+}
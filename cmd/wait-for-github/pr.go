@@ -19,14 +19,19 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 
 	"log/slog"
 
 	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/logging"
 	"github.com/grafana/wait-for-github/internal/utils"
 	"github.com/urfave/cli/v3"
 )
@@ -54,9 +59,15 @@ type prConfig struct {
 	actionRetries   int
 	autoMerge       bool
 	autoMergeMethod string
+	comment         bool
 	writer          fileWriter
 }
 
+// name returns how the PR is referred to in results and notifications.
+func (c prConfig) name() string {
+	return fmt.Sprintf("%s/%s#%d", c.owner, c.repo, c.pr)
+}
+
 var (
 	// https://regex101.com/r/nexaWT/1
 	pullRequestRegexp = regexp.MustCompile(`.*github\.com/(?P<owner>[^/]+)/(?P<repo>[^/]+)/pull/(?P<number>\d+)/?.*`)
@@ -111,7 +122,7 @@ func parsePRArguments(ctx context.Context, cmd *cli.Command, logger *slog.Logger
 			return prConfig{}, err
 		}
 
-		return prConfig{}, cli.Exit("invalid number of arguments", 1)
+		return prConfig{}, cli.Exit("invalid number of arguments", utils.ExitUsage)
 	}
 
 	n, err := strconv.Atoi(number)
@@ -120,6 +131,10 @@ func parsePRArguments(ctx context.Context, cmd *cli.Command, logger *slog.Logger
 	}
 	logger.InfoContext(ctx, "waiting for PR to be merged/closed", "owner", owner, "repo", repo, "pr", n)
 
+	return newPRConfig(cmd, owner, repo, n), nil
+}
+
+func newPRConfig(cmd *cli.Command, owner, repo string, n int) prConfig {
 	// Filter out empty strings from excludes. When GITHUB_CI_EXCLUDE is set to
 	// an empty string, urfave/cli splits it into a slice containing one empty
 	// string. Remove these so that an empty env var is treated the same as unset.
@@ -140,8 +155,48 @@ func parsePRArguments(ctx context.Context, cmd *cli.Command, logger *slog.Logger
 		actionRetries:   int(cmd.Int("action-retries")),
 		autoMerge:       cmd.Bool("auto-merge"),
 		autoMergeMethod: cmd.String("auto-merge-method"),
+		comment:         cmd.Bool("comment"),
 		writer:          osFileWriter{},
-	}, nil
+	}
+}
+
+// parsePRTargets returns a prConfig for each PR to wait for.
+func parsePRTargets(ctx context.Context, cmd *cli.Command, logger *slog.Logger) ([]prConfig, error) {
+	urls := targetURLs(cmd)
+	if urls == nil {
+		prConf, err := parsePRArguments(ctx, cmd, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		return []prConfig{prConf}, nil
+	}
+
+	if cmd.String("commit-info-file") != "" {
+		return nil, cli.Exit("--commit-info-file can only be used when waiting for a single PR", utils.ExitUsage)
+	}
+
+	if cmd.Bool("comment") {
+		return nil, cli.Exit("--comment can only be used when waiting for a single PR", utils.ExitUsage)
+	}
+
+	prConfs := make([]prConfig, 0, len(urls))
+	for _, url := range urls {
+		owner, repo, number := extractNumberFromPrURL(url)
+		if len(number) == 0 {
+			return nil, ErrInvalidPRURL{url}
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("PR must be a number, got '%s'", number)
+		}
+		logger.InfoContext(ctx, "waiting for PR to be merged/closed", "owner", owner, "repo", repo, "pr", n)
+
+		prConfs = append(prConfs, newPRConfig(cmd, owner, repo, n))
+	}
+
+	return prConfs, nil
 }
 
 type commitInfo struct {
@@ -155,15 +210,41 @@ type checkMergedAndOverallCI interface {
 	github.CheckPRMerged
 	github.GetPRHeadSHA
 	github.CheckOverallCIStatus
+	github.GetDetailedCIStatus
 	github.RerunFailedWorkflows
 	github.MergePR
+	github.GetCheckRunAnnotations
+	github.UpsertPRComment
 }
 
+// What waiting for a PR ended with.
+const (
+	prStatusMerged   = "merged"
+	prStatusClosed   = "closed"
+	prStatusFailed   = "failed"
+	prStatusConflict = "conflict"
+	prStatusPending  = "pending"
+)
+
 type prCheck struct {
 	prConfig
 	githubClient checkMergedAndOverallCI
 	logger       *slog.Logger
 	retriesDone  int
+	checksGrace  utils.ChecksGracePeriod
+
+	// set once the PR is merged
+	mergedCommit string
+	mergedAt     int64
+
+	// status is one of the prStatus constants, and headSHA the last head
+	// commit CI was checked on
+	status  string
+	headSHA string
+
+	// mergeError is why the last attempt to auto-merge the PR failed, if it
+	// did
+	mergeError error
 }
 
 func (pr *prCheck) Check(ctx context.Context) error {
@@ -173,6 +254,8 @@ func (pr *prCheck) Check(ctx context.Context) error {
 	}
 
 	if mergedCommit != "" {
+		pr.mergedCommit, pr.mergedAt = mergedCommit, mergedAt
+		pr.status = prStatusMerged
 		pr.logger.InfoContext(ctx, "PR is merged, exiting")
 		if pr.commitInfoFile != "" {
 			commit := commitInfo{
@@ -192,11 +275,12 @@ func (pr *prCheck) Check(ctx context.Context) error {
 				return fmt.Errorf("failed to write commit info to file: %w", err)
 			}
 		}
-		return cli.Exit("PR is merged", 0)
+		return cli.Exit("PR is merged", utils.ExitSuccess)
 	}
 
 	if closed {
-		return cli.Exit("PR is closed", 1)
+		pr.status = prStatusClosed
+		return cli.Exit("PR is closed", utils.ExitClosed)
 	}
 
 	if pr.ignoreFailedCI {
@@ -209,12 +293,15 @@ func (pr *prCheck) Check(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	pr.headSHA = sha
 
 	status, err := pr.githubClient.GetCIStatus(ctx, pr.owner, pr.repo, sha, pr.excludes)
 	if err != nil {
 		return err
 	}
 
+	status = pr.checksGrace.Resolve(ctx, pr.logger, status)
+
 	if status == github.CIStatusFailed {
 		var shouldContinue bool
 		shouldContinue, pr.retriesDone = utils.TryRerunFailedWorkflows(ctx, pr.githubClient, pr.logger, pr.owner, pr.repo, sha, pr.actionRetries, pr.retriesDone)
@@ -223,17 +310,26 @@ func (pr *prCheck) Check(ctx context.Context) error {
 		}
 
 		pr.logger.InfoContext(ctx, "CI failed, exiting")
-		return cli.Exit("CI failed", 1)
+		pr.status = prStatusFailed
+		return cli.Exit("CI failed", utils.ExitFailed)
 	}
 
 	if pr.autoMerge && status == github.CIStatusPassed {
 		pr.logger.InfoContext(ctx, "CI passed and auto-merge is enabled, merging PR", "method", pr.autoMergeMethod)
 		// Pass sha to prevent merging a different commit than the one CI ran on.
-		// Merge failures (conflicts, branch protection) are retried on each poll;
+		// Merge conflicts need someone to resolve them, so we give up. Other
+		// merge failures (e.g. branch protection) are retried on each poll;
 		// the global timeout bounds how long we wait.
-		if err := pr.githubClient.MergePR(ctx, pr.owner, pr.repo, pr.pr, sha, pr.autoMergeMethod); err != nil {
+		err := pr.githubClient.MergePR(ctx, pr.owner, pr.repo, pr.pr, sha, pr.autoMergeMethod)
+		pr.mergeError = err
+		switch {
+		case errors.Is(err, github.ErrMergeConflict):
+			pr.logger.InfoContext(ctx, "PR has merge conflicts, exiting")
+			pr.status = prStatusConflict
+			return cli.Exit("PR has merge conflicts", utils.ExitMergeConflict)
+		case err != nil:
 			pr.logger.WarnContext(ctx, "failed to merge PR, will retry on next poll", "error", err)
-		} else {
+		default:
 			pr.logger.InfoContext(ctx, "PR merge requested, waiting for GitHub to confirm")
 		}
 		return nil
@@ -243,24 +339,130 @@ func (pr *prCheck) Check(ctx context.Context) error {
 	return nil
 }
 
-func checkPRMerged(timeoutCtx context.Context, githubClient checkMergedAndOverallCI, cfg *config, prConf *prConfig) error {
-	checkPRMergedOrClosed := &prCheck{
+func newPRCheck(githubClient checkMergedAndOverallCI, cfg *config, prConf *prConfig, logger *slog.Logger) *prCheck {
+	return &prCheck{
 		githubClient: githubClient,
 		prConfig:     *prConf,
-		logger:       cfg.logger,
+		logger:       logger,
+		status:       prStatusPending,
+		checksGrace: utils.ChecksGracePeriod{
+			Period:        cfg.checksGracePeriod,
+			RequireChecks: cfg.requireChecks,
+		},
 	}
+}
+
+func checkPRMerged(timeoutCtx context.Context, githubClient checkMergedAndOverallCI, cfg *config, prConf *prConfig, out io.Writer) error {
+	checkPRMergedOrClosed := newPRCheck(githubClient, cfg, prConf, cfg.logger)
 
-	return utils.RunUntilCancelledOrTimeout(timeoutCtx, cfg.logger, checkPRMergedOrClosed, cfg.recheckInterval)
+	err := utils.RunUntilCancelledOrTimeout(timeoutCtx, cfg.logger, checkPRMergedOrClosed, cfg.pollStrategy())
+
+	// only report a result if the wait ended, not if it couldn't be carried out
+	var exitErr cli.ExitCoder
+	if (cfg.actions != nil || prConf.comment || checkPRMergedOrClosed.status == prStatusFailed) && errors.As(err, &exitErr) {
+		if reportErr := reportPRResult(timeoutCtx, githubClient, cfg, out, checkPRMergedOrClosed); reportErr != nil {
+			return reportErr
+		}
+	}
+
+	return err
+}
+
+type prResultClient interface {
+	github.GetPRHeadSHA
+	github.GetDetailedCIStatus
+	github.GetCheckRunAnnotations
+	github.UpsertPRComment
+}
+
+// reportPRResult shows the annotations of the failed checks if CI failed, and
+// writes the result of waiting for a PR to the PR if asked to comment on it,
+// and to the GitHub Actions step if there is one.
+func reportPRResult(ctx context.Context, client prResultClient, cfg *config, out io.Writer, pr *prCheck) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reportTimeout)
+	defer cancel()
+
+	sha := pr.headSHA
+	if sha == "" {
+		var err error
+		if sha, err = client.GetPRHeadSHA(ctx, pr.owner, pr.repo, pr.pr); err != nil {
+			return fmt.Errorf("failed to get PR result: %w", err)
+		}
+	}
+
+	checks, err := client.GetDetailedCIStatus(ctx, pr.owner, pr.repo, sha)
+	if err != nil {
+		return fmt.Errorf("failed to get PR result: %w", err)
+	}
+
+	if pr.status == prStatusFailed {
+		annotations := failedCheckAnnotations(ctx, client, pr.logger, pr.owner, pr.repo, checks, nil, pr.excludes)
+		showAnnotations(out, annotations, cfg.actions != nil)
+	}
+
+	records := make([]checkRecord, 0, len(checks))
+	for _, check := range checks {
+		records = append(records, newCheckRecord(check))
+	}
+	cfg.summary.failedChecks = failedChecks(records, pr.excludes)
+
+	if pr.comment {
+		comment := resultComment{
+			command:    "pr",
+			key:        commentKey(nil, pr.excludes),
+			status:     pr.status,
+			sha:        sha,
+			records:    records,
+			excludes:   pr.excludes,
+			reruns:     pr.retriesDone,
+			mergeError: pr.mergeError,
+		}
+		if err := postResultComment(ctx, client, pr.owner, pr.repo, pr.pr, comment); err != nil {
+			return err
+		}
+	}
+
+	if cfg.actions == nil {
+		return nil
+	}
+
+	return writePRStep(cfg.actions, pr, records)
+}
+
+// checkPRsMerged waits for several PRs at once, and reports the result for
+// each of them.
+func checkPRsMerged(timeoutCtx context.Context, githubClient checkMergedAndOverallCI, cfg *config, prConfs []prConfig, mode string, table tableWriter) error {
+	targets := make([]utils.Target, 0, len(prConfs))
+	for _, prConf := range prConfs {
+		logger := cfg.logger.With(logging.OwnerAttr(prConf.owner), logging.RepoAttr(prConf.repo), "pr", prConf.pr)
+
+		targets = append(targets, utils.Target{
+			Name:  prConf.name(),
+			Check: newPRCheck(githubClient, cfg, &prConf, logger),
+		})
+	}
+
+	results, exitErr := utils.RunTargetsUntilCancelledOrTimeout(timeoutCtx, cfg.logger, targets, cfg.pollStrategy(), mode)
+	if err := renderTargetResults(results, table); err != nil {
+		return err
+	}
+
+	return exitErr
+}
+
+// mergeMethods returns the valid values for --auto-merge-method.
+func mergeMethods() []string {
+	return []string{"merge", "squash", "rebase"}
 }
 
 func prCommand(cfg *config) *cli.Command {
-	var prConf prConfig
+	var prConfs []prConfig
 
 	return &cli.Command{
 		Name:      "pr",
 		Usage:     "Wait for a PR to be merged",
-		ArgsUsage: "<https://github.com/OWNER/REPO/pulls/PR|owner> [<repo> <pr>]",
-		Flags: []cli.Flag{
+		ArgsUsage: "<https://github.com/OWNER/REPO/pulls/PR|owner> [<repo> <pr> | <url>...]",
+		Flags: append(targetFlags("A PR URL to wait for."),
 			&cli.StringFlag{
 				Name: "commit-info-file",
 				Usage: "Path to a file which the commit info will be written. " +
@@ -307,27 +509,42 @@ func prCommand(cfg *config) *cli.Command {
 					cli.EnvVar("GITHUB_AUTO_MERGE_METHOD"),
 				),
 				Validator: func(s string) error {
-					switch s {
-					case "merge", "squash", "rebase":
-						return nil
-					default:
-						return fmt.Errorf("invalid merge method %q: must be one of merge, squash, rebase", s)
+					if !slices.Contains(mergeMethods(), s) {
+						return fmt.Errorf("invalid merge method %q: must be one of %s", s, strings.Join(mergeMethods(), ", "))
 					}
+					return nil
 				},
 			},
-		},
+			commentFlag("the PR"),
+		),
 		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 			var err error
-			prConf, err = parsePRArguments(ctx, cmd, cfg.logger)
+			prConfs, err = parsePRTargets(ctx, cmd, cfg.logger)
 
 			return ctx, err
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo, cfg.pendingRecheckTime)
+			names := make([]string, 0, len(prConfs))
+			for _, prConf := range prConfs {
+				names = append(names, prConf.name())
+			}
+			cfg.summary.target = strings.Join(names, ", ")
+
+			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo)
 			if err != nil {
 				return err
 			}
-			return checkPRMerged(ctx, githubClient, cfg, &prConf)
+
+			if len(prConfs) == 1 {
+				return checkPRMerged(ctx, githubClient, cfg, &prConfs[0], os.Stdout)
+			}
+
+			table, err := newTableWriter(os.Stdout)
+			if err != nil {
+				return err
+			}
+
+			return checkPRsMerged(ctx, githubClient, cfg, prConfs, cmd.String("mode"), table)
 		},
 	}
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"testing"
 	"time"
@@ -43,6 +44,9 @@ type fakeGithubClientPRCheck struct {
 	mergePRError              error
 
 	CIStatus              github.CIStatus
+	Checks                []github.CICheckStatus
+	Annotations           map[int64][]github.CheckRunAnnotation
+	Comments              map[int]string // comment bodies posted, by PR
 	RerunCount            int
 	HasRunsInProgress     bool
 	RerunCalledCount      int
@@ -66,6 +70,10 @@ func (fg *fakeGithubClientPRCheck) GetCIStatus(ctx context.Context, owner, repo
 	return fg.CIStatus, fg.getCIStatusError
 }
 
+func (fg *fakeGithubClientPRCheck) GetDetailedCIStatus(ctx context.Context, owner, repo string, commitHash string) ([]github.CICheckStatus, error) {
+	return fg.Checks, fg.getCIStatusError
+}
+
 func (fg *fakeGithubClientPRCheck) RerunFailedWorkflowsForCommit(ctx context.Context, owner, repo, commitHash string) (int, bool, error) {
 	fg.RerunCalledCount++
 	return fg.RerunCount, fg.HasRunsInProgress, fg.rerunFailedWorkflowsError
@@ -78,6 +86,18 @@ func (fg *fakeGithubClientPRCheck) MergePR(ctx context.Context, owner, repo stri
 	return fg.mergePRError
 }
 
+func (fg *fakeGithubClientPRCheck) GetCheckRunAnnotations(ctx context.Context, owner, repo string, checkRunID int64) ([]github.CheckRunAnnotation, error) {
+	return fg.Annotations[checkRunID], nil
+}
+
+func (fg *fakeGithubClientPRCheck) UpsertPRComment(ctx context.Context, owner, repo string, pr int, marker, body string) error {
+	if fg.Comments == nil {
+		fg.Comments = map[int]string{}
+	}
+	fg.Comments[pr] = body
+	return nil
+}
+
 func TestPRCheck(t *testing.T) {
 	t.Parallel()
 
@@ -106,14 +126,14 @@ func TestPRCheck(t *testing.T) {
 			fakeClient: fakeGithubClientPRCheck{
 				Closed: true,
 			},
-			expectedExitCode: &one,
+			expectedExitCode: &exitClosed,
 		},
 		{
 			name: "PR is open",
 			fakeClient: fakeGithubClientPRCheck{
 				Closed: false,
 			},
-			expectedExitCode: &one,
+			expectedExitCode: &exitTimeout,
 		},
 		{
 			name: "Error from IsPRMergedOrClosed",
@@ -144,7 +164,7 @@ func TestPRCheck(t *testing.T) {
 				CIStatus: github.CIStatusFailed,
 			},
 			ignoreFailedCI:   true,
-			expectedExitCode: &one,
+			expectedExitCode: &exitClosed,
 		},
 		{
 			name: "CI failed with action-retries, workflows rerun",
@@ -236,6 +256,20 @@ func TestPRCheck(t *testing.T) {
 			expectMergeMethod: "squash",
 			// No exit code - continues polling and will retry
 		},
+		{
+			name: "CI passed with auto-merge, merge conflicts",
+			fakeClient: fakeGithubClientPRCheck{
+				HeadSHA:      "abc123",
+				CIStatus:     github.CIStatusPassed,
+				mergePRError: fmt.Errorf("failed to merge PR: %w", github.ErrMergeConflict),
+			},
+			autoMerge:         true,
+			autoMergeMethod:   "squash",
+			expectedExitCode:  &exitMergeConflict,
+			expectMergeCalled: true,
+			expectMergeSHA:    "abc123",
+			expectMergeMethod: "squash",
+		},
 		{
 			name: "CI pending with auto-merge, no merge attempt",
 			fakeClient: fakeGithubClientPRCheck{
@@ -284,7 +318,7 @@ func TestPRCheck(t *testing.T) {
 				autoMergeMethod: tt.autoMergeMethod,
 			}
 
-			err := checkPRMerged(ctx, fakePRStatusChecker, cfg, &prConfig)
+			err := checkPRMerged(ctx, fakePRStatusChecker, cfg, &prConfig, io.Discard)
 			if tt.expectedExitCode != nil {
 				var exitErr cli.ExitCoder
 				require.ErrorAs(t, err, &exitErr)
@@ -472,7 +506,7 @@ func TestPRCheckRateLimitRetry(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	err := checkPRMerged(ctx, client, cfg, prConf)
+	err := checkPRMerged(ctx, client, cfg, prConf, io.Discard)
 
 	var exitErr cli.ExitCoder
 	require.ErrorAs(t, err, &exitErr)
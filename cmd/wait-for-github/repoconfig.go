@@ -0,0 +1,138 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// repoConfigPath is where a repository keeps its defaults, on its default
+// branch.
+const repoConfigPath = ".github/wait-for-github.yml"
+
+// repoConfigOptions are the options a repository's defaults file can set.
+// Anything to do with authentication or where to connect to is deliberately
+// left out, as the file is controlled by the repository, not the caller.
+var repoConfigOptions = []string{
+	"check",
+	"exclude",
+	"action-retries",
+	"ignore-failed-ci",
+	"tool",
+	"fail-on-severity",
+}
+
+// repoConfig is the contents of a repository's defaults file.
+type repoConfig struct {
+	options profile
+	// allowedMergeMethods restricts --auto-merge-method. The first one is
+	// used if no method is chosen. Empty means any method is allowed.
+	allowedMergeMethods []string
+}
+
+func parseRepoConfig(data []byte) (*repoConfig, error) {
+	var options profile
+	if err := yaml.Unmarshal(data, &options); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", repoConfigPath, err)
+	}
+
+	rc := &repoConfig{options: profile{}}
+
+	var unknown []string
+	for key, value := range options {
+		switch {
+		case key == "allowed-merge-methods":
+			methods, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("allowed-merge-methods in %s must be a list", repoConfigPath)
+			}
+			for _, method := range methods {
+				method := fmt.Sprint(method)
+				if !slices.Contains(mergeMethods(), method) {
+					return nil, fmt.Errorf("invalid merge method %q in %s: must be one of %s",
+						method, repoConfigPath, strings.Join(mergeMethods(), ", "))
+				}
+				rc.allowedMergeMethods = append(rc.allowedMergeMethods, method)
+			}
+		case slices.Contains(repoConfigOptions, key):
+			rc.options[key] = value
+		default:
+			unknown = append(unknown, key)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%s has unknown options: %s", repoConfigPath, strings.Join(unknown, ", "))
+	}
+
+	return rc, nil
+}
+
+// fetchRepoConfig returns the defaults file of owner/repo, or nil if the
+// repository doesn't have one.
+func fetchRepoConfig(ctx context.Context, client github.GetDefaultBranchFile, logger *slog.Logger, owner, repo string) (*repoConfig, error) {
+	data, found, err := client.GetDefaultBranchFile(ctx, owner, repo, repoConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from %s/%s: %w", repoConfigPath, owner, repo, err)
+	}
+	if !found {
+		logger.DebugContext(ctx, "repository has no defaults file", "path", repoConfigPath)
+		return nil, nil
+	}
+
+	rc, err := parseRepoConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", owner, repo, err)
+	}
+
+	logger.InfoContext(ctx, "using repository defaults", "path", repoConfigPath)
+
+	return rc, nil
+}
+
+// checkMergeMethod makes sure the merge method cmd will use is one the
+// repository allows, choosing the repository's preferred one if no method was
+// chosen. It must run after all other options have been applied.
+func (rc *repoConfig) checkMergeMethod(cmd *cli.Command) error {
+	if len(rc.allowedMergeMethods) == 0 || !slices.ContainsFunc(cmd.Flags, func(f cli.Flag) bool {
+		return slices.Contains(f.Names(), "auto-merge-method")
+	}) {
+		return nil
+	}
+
+	if !cmd.IsSet("auto-merge-method") {
+		return cmd.Set("auto-merge-method", rc.allowedMergeMethods[0])
+	}
+
+	method := cmd.String("auto-merge-method")
+	if !slices.Contains(rc.allowedMergeMethods, method) {
+		return fmt.Errorf("merge method %q is not allowed by %s: must be one of %s",
+			method, repoConfigPath, strings.Join(rc.allowedMergeMethods, ", "))
+	}
+
+	return nil
+}
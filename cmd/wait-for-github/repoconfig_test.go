@@ -0,0 +1,146 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+type fakeRepoFileClient struct {
+	files map[string]string
+}
+
+func (c fakeRepoFileClient) GetDefaultBranchFile(ctx context.Context, owner, repo, path string) ([]byte, bool, error) {
+	content, ok := c.files[owner+"/"+repo+"/"+path]
+	return []byte(content), ok, nil
+}
+
+func TestParseRepoConfig(t *testing.T) {
+	t.Parallel()
+
+	rc, err := parseRepoConfig([]byte("exclude: [lint]\naction-retries: 2\nallowed-merge-methods: [squash, rebase]\n"))
+	require.NoError(t, err)
+	require.Equal(t, profile{"exclude": []any{"lint"}, "action-retries": 2}, rc.options)
+	require.Equal(t, []string{"squash", "rebase"}, rc.allowedMergeMethods)
+
+	_, err = parseRepoConfig([]byte("github-token: abc\n"))
+	require.EqualError(t, err, ".github/wait-for-github.yml has unknown options: github-token")
+
+	_, err = parseRepoConfig([]byte("allowed-merge-methods: [fast-forward]\n"))
+	require.EqualError(t, err, `invalid merge method "fast-forward" in .github/wait-for-github.yml: must be one of merge, squash, rebase`)
+}
+
+func TestApplyConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	client := fakeRepoFileClient{files: map[string]string{
+		"owner/repo/.github/wait-for-github.yml": "exclude: [repo-check]\naction-retries: 3\nallowed-merge-methods: [squash]\n",
+	}}
+
+	type result struct {
+		excludes      []string
+		actionRetries int
+		mergeMethod   string
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		configFile *configFile
+		profile    profile
+		want       result
+		wantErr    string
+	}{
+		{
+			name: "repository defaults",
+			args: []string{"owner", "repo", "1"},
+			want: result{excludes: []string{"repo-check"}, actionRetries: 3, mergeMethod: "squash"},
+		},
+		{
+			name: "flags take precedence",
+			args: []string{"--exclude", "flag-check", "owner", "repo", "1"},
+			want: result{excludes: []string{"flag-check"}, actionRetries: 3, mergeMethod: "squash"},
+		},
+		{
+			name:    "repository defaults take precedence over the profile",
+			args:    []string{"owner", "repo", "1"},
+			profile: profile{"action-retries": 1, "ignore-failed-ci": true},
+			want:    result{excludes: []string{"repo-check"}, actionRetries: 3, mergeMethod: "squash"},
+		},
+		{
+			name:       "config file overrides take precedence over repository defaults",
+			args:       []string{"owner", "repo", "1"},
+			configFile: &configFile{Repos: map[string]profile{"owner/repo": {"action-retries": 0}}},
+			want:       result{excludes: []string{"repo-check"}, actionRetries: 0, mergeMethod: "squash"},
+		},
+		{
+			name:    "disallowed merge method",
+			args:    []string{"--auto-merge", "--auto-merge-method", "merge", "owner", "repo", "1"},
+			wantErr: `merge method "merge" is not allowed by .github/wait-for-github.yml: must be one of squash`,
+		},
+		{
+			name: "no defaults file",
+			args: []string{"owner", "other", "1"},
+			want: result{excludes: []string{}, mergeMethod: "merge"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config{logger: testLogger, configFile: tt.configFile, profile: tt.profile}
+
+			var got result
+			prCmd := prCommand(&cfg)
+			prCmd.Before = nil
+			prCmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+				if err := applyConfigFiles(ctx, cmd, &cfg, client); err != nil {
+					return err
+				}
+
+				got = result{
+					excludes:      cmd.StringSlice("exclude"),
+					actionRetries: cmd.Int("action-retries"),
+					mergeMethod:   cmd.String("auto-merge-method"),
+				}
+				return nil
+			}
+
+			rootCmd := &cli.Command{
+				Name:      "root",
+				Commands:  []*cli.Command{prCmd},
+				Writer:    io.Discard,
+				ErrWriter: io.Discard,
+			}
+
+			err := rootCmd.Run(t.Context(), append([]string{"root", "pr"}, tt.args...))
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
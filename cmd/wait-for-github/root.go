@@ -20,13 +20,21 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/grafana/wait-for-github/internal/actions"
 	"github.com/grafana/wait-for-github/internal/github"
 	"github.com/grafana/wait-for-github/internal/logging"
+	"github.com/grafana/wait-for-github/internal/metrics"
+	"github.com/grafana/wait-for-github/internal/notify"
+	"github.com/grafana/wait-for-github/internal/tracing"
+	"github.com/grafana/wait-for-github/internal/utils"
 	"github.com/urfave/cli/v3"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type cmdFunc func(cfg *config) *cli.Command
@@ -38,15 +46,11 @@ func root() *cli.Command {
 
 	// give a timeout context to all commands
 	var commands []*cli.Command
-	for _, cf := range []cmdFunc{ciCommand, prCommand} {
+	for _, cf := range []cmdFunc{ciCommand, prCommand, artifactCommand, codeScanningCommand, applyCommand} {
 		cmd := cf(&cfg)
-		action := cmd.Action
-		cmd.Action = func(c context.Context, cmd *cli.Command) error {
-			timeoutCtx, cancel := context.WithTimeout(c, cfg.globalTimeout)
-			defer cancel()
-
-			return action(timeoutCtx, cmd)
-		}
+		cmd.Action = waitAction(&cfg, cmd.Action)
+		wrapBeforeWithConfigFile(cmd, &cfg)
+		exitOnUsageError(cmd)
 		commands = append(commands, cmd)
 	}
 
@@ -54,6 +58,29 @@ func root() *cli.Command {
 		Name:  "wait-for-github",
 		Usage: "Wait for things to happen on GitHub",
 		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name: "config",
+				Usage: "Path to a config file with profiles and per-repository options. " +
+					"Defaults to $XDG_CONFIG_HOME/wait-for-github/config.yaml, if it exists.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("WAIT_FOR_GITHUB_CONFIG"),
+				),
+			},
+			&cli.StringFlag{
+				Name:  "profile",
+				Usage: "Name of the profile in the config file to use, instead of its default profile.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("WAIT_FOR_GITHUB_PROFILE"),
+				),
+			},
+			&cli.BoolFlag{
+				Name: "repo-config",
+				Usage: "Read defaults for command options from " + repoConfigPath + " on the default " +
+					"branch of the repository being waited for.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("WAIT_FOR_GITHUB_REPO_CONFIG"),
+				),
+			},
 			&cli.GenericFlag{
 				Name:    "log-level",
 				Aliases: []string{"l"},
@@ -61,11 +88,34 @@ func root() *cli.Command {
 				Value:   &defaultLogLevel,
 				Action: func(ctx context.Context, cmd *cli.Command, value cli.Value) error {
 					level := value.(*logging.Level)
-					cfg.logger = logging.SetupLogger(slog.Level(*level))
+					cfg.logOptions.Level = slog.Level(*level)
+					cfg.logger = logging.SetupLogger(cfg.logOptions)
 
 					return nil
 				},
 			},
+			&cli.StringFlag{
+				Name: "log-format",
+				Usage: fmt.Sprintf("Format of the logs written to --log-file, or to stderr if it isn't given. Valid formats are: %s. "+
+					"auto picks one for where the logs are going: JSON for a file.", strings.Join(logging.Formats(), ", ")),
+				Value: logging.FormatAuto,
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("WAIT_FOR_GITHUB_LOG_FORMAT"),
+				),
+				Validator: func(s string) error {
+					if !slices.Contains(logging.Formats(), s) {
+						return fmt.Errorf("invalid log format %q: must be one of %s", s, strings.Join(logging.Formats(), ", "))
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "log-file",
+				Usage: "Also write logs to this file, appending to it if it exists. Logs on stderr are then always human-readable.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("WAIT_FOR_GITHUB_LOG_FILE"),
+				),
+			},
 			&cli.StringFlag{
 				Name:    "github-app-private-key-path",
 				Aliases: []string{"p"},
@@ -92,6 +142,14 @@ func root() *cli.Command {
 					cli.EnvVar("GITHUB_APP_INSTALLATION_ID"),
 				),
 			},
+			&cli.StringFlag{
+				Name: "github-url",
+				Usage: "URL of the GitHub Enterprise Server or GHE.com instance to use. Defaults to github.com, " +
+					"or in GitHub Actions to $GITHUB_SERVER_URL if what's being waited for is given as a URL on that host.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("GITHUB_URL"),
+				),
+			},
 			&cli.StringFlag{
 				Name: "github-token",
 				Usage: "GitHub token. If not provided, the app will try to use the " +
@@ -109,12 +167,56 @@ func root() *cli.Command {
 				Value: time.Duration(30 * time.Second),
 			},
 			&cli.DurationFlag{
-				Name:  "pending-recheck-time",
-				Usage: "Time after which to recheck the pending status on GitHub.",
+				Name: "max-recheck-interval",
+				Usage: "Longest interval the recheck interval can grow to while nothing changes, doubling each " +
+					"time. By default, it doesn't grow.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("MAX_RECHECK_INTERVAL"),
+				),
+			},
+			&cli.DurationFlag{
+				Name: "fast-recheck-period",
+				Usage: "How long to recheck every --recheck-interval for, at first and whenever a check changes " +
+					"status, before the interval starts growing up to --max-recheck-interval.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("FAST_RECHECK_PERIOD"),
+				),
+				Value: 5 * time.Minute,
+			},
+			&cli.FloatFlag{
+				Name: "recheck-jitter",
+				Usage: "Fraction of each recheck interval to randomly add or take away, so that waits started " +
+					"at the same time don't all recheck at once.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("RECHECK_JITTER"),
+				),
+				Value: 0.1,
+				Validator: func(f float64) error {
+					if f < 0 || f >= 1 {
+						return fmt.Errorf("invalid jitter %v: must be at least 0 and less than 1", f)
+					}
+					return nil
+				},
+			},
+			&cli.DurationFlag{
+				Name:    "checks-grace-period",
+				Aliases: []string{"pending-recheck-time"},
+				Usage: "How long to keep waiting when a commit has no checks or statuses, " +
+					"in case they haven't been created yet. Doesn't apply to the checks named with ci --check, " +
+					"which are waited for until they show up.",
 				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("CHECKS_GRACE_PERIOD"),
 					cli.EnvVar("PENDING_RECHECK_TIME"),
 				),
-				Value: 5 * time.Second,
+				Value: time.Minute,
+			},
+			&cli.BoolFlag{
+				Name: "require-checks",
+				Usage: "Fail if a commit still has no checks or statuses after the grace period, " +
+					"e.g. because the commit doesn't exist. By default, this is treated as success.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("REQUIRE_CHECKS"),
+				),
 			},
 			&cli.DurationFlag{
 				Name:  "timeout",
@@ -124,33 +226,301 @@ func root() *cli.Command {
 				),
 				Value: time.Duration(7 * 24 * time.Hour),
 			},
+			&cli.StringSliceFlag{
+				Name: "notify",
+				Usage: "Send a notification when the wait ends, to KIND=URL. KIND is webhook, to post JSON to the URL, " +
+					"or slack, to post to a Slack incoming webhook. Can be given multiple times.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("WAIT_FOR_GITHUB_NOTIFY"),
+				),
+			},
+			&cli.StringSliceFlag{
+				Name:  "notify-on",
+				Usage: fmt.Sprintf("Outcomes of the wait to send notifications for. Valid outcomes are: %s.", strings.Join(notify.Outcomes(), ", ")),
+				Value: notify.Outcomes(),
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("WAIT_FOR_GITHUB_NOTIFY_ON"),
+				),
+			},
+			&cli.StringFlag{
+				Name:  "metrics-addr",
+				Usage: "Address to serve Prometheus metrics on at /metrics while waiting, e.g. :9090.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar("WAIT_FOR_GITHUB_METRICS_ADDR"),
+				),
+			},
 		},
-		Commands: commands,
+		Commands:     commands,
+		OnUsageError: onUsageError,
 		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			if err := loadProfile(cmd, &cfg); err != nil {
+				return ctx, err
+			}
+
 			err := initialiseConfig(ctx, cmd, &cfg)
 			return ctx, err
 		},
 	}
 }
 
+// waitAction wraps the action of a command which waits for something, so
+// that the wait is traced, times out after --timeout and sends notifications
+// when it ends.
+func waitAction(cfg *config, action cli.ActionFunc) cli.ActionFunc {
+	return func(c context.Context, cmd *cli.Command) error {
+		// the whole wait is one trace, or part of the caller's
+		name := strings.Join(cmd.Path()[1:], " ")
+		spanCtx, span := tracing.Start(tracing.ContextFromEnv(c), cmd.FullName(), attribute.String("command", name))
+
+		timeoutCtx, cancel := context.WithTimeout(spanCtx, cfg.globalTimeout)
+		defer cancel()
+
+		start := time.Now()
+		err := action(timeoutCtx, cmd)
+		tracing.End(span, err)
+		sendNotifications(c, cfg, cmd, err, time.Since(start))
+
+		// cli exits as soon as this returns an exit error, so the spans
+		// have to be sent now
+		if cfg.shutdownTracing != nil {
+			if shutdownErr := cfg.shutdownTracing(c); shutdownErr != nil {
+				cfg.logger.WarnContext(c, "failed to send traces", "error", shutdownErr)
+			}
+		}
+
+		return err
+	}
+}
+
+// loadProfile loads the config file and applies the selected profile to the
+// global flags. The command's own flags are set once they have been parsed, by
+// wrapBeforeWithConfigFile.
+func loadProfile(cmd *cli.Command, cfg *config) error {
+	path := cmd.String("config")
+	mustExist := path != ""
+	if path == "" {
+		path = defaultConfigPath()
+	}
+
+	file, err := loadConfigFile(path, mustExist)
+	if err != nil {
+		return err
+	}
+	if err := file.validate(cmd); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	p, err := file.profile(cmd.String("profile"))
+	if err != nil {
+		return err
+	}
+
+	cfg.configFile = file
+	cfg.profile = p
+
+	return applyProfile(cmd, p)
+}
+
+// onUsageError shows the same help as cli does for invalid flags, but exits
+// with utils.ExitUsage rather than 1.
+func onUsageError(ctx context.Context, cmd *cli.Command, err error, isSubcommand bool) error {
+	fmt.Fprintf(cmd.Root().ErrWriter, "Incorrect Usage: %s\n\n", err.Error())
+	if isSubcommand {
+		_ = cli.ShowSubcommandHelp(cmd)
+	} else {
+		_ = cli.ShowRootCommandHelp(cmd)
+	}
+
+	return cli.Exit("", utils.ExitUsage)
+}
+
+// exitOnUsageError makes cmd and its subcommands use onUsageError.
+func exitOnUsageError(cmd *cli.Command) {
+	cmd.OnUsageError = onUsageError
+	for _, sub := range cmd.Commands {
+		exitOnUsageError(sub)
+	}
+}
+
+// wrapBeforeWithConfigFile makes cmd and its subcommands apply the config
+// files to their flags before running.
+func wrapBeforeWithConfigFile(cmd *cli.Command, cfg *config) {
+	before := cmd.Before
+	cmd.Before = func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+		var client github.GetDefaultBranchFile
+		if cfg.useRepoConfig {
+			githubClient, err := github.NewGithubClient(ctx, cfg.logger, cfg.AuthInfo)
+			if err != nil {
+				return ctx, err
+			}
+			client = githubClient
+		}
+
+		if err := applyConfigFiles(ctx, cmd, cfg, client); err != nil {
+			return ctx, err
+		}
+
+		if before == nil {
+			return ctx, nil
+		}
+		return before(ctx, cmd)
+	}
+
+	for _, sub := range cmd.Commands {
+		wrapBeforeWithConfigFile(sub, cfg)
+	}
+}
+
+// applyConfigFiles sets cmd's flags from, in order of precedence: the
+// overrides in the config file for the repository the command is for, the
+// repository's own defaults file if client is given, and the profile. Flags
+// and environment variables take precedence over all of them.
+func applyConfigFiles(ctx context.Context, cmd *cli.Command, cfg *config, client github.GetDefaultBranchFile) error {
+	var rc *repoConfig
+
+	if repo, ok := targetRepo(cmd); ok {
+		if cfg.configFile != nil {
+			if err := applyProfile(cmd, cfg.configFile.repo(repo)); err != nil {
+				return err
+			}
+		}
+
+		if client != nil {
+			owner, name, _ := strings.Cut(repo, "/")
+
+			var err error
+			rc, err = fetchRepoConfig(ctx, client, cfg.logger.With("owner", owner, "repo", name), owner, name)
+			if err != nil {
+				return err
+			}
+			if rc != nil {
+				if err := applyProfile(cmd, rc.options); err != nil {
+					return fmt.Errorf("%s of %s: %w", repoConfigPath, repo, err)
+				}
+			}
+		}
+	}
+
+	if err := applyProfile(cmd, cfg.profile); err != nil {
+		return err
+	}
+
+	if rc != nil {
+		return rc.checkMergeMethod(cmd)
+	}
+
+	return nil
+}
+
+// actionsServerURL returns GITHUB_SERVER_URL, the URL of the GitHub instance
+// a GitHub Actions workflow is running on, if one of the URLs in args is for
+// it. Workflows on GitHub Enterprise Server can wait for things on github.com,
+// so it isn't used for targets given as `owner repo ref`, or on other hosts.
+func actionsServerURL(args []string) string {
+	serverURL := os.Getenv("GITHUB_SERVER_URL")
+	server, err := url.Parse(serverURL)
+	if serverURL == "" || err != nil {
+		return ""
+	}
+
+	for _, arg := range args {
+		// e.g. --target=URL
+		if _, value, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(arg, "-") {
+			arg = value
+		}
+		if !looksLikeURL(arg) {
+			continue
+		}
+
+		target, err := url.Parse(arg)
+		if err == nil && strings.EqualFold(target.Host, server.Host) {
+			return serverURL
+		}
+	}
+
+	return ""
+}
+
 func validLogLevels() []string {
 	return []string{"debug", "info", "warn", "error"}
 }
 
+// maskSecret keeps secret out of the logs, and out of the workflow's logs
+// when running in GitHub Actions. The workflow commands are written to stderr,
+// so that they don't end up in output which is redirected, like `ci list -o
+// json`. A token from $GITHUB_TOKEN is left to the runner, which masks the
+// secrets it passes to steps itself.
+func maskSecret(cmd *cli.Command, cfg *config, secret string) {
+	logging.AddSecret(secret)
+
+	if cfg.actions == nil || secret == os.Getenv("GITHUB_TOKEN") {
+		return
+	}
+
+	for _, command := range actions.MaskCommands(secret) {
+		fmt.Fprintln(cmd.Root().ErrWriter, command)
+	}
+}
+
 func initialiseConfig(ctx context.Context, cmd *cli.Command, cfg *config) error {
-	cfg.logger = logging.SetupLogger(slog.Level(defaultLogLevel))
+	cfg.logOptions = logging.Options{
+		Level:  slog.Level(defaultLogLevel),
+		Format: cmd.String("log-format"),
+	}
+	if path := cmd.String("log-file"); path != "" {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		// left open, to log to until exiting
+		cfg.logOptions.File = file
+	}
+	cfg.logger = logging.SetupLogger(cfg.logOptions)
 
 	cfg.logger.DebugContext(ctx, "debug logging enabled")
 
 	cfg.recheckInterval = cmd.Duration("recheck-interval")
-	cfg.pendingRecheckTime = cmd.Duration("pending-recheck-time")
+	cfg.maxRecheckInterval = cmd.Duration("max-recheck-interval")
+	cfg.fastRecheckPeriod = cmd.Duration("fast-recheck-period")
+	cfg.recheckJitter = cmd.Float("recheck-jitter")
+	cfg.checksGracePeriod = cmd.Duration("checks-grace-period")
+	cfg.requireChecks = cmd.Bool("require-checks")
+	cfg.useRepoConfig = cmd.Bool("repo-config")
 	cfg.globalTimeout = cmd.Duration("timeout")
+	cfg.actions = actions.FromEnv()
+
+	var err error
+	cfg.notify, cfg.notifyOn, err = parseNotifyFlags(cmd)
+	if err != nil {
+		return cli.Exit(err.Error(), utils.ExitUsage)
+	}
+
+	if addr := cmd.String("metrics-addr"); addr != "" {
+		if err := metrics.Serve(ctx, cfg.logger, addr); err != nil {
+			return err
+		}
+	}
+
+	cfg.shutdownTracing, err = tracing.Setup(ctx)
+	if err != nil {
+		return err
+	}
+
+	githubURL := cmd.String("github-url")
+	if githubURL == "" {
+		githubURL = actionsServerURL(cmd.Args().Slice())
+	}
+	githubURL = strings.TrimSuffix(githubURL, "/")
+	if githubURL == "https://github.com" {
+		githubURL = ""
+	}
 
 	token := cmd.String("github-token")
 	if token != "" {
 		cfg.logger.DebugContext(ctx, "will use github token for authentication")
-		cfg.logger.DebugContext(ctx, "using token starting with", "token", token[:10])
+		maskSecret(cmd, cfg, token)
 		cfg.AuthInfo.GithubToken = token
+		cfg.AuthInfo.BaseURL = githubURL
 
 		return nil
 	}
@@ -166,18 +536,21 @@ func initialiseConfig(ctx context.Context, cmd *cli.Command, cfg *config) error
 		}
 	}
 
+	maskSecret(cmd, cfg, string(privateKey))
+
 	appId := cmd.Int64("github-app-id")
 	installationID := cmd.Int64("github-app-installation-id")
 
 	if len(privateKey) == 0 || appId == 0 || installationID == 0 {
 		cfg.logger.ErrorContext(ctx, "must provide either a GitHub token or a GitHub app private key, app ID and installation ID")
-		cli.ShowAppHelpAndExit(cmd, 1)
+		cli.ShowAppHelpAndExit(cmd, utils.ExitUsage)
 	}
 
 	cfg.AuthInfo = github.AuthInfo{
 		InstallationID: installationID,
 		AppID:          appId,
 		PrivateKey:     privateKey,
+		BaseURL:        githubURL,
 	}
 
 	return nil
@@ -0,0 +1,65 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/grafana/wait-for-github/internal/actions"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestActionsServerURL(t *testing.T) {
+	t.Setenv("GITHUB_SERVER_URL", "https://github.example.com")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"same host", []string{"ci", "https://github.example.com/grafana/loki/commit/abc"}, "https://github.example.com"},
+		{"target flag", []string{"ci", "--target=https://GitHub.example.com/grafana/loki/pull/1"}, "https://github.example.com"},
+		{"github.com", []string{"pr", "https://github.com/grafana/loki/pull/1"}, ""},
+		{"owner repo ref", []string{"ci", "grafana", "loki", "main"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, actionsServerURL(tt.args))
+		})
+	}
+
+	t.Setenv("GITHUB_SERVER_URL", "")
+	require.Empty(t, actionsServerURL([]string{"ci", "https://github.example.com/grafana/loki/commit/abc"}))
+}
+
+func TestMaskSecret(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "ghs_fromrunner")
+
+	var stdout, stderr bytes.Buffer
+	cmd := &cli.Command{Writer: &stdout, ErrWriter: &stderr}
+	cfg := &config{actions: &actions.Step{}}
+
+	maskSecret(cmd, cfg, "ghp_given")
+	maskSecret(cmd, cfg, "ghs_fromrunner")
+	maskSecret(cmd, &config{}, "ghp_outside")
+
+	require.Empty(t, stdout.String(), "workflow commands mustn't mix with the output")
+	require.Equal(t, "::add-mask::ghp_given\n", stderr.String())
+}
@@ -0,0 +1,117 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/actions"
+	"github.com/grafana/wait-for-github/internal/github"
+)
+
+// writeCIStep writes the result of waiting for CI to the GitHub Actions step.
+func writeCIStep(step *actions.Step, result ciResult) error {
+	err := step.SetOutputs(
+		actions.Output{Name: "status", Value: result.Status},
+		actions.Output{Name: "sha", Value: result.SHA},
+		actions.Output{Name: "failed-checks", Value: strings.Join(failedChecks(result.Checks, result.Excluded), ",")},
+		actions.Output{Name: "rerun-count", Value: strconv.Itoa(result.Reruns)},
+	)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("CI for %s/%s@%s", result.Owner, result.Repo, result.Ref)
+	return step.AppendSummary(checksSummary(title, result.Status, result.Checks))
+}
+
+// writePRStep writes the result of waiting for a PR to the GitHub Actions
+// step.
+func writePRStep(step *actions.Step, pr *prCheck, records []checkRecord) error {
+	mergedAt := ""
+	if pr.mergedAt != 0 {
+		mergedAt = strconv.FormatInt(pr.mergedAt, 10)
+	}
+
+	err := step.SetOutputs(
+		actions.Output{Name: "status", Value: pr.status},
+		actions.Output{Name: "merged-sha", Value: pr.mergedCommit},
+		actions.Output{Name: "merged-at", Value: mergedAt},
+		actions.Output{Name: "failed-checks", Value: strings.Join(failedChecks(records, pr.excludes), ",")},
+		actions.Output{Name: "rerun-count", Value: strconv.Itoa(pr.retriesDone)},
+	)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("PR %s/%s#%d", pr.owner, pr.repo, pr.pr)
+	return step.AppendSummary(checksSummary(title, pr.status, records))
+}
+
+// failedChecks returns the names of the failed checks, leaving out the
+// excluded ones as they don't affect the result.
+func failedChecks(records []checkRecord, excludes []string) []string {
+	var failed []string
+	for _, record := range records {
+		if record.Status == github.CIStatusFailed.Name() && !slices.Contains(excludes, record.Name) {
+			failed = append(failed, record.Name)
+		}
+	}
+
+	return failed
+}
+
+// checksSummary returns a job summary, in markdown, of the final state of the
+// checks.
+func checksSummary(title, status string, records []checkRecord) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", markdownEscaper.Replace(title))
+	fmt.Fprintf(&b, "Overall status: **%s**\n\n", status)
+
+	if len(records) == 0 {
+		b.WriteString("No CI checks found.\n")
+		return b.String()
+	}
+
+	b.WriteString("| Check | Status | Conclusion | Duration | Details |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, record := range records {
+		name := record.Name
+		if record.Workflow != "" {
+			name = record.Workflow + " / " + name
+		}
+
+		duration := ""
+		if record.StartedAt != nil && record.CompletedAt != nil {
+			duration = record.CompletedAt.Sub(*record.StartedAt).Round(time.Second).String()
+		}
+
+		details := ""
+		if record.DetailsURL != "" {
+			details = fmt.Sprintf("[Details](%s)", record.DetailsURL)
+		}
+
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+			markdownEscaper.Replace(name), record.Status, record.Conclusion, duration, details)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,157 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/actions"
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStep(t *testing.T) *actions.Step {
+	t.Helper()
+
+	dir := t.TempDir()
+	return &actions.Step{
+		OutputPath:  filepath.Join(dir, "output"),
+		SummaryPath: filepath.Join(dir, "summary"),
+	}
+}
+
+// readOutputs returns the outputs written to step, as `name=value` lines.
+func readOutputs(t *testing.T, step *actions.Step) string {
+	t.Helper()
+
+	content, err := os.ReadFile(step.OutputPath)
+	require.NoError(t, err)
+
+	multiline := regexp.MustCompile(`(?m)^(\S+)<<(ghadelimiter_[0-9a-f]+)\n((?s:.*?))\n?ghadelimiter_[0-9a-f]+\n`)
+	return multiline.ReplaceAllString(string(content), "$1=$3\n")
+}
+
+func readSummary(t *testing.T, step *actions.Step) string {
+	t.Helper()
+
+	content, err := os.ReadFile(step.SummaryPath)
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+func TestCIStep(t *testing.T) {
+	t.Parallel()
+
+	step := newTestStep(t)
+	client := &FakeCIStatusChecker{
+		status: github.CIStatusFailed,
+		sha:    "abc123",
+		checks: []github.CICheckStatus{
+			github.CheckRun{Name: "build", Status: "COMPLETED", Conclusion: "SUCCESS"},
+			github.CheckRun{Name: "lint", Status: "COMPLETED", Conclusion: "FAILURE"},
+			github.CheckRun{Name: "test", Status: "COMPLETED", Conclusion: "FAILURE"},
+		},
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+		actions:         step,
+	}
+	ciConf := &ciConfig{owner: "owner", repo: "repo", ref: "main", excludes: []string{"lint"}}
+
+	err := checkCIStatus(context.Background(), client, cfg, ciConf, io.Discard)
+	require.Error(t, err)
+
+	require.Equal(t, "status=failed\nsha=abc123\nfailed-checks=test\nrerun-count=0\n", readOutputs(t, step))
+	require.Equal(t, `### CI for owner/repo@main
+
+Overall status: **failed**
+
+| Check | Status | Conclusion | Duration | Details |
+| --- | --- | --- | --- | --- |
+| build | passed | success |  |  |
+| lint | failed | failure |  |  |
+| test | failed | failure |  |  |
+`, readSummary(t, step))
+}
+
+func TestPRStep(t *testing.T) {
+	t.Parallel()
+
+	step := newTestStep(t)
+	client := &fakeGithubClientPRCheck{
+		MergedCommit: "def456",
+		HeadSHA:      "abc123",
+		MergedAt:     1234567890,
+		Checks: []github.CICheckStatus{
+			github.StatusContext{Context: "deploy", State: "SUCCESS", TargetURL: "https://example.com/deploy"},
+		},
+	}
+	cfg := &config{
+		recheckInterval: time.Millisecond,
+		logger:          testLogger,
+		actions:         step,
+	}
+	prConf := &prConfig{owner: "owner", repo: "repo", pr: 1}
+
+	err := checkPRMerged(context.Background(), client, cfg, prConf, io.Discard)
+	require.Error(t, err)
+
+	require.Equal(t, "status=merged\nmerged-sha=def456\nmerged-at=1234567890\nfailed-checks=\nrerun-count=0\n", readOutputs(t, step))
+	require.Equal(t, `### PR owner/repo#1
+
+Overall status: **merged**
+
+| Check | Status | Conclusion | Duration | Details |
+| --- | --- | --- | --- | --- |
+| deploy | passed | success |  | [Details](https://example.com/deploy) |
+`, readSummary(t, step))
+}
+
+func TestChecksSummary(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	completed := started.Add(90 * time.Second)
+
+	require.Equal(t, "### CI for owner/repo@main\n\nOverall status: **pending**\n\nNo CI checks found.\n",
+		checksSummary("CI for owner/repo@main", "pending", nil))
+
+	require.Equal(t, `### CI
+
+Overall status: **passed**
+
+| Check | Status | Conclusion | Duration | Details |
+| --- | --- | --- | --- | --- |
+| CI / lint\|vet | passed | success | 1m30s | [Details](https://example.com/1) |
+`, checksSummary("CI", "passed", []checkRecord{{
+		Name:        "lint|vet",
+		Workflow:    "CI",
+		Status:      "passed",
+		Conclusion:  "success",
+		StartedAt:   &started,
+		CompletedAt: &completed,
+		DetailsURL:  "https://example.com/1",
+	}}))
+}
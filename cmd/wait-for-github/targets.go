@@ -0,0 +1,107 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/grafana/wait-for-github/internal/ansi"
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/transform"
+)
+
+// targetFlags returns the flags for waiting on several targets at once.
+// targetUsage describes what a target is for the command.
+func targetFlags(targetUsage string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name: "target",
+			Usage: targetUsage + " Can be given multiple times, or several URLs can be " +
+				"given as arguments, to wait for all of them at once.",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("GITHUB_TARGETS"),
+			),
+		},
+		&cli.StringFlag{
+			Name: "mode",
+			Usage: "When waiting for several targets, whether all of them or any one of them " +
+				"must succeed. Valid modes are: " + strings.Join(utils.Modes(), ", ") + ".",
+			Value: utils.ModeAll,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("GITHUB_TARGET_MODE"),
+			),
+			Validator: func(s string) error {
+				if !slices.Contains(utils.Modes(), s) {
+					return fmt.Errorf("invalid mode %q: must be one of %s", s, strings.Join(utils.Modes(), ", "))
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// targetURLs returns the URLs of the targets to wait for, or nil if the
+// command's arguments should be parsed as a single target. Several targets
+// must be given as URLs, either with --target or as arguments.
+func targetURLs(cmd *cli.Command) []string {
+	urls := cmd.StringSlice("target")
+	args := cmd.Args().Slice()
+
+	// `owner repo ref` is a single target
+	if len(urls) == 0 && (len(args) < 2 || !looksLikeURL(args[0])) {
+		return nil
+	}
+
+	return append(args, urls...)
+}
+
+func looksLikeURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+// renderTargetResults writes a table with the result of each target.
+func renderTargetResults(results []utils.TargetResult, table tableWriter) error {
+	table.Header([]string{"Target", "Result", "Message"})
+
+	caser := ansi.NewANSITransformer(cases.Title(language.English))
+
+	var data [][]string
+	for _, result := range results {
+		status := github.CIStatusPending
+		switch {
+		case result.Done && result.ExitCode == 0:
+			status = github.CIStatusPassed
+		case result.Done:
+			status = github.CIStatusFailed
+		}
+
+		statusString, _, _ := transform.String(caser, status.String())
+		data = append(data, []string{result.Name, statusString, result.Message})
+	}
+
+	if err := table.Bulk(data); err != nil {
+		return fmt.Errorf("failed to write table: %w", err)
+	}
+
+	return table.Render()
+}
@@ -0,0 +1,254 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/utils"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestParseCITargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []ciConfig
+		wantErr string
+	}{
+		{
+			name: "single target",
+			args: []string{"owner", "repo", "abc123"},
+			want: []ciConfig{{owner: "owner", repo: "repo", ref: "abc123", writer: osFileWriter{}}},
+		},
+		{
+			name: "several URLs",
+			args: []string{"https://github.com/owner/repo/commit/abc123", "https://github.com/owner/other/pull/1"},
+			want: []ciConfig{
+				{owner: "owner", repo: "repo", ref: "abc123", writer: osFileWriter{}},
+				{owner: "owner", repo: "other", ref: "refs/pull/1/head", writer: osFileWriter{}},
+			},
+		},
+		{
+			name: "URLs with --target",
+			args: []string{"--target", "https://github.com/owner/other/pull/1", "https://github.com/owner/repo/commit/abc123"},
+			want: []ciConfig{
+				{owner: "owner", repo: "repo", ref: "abc123", writer: osFileWriter{}},
+				{owner: "owner", repo: "other", ref: "refs/pull/1/head", writer: osFileWriter{}},
+			},
+		},
+		{
+			name:    "invalid URL",
+			args:    []string{"https://github.com/owner/repo/commit/abc123", "https://invalid_url"},
+			wantErr: "invalid URL to either PR or commit: https://invalid_url",
+		},
+		{
+			name:    "result file with several URLs",
+			args:    []string{"--result-file", "result.json", "https://github.com/owner/repo/commit/abc123", "https://github.com/owner/other/pull/1"},
+			wantErr: "--result-file can only be used when waiting for a single commit",
+		},
+		{
+			name: "show failure logs",
+			args: []string{"--show-failure-logs", "owner", "repo", "abc123"},
+			want: []ciConfig{{owner: "owner", repo: "repo", ref: "abc123", writer: osFileWriter{}, failureLogLines: defaultFailureLogLines}},
+		},
+		{
+			name: "show failure logs with a number of lines",
+			args: []string{"--show-failure-logs=10", "owner", "repo", "abc123"},
+			want: []ciConfig{{owner: "owner", repo: "repo", ref: "abc123", writer: osFileWriter{}, failureLogLines: 10}},
+		},
+		{
+			name:    "show failure logs with several URLs",
+			args:    []string{"--show-failure-logs", "https://github.com/owner/repo/commit/abc123", "https://github.com/owner/other/pull/1"},
+			wantErr: "--show-failure-logs can only be used when waiting for a single commit",
+		},
+		{
+			name: "comment on a PR",
+			args: []string{"--comment", "https://github.com/owner/repo/pull/12"},
+			want: []ciConfig{{owner: "owner", repo: "repo", ref: "refs/pull/12/head", writer: osFileWriter{}, commentPR: 12}},
+		},
+		{
+			name:    "comment on a commit",
+			args:    []string{"--comment", "owner", "repo", "abc123"},
+			wantErr: "--comment can only be used when waiting for CI on a PR",
+		},
+		{
+			name:    "comment with several URLs",
+			args:    []string{"--comment", "https://github.com/owner/repo/pull/1", "https://github.com/owner/other/pull/2"},
+			wantErr: "--comment can only be used when waiting for a single commit",
+		},
+		{
+			name: "report as a commit status",
+			args: []string{"--report-as", "wfg/gate", "--report-as-type", "status", "owner", "repo", "abc123"},
+			want: []ciConfig{{owner: "owner", repo: "repo", ref: "abc123", writer: osFileWriter{}, reportAs: "wfg/gate", reportAsType: gateTypeStatus}},
+		},
+		{
+			name:    "report as with several URLs",
+			args:    []string{"--report-as", "wfg/gate", "https://github.com/owner/repo/commit/abc123", "https://github.com/owner/other/pull/1"},
+			wantErr: "--report-as can only be used when waiting for a single commit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg config
+			ciCmd := ciCommand(&cfg)
+			ciCmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+				got, err := parseCITargets(ctx, cmd, testLogger)
+				if tt.wantErr != "" {
+					require.EqualError(t, err, tt.wantErr)
+					return nil
+				}
+
+				require.NoError(t, err)
+				for i := range got {
+					got[i].checks = nil
+					got[i].excludes = nil
+				}
+				require.Equal(t, tt.want, got)
+				return nil
+			}
+
+			rootCmd := &cli.Command{
+				Name:      "root",
+				Commands:  []*cli.Command{ciCmd},
+				Writer:    io.Discard,
+				ErrWriter: io.Discard,
+			}
+
+			args := append([]string{"root", "ci"}, tt.args...)
+			require.NoError(t, rootCmd.Run(t.Context(), args))
+		})
+	}
+}
+
+func TestParsePRTargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    [][2]any
+		wantErr string
+	}{
+		{
+			name: "single target",
+			args: []string{"owner", "repo", "1"},
+			want: [][2]any{{"owner/repo", 1}},
+		},
+		{
+			name: "several URLs",
+			args: []string{"https://github.com/owner/repo/pull/1", "--target", "https://github.com/owner/other/pull/2"},
+			want: [][2]any{{"owner/repo", 1}, {"owner/other", 2}},
+		},
+		{
+			name:    "commit info file with several PRs",
+			args:    []string{"--commit-info-file", "info.json", "https://github.com/owner/repo/pull/1", "https://github.com/owner/other/pull/2"},
+			wantErr: "--commit-info-file can only be used when waiting for a single PR",
+		},
+		{
+			name:    "comment with several PRs",
+			args:    []string{"--comment", "https://github.com/owner/repo/pull/1", "https://github.com/owner/other/pull/2"},
+			wantErr: "--comment can only be used when waiting for a single PR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg config
+			prCmd := prCommand(&cfg)
+			prCmd.Before = nil
+			prCmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+				got, err := parsePRTargets(ctx, cmd, testLogger)
+				if tt.wantErr != "" {
+					require.EqualError(t, err, tt.wantErr)
+					return nil
+				}
+
+				require.NoError(t, err)
+				var prs [][2]any
+				for _, prConf := range got {
+					prs = append(prs, [2]any{prConf.owner + "/" + prConf.repo, prConf.pr})
+				}
+				require.Equal(t, tt.want, prs)
+				return nil
+			}
+
+			rootCmd := &cli.Command{
+				Name:      "root",
+				Commands:  []*cli.Command{prCmd},
+				Writer:    io.Discard,
+				ErrWriter: io.Discard,
+			}
+
+			args := append([]string{"root", "pr"}, tt.args...)
+			require.NoError(t, rootCmd.Run(t.Context(), args))
+		})
+	}
+}
+
+func TestRenderTargetResults(t *testing.T) {
+	t.Parallel()
+
+	table := &mockTableWriter{}
+	err := renderTargetResults([]utils.TargetResult{
+		{Name: "owner/repo#1", Done: true, ExitCode: 0, Message: "PR is merged"},
+		{Name: "owner/repo#2", Done: true, ExitCode: 1, Message: "PR is closed"},
+		{Name: "owner/repo#3"},
+	}, table)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"Target", "Result", "Message"}, table.headers)
+	require.Equal(t, [][]string{
+		{"owner/repo#1", "Passed", "PR is merged"},
+		{"owner/repo#2", "Failed", "PR is closed"},
+		{"owner/repo#3", "Pending", ""},
+	}, table.rows)
+}
+
+func TestCheckCIStatusForTargets(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config{
+		recheckInterval: 1,
+		logger:          testLogger,
+	}
+	ciConfs := []ciConfig{
+		{owner: "owner", repo: "repo", ref: "abc123", writer: osFileWriter{}},
+		{owner: "owner", repo: "other", ref: "def456"},
+	}
+
+	table := &mockTableWriter{}
+	err := checkCIStatusForTargets(context.Background(), &FakeCIStatusChecker{status: github.CIStatusPassed}, cfg, ciConfs, utils.ModeAll, table)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 0, exitErr.ExitCode())
+	require.Len(t, table.rows, 2)
+	require.Equal(t, "owner/repo@abc123", table.rows[0][0])
+	require.Equal(t, "owner/other@def456", table.rows[1][0])
+}
@@ -19,11 +19,17 @@ package main
 import (
 	"io"
 	"log/slog"
+
+	"github.com/grafana/wait-for-github/internal/utils"
 )
 
 var (
 	zero = 0
 	one  = 1
+
+	exitClosed        = utils.ExitClosed
+	exitTimeout       = utils.ExitTimeout
+	exitMergeConflict = utils.ExitMergeConflict
 )
 
 var testLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
@@ -0,0 +1,214 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is what GitHub said was left of one of its rate limits.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time
+}
+
+// rateLimitReserve is the fraction of a rate limit which isn't budgeted for
+// polling, so that there's some left for everything else, like reporting the
+// result of the wait.
+const rateLimitReserve = 0.05
+
+// RateBudget keeps track of the rate limits GitHub reports on its responses,
+// and of the requests made which count against them, so that polling can be
+// slowed down before running out rather than after. It's shared by everything
+// using the same credentials, so it tracks all the requests made.
+type RateBudget struct {
+	mu       sync.Mutex
+	limits   map[string]RateLimit
+	requests map[string]int
+}
+
+// DefaultRateBudget is the budget of the requests made by clients from
+// NewGithubClient.
+var DefaultRateBudget = &RateBudget{}
+
+// Requests returns how many points of each rate limit have been used by the
+// requests made so far, by resource, e.g. core or graphql. Each request costs
+// a point, except GraphQL queries, which cost what GitHub says they did.
+func (b *RateBudget) Requests() map[string]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return maps.Clone(b.requests)
+}
+
+// RequestsSince returns how many points of each rate limit have been used
+// since Requests returned before.
+func (b *RateBudget) RequestsSince(before map[string]int) map[string]int {
+	since := b.Requests()
+	for resource, n := range before {
+		since[resource] -= n
+	}
+
+	return since
+}
+
+// MinInterval returns how long to wait between polls which each use cost
+// points so that the rate limits last until they reset, and the resource
+// which needs the longest wait. It's 0 if the rate limits aren't known.
+func (b *RateBudget) MinInterval(now time.Time, cost map[string]int) (time.Duration, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		interval time.Duration
+		resource string
+	)
+	for res, n := range cost {
+		limit, ok := b.limits[res]
+		if !ok || n <= 0 || !limit.Reset.After(now) {
+			continue
+		}
+
+		untilReset := limit.Reset.Sub(now)
+		budget := limit.Remaining - int(float64(limit.Limit)*rateLimitReserve)
+
+		// if there's nothing left to spend, wait for the reset
+		resInterval := untilReset
+		if polls := budget / n; polls > 0 {
+			resInterval = untilReset / time.Duration(polls)
+		}
+
+		if resInterval > interval {
+			interval, resource = resInterval, res
+		}
+	}
+
+	return interval, resource
+}
+
+// Limit returns what GitHub last said was left of the rate limit for
+// resource.
+func (b *RateBudget) Limit(resource string) (RateLimit, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	limit, ok := b.limits[resource]
+	return limit, ok
+}
+
+// record records a request which cost points of the rate limit on resp, and
+// what's left of it. Responses without rate limit headers, such as artifacts
+// and logs downloaded from blob storage after a redirect, aren't counted.
+func (b *RateBudget) record(resp *http.Response, points int) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	used, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Used"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// unchanged responses to conditional requests don't count against the
+	// rate limit
+	if resp.StatusCode != http.StatusNotModified {
+		if b.requests == nil {
+			b.requests = make(map[string]int)
+		}
+		b.requests[resource] += points
+	}
+
+	if b.limits == nil {
+		b.limits = make(map[string]RateLimit)
+	}
+	b.limits[resource] = RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Used:      used,
+		Reset:     time.Unix(reset, 0),
+	}
+}
+
+// graphQLCost returns how many points of the rate limit the GraphQL query
+// answered by resp cost, as reported by its rateLimit field. Queries without
+// it are assumed to cost a point, the least a query can.
+// See: https://docs.github.com/en/graphql/overview/rate-limits-and-query-limits-for-the-graphql-api#primary-rate-limit
+func graphQLCost(resp *http.Response) (int, error) {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return 0, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var query struct {
+		Data struct {
+			RateLimit struct {
+				Cost int `json:"cost"`
+			} `json:"rateLimit"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &query); err != nil || query.Data.RateLimit.Cost < 1 {
+		return 1, nil
+	}
+
+	return query.Data.RateLimit.Cost, nil
+}
+
+type budgetTransport struct {
+	budget *RateBudget
+	next   http.RoundTripper
+}
+
+// Transport returns a transport which records the requests made through next
+// and the rate limits on the responses in b.
+func (b *RateBudget) Transport(next http.RoundTripper) http.RoundTripper {
+	return budgetTransport{budget: b, next: next}
+}
+
+func (t budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	points := 1
+	if resp.Header.Get("X-RateLimit-Resource") == "graphql" {
+		if points, err = graphQLCost(resp); err != nil {
+			return nil, err
+		}
+	}
+
+	t.budget.record(resp, points)
+	return resp, nil
+}
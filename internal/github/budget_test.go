@@ -0,0 +1,110 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateBudgetTransport(t *testing.T) {
+	t.Parallel()
+
+	reset := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blob" {
+			// e.g. a log downloaded after a redirect
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "1234")
+		w.Header().Set("X-RateLimit-Used", "3766")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		switch r.URL.Path {
+		case "/graphql":
+			w.Header().Set("X-RateLimit-Resource", "graphql")
+			_, _ = w.Write([]byte(`{"data":{"rateLimit":{"cost":3}}}`))
+		case "/graphql-without-cost":
+			w.Header().Set("X-RateLimit-Resource", "graphql")
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		case "/unchanged":
+			w.WriteHeader(http.StatusNotModified)
+		}
+	}))
+	defer server.Close()
+
+	var budget RateBudget
+	client := &http.Client{Transport: budget.Transport(http.DefaultTransport)}
+
+	get := func(path string) {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	get("/")
+	before := budget.Requests()
+	get("/")
+	get("/graphql")
+	get("/graphql-without-cost")
+	get("/unchanged")
+	get("/blob")
+
+	require.Equal(t, map[string]int{"core": 2, "graphql": 4}, budget.Requests())
+	require.Equal(t, map[string]int{"core": 1, "graphql": 4}, budget.RequestsSince(before))
+
+	limit, ok := budget.Limit("graphql")
+	require.True(t, ok)
+	require.Equal(t, RateLimit{Limit: 5000, Remaining: 1234, Used: 3766, Reset: reset}, limit)
+}
+
+func TestRateBudgetMinInterval(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	budget := RateBudget{limits: map[string]RateLimit{
+		// 250 of the 5000 are kept in reserve, leaving 100 requests
+		"core": {Limit: 5000, Remaining: 350, Reset: now.Add(time.Hour)},
+		// only the reserve is left
+		"graphql": {Limit: 5000, Remaining: 200, Reset: now.Add(10 * time.Minute)},
+		// already reset
+		"search": {Limit: 30, Remaining: 0, Reset: now.Add(-time.Minute)},
+	}}
+
+	interval, resource := budget.MinInterval(now, map[string]int{"core": 2})
+	require.Equal(t, "core", resource)
+	require.Equal(t, 72*time.Second, interval) // an hour over 50 checks
+
+	interval, resource = budget.MinInterval(now, map[string]int{"core": 2, "graphql": 1})
+	require.Equal(t, "graphql", resource)
+	require.Equal(t, 10*time.Minute, interval)
+
+	interval, _ = budget.MinInterval(now, map[string]int{"search": 1, "unknown": 1})
+	require.Zero(t, interval)
+
+	interval, _ = budget.MinInterval(now, nil)
+	require.Zero(t, interval)
+}
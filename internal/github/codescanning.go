@@ -0,0 +1,229 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/grafana/wait-for-github/internal/metrics"
+)
+
+type GetCodeScanningResults interface {
+	GetPRBaseRef
+	GetCodeScanningAnalyses(ctx context.Context, owner, repo, ref string) ([]CodeScanningAnalysis, error)
+	GetCodeScanningAlerts(ctx context.Context, owner, repo, ref string) ([]CodeScanningAlert, error)
+}
+
+// Code scanning alert severities. Security rules have a security severity
+// level (critical to low), other rules a plain severity (error to note).
+// See: https://docs.github.com/en/code-security/code-scanning/managing-code-scanning-alerts/about-code-scanning-alerts#about-alert-severity-and-security-severity-levels
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityNote     = "note"
+)
+
+// severityRanks orders the severities, so that security severities and plain
+// severities can be compared against a single threshold.
+var severityRanks = map[string]int{
+	SeverityCritical: 4,
+	SeverityHigh:     3,
+	SeverityError:    3,
+	SeverityMedium:   2,
+	SeverityWarning:  2,
+	SeverityLow:      1,
+	SeverityNote:     1,
+}
+
+// Severities returns all of the known severities, most severe first.
+func Severities() []string {
+	return []string{SeverityCritical, SeverityHigh, SeverityError, SeverityMedium, SeverityWarning, SeverityLow, SeverityNote}
+}
+
+// SeverityAtLeast reports whether severity is at least as severe as threshold.
+// Unknown severities never meet the threshold.
+func SeverityAtLeast(severity, threshold string) bool {
+	rank, ok := severityRanks[strings.ToLower(severity)]
+	if !ok {
+		return false
+	}
+
+	return rank >= severityRanks[strings.ToLower(threshold)]
+}
+
+// CodeScanningAnalysis is the result of a SARIF upload for a commit.
+type CodeScanningAnalysis struct {
+	ID           int64
+	Tool         string
+	Category     string
+	Ref          string
+	CommitSHA    string
+	Error        string
+	ResultsCount int
+}
+
+// CodeScanningAlert is an open code scanning alert.
+type CodeScanningAlert struct {
+	Number   int
+	Tool     string
+	RuleID   string
+	Severity string
+	Path     string
+	Line     int
+	URL      string
+}
+
+var commitSHARegexp = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// commitClockSkew is how much older than the commit an analysis can be and
+// still be of it, as commit dates come from the committer's clock.
+const commitClockSkew = time.Hour
+
+// GetCodeScanningAnalyses returns the code scanning analyses for the commit
+// that ref points at. If ref is a commit SHA, the analyses on any ref are
+// searched for ones of that commit. They're listed newest first, so the
+// search stops at those older than the commit.
+func (c GHClient) GetCodeScanningAnalyses(ctx context.Context, owner, repoName, ref string) ([]CodeScanningAnalysis, error) {
+	opts := &github.AnalysesListOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	if !commitSHARegexp.MatchString(ref) {
+		opts.Ref = github.Ptr(ref)
+	}
+
+	sha, committed, err := c.resolveCommit(ctx, owner, repoName, ref)
+	if err != nil {
+		return nil, err
+	}
+	oldest := committed.Add(-commitClockSkew)
+
+	var matching []CodeScanningAnalysis
+	for {
+		analyses, resp, err := c.client.CodeScanning.ListAnalysesForRepo(metrics.WithOperation(ctx, "ListCodeScanningAnalyses"), owner, repoName, opts)
+		if err != nil {
+			// GitHub returns a 404 when there are no analyses yet
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, nil
+			}
+
+			if respErr := c.handleResponseError(resp, "ListCodeScanningAnalyses", owner, repoName); respErr != nil {
+				return nil, respErr
+			}
+			return nil, fmt.Errorf("failed to list code scanning analyses: %w", err)
+		}
+
+		for _, analysis := range analyses {
+			if analysis.CreatedAt != nil && analysis.CreatedAt.Before(oldest) {
+				return matching, nil
+			}
+
+			if analysis.GetCommitSHA() != sha {
+				continue
+			}
+
+			matching = append(matching, CodeScanningAnalysis{
+				ID:           analysis.GetID(),
+				Tool:         analysis.GetTool().GetName(),
+				Category:     analysis.GetCategory(),
+				Ref:          analysis.GetRef(),
+				CommitSHA:    analysis.GetCommitSHA(),
+				Error:        analysis.GetError(),
+				ResultsCount: analysis.GetResultsCount(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			return matching, nil
+		}
+		opts.ListOptions.Page = resp.NextPage
+	}
+}
+
+// GetCodeScanningAlerts returns the open code scanning alerts on a ref.
+func (c GHClient) GetCodeScanningAlerts(ctx context.Context, owner, repoName, ref string) ([]CodeScanningAlert, error) {
+	opts := &github.AlertListOptions{
+		State: "open",
+		Ref:   ref,
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var alerts []CodeScanningAlert
+	for {
+		page, resp, err := c.client.CodeScanning.ListAlertsForRepo(metrics.WithOperation(ctx, "ListCodeScanningAlerts"), owner, repoName, opts)
+		if err != nil {
+			if respErr := c.handleResponseError(resp, "ListCodeScanningAlerts", owner, repoName); respErr != nil {
+				return nil, respErr
+			}
+			return nil, fmt.Errorf("failed to list code scanning alerts: %w", err)
+		}
+
+		for _, alert := range page {
+			severity := alert.GetRule().GetSecuritySeverityLevel()
+			if severity == "" {
+				severity = alert.GetRule().GetSeverity()
+			}
+
+			location := alert.GetMostRecentInstance().GetLocation()
+			alerts = append(alerts, CodeScanningAlert{
+				Number:   alert.GetNumber(),
+				Tool:     alert.GetTool().GetName(),
+				RuleID:   alert.GetRule().GetID(),
+				Severity: strings.ToLower(severity),
+				Path:     location.GetPath(),
+				Line:     location.GetStartLine(),
+				URL:      alert.GetHTMLURL(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.ListOptions.Page = resp.NextPage
+	}
+
+	return alerts, nil
+}
+
+// resolveCommit returns the SHA of the commit ref points to, and when it was
+// committed.
+func (c GHClient) resolveCommit(ctx context.Context, owner, repoName, ref string) (string, time.Time, error) {
+	// only the commit is needed, not its files
+	commit, resp, err := c.client.Repositories.GetCommit(metrics.WithOperation(ctx, "GetCommit"), owner, repoName, ref, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "GetCommit", owner, repoName); respErr != nil {
+			return "", time.Time{}, respErr
+		}
+		return "", time.Time{}, fmt.Errorf("failed to resolve %s to a commit: %w", ref, err)
+	}
+
+	return commit.GetSHA(), commit.GetCommit().GetCommitter().GetDate().Time, nil
+}
@@ -0,0 +1,202 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeverityAtLeast(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, SeverityAtLeast(SeverityCritical, SeverityHigh))
+	require.True(t, SeverityAtLeast(SeverityHigh, SeverityHigh))
+	require.True(t, SeverityAtLeast(SeverityError, SeverityHigh))
+	require.True(t, SeverityAtLeast("HIGH", SeverityHigh))
+	require.False(t, SeverityAtLeast(SeverityMedium, SeverityHigh))
+	require.False(t, SeverityAtLeast(SeverityNote, SeverityWarning))
+	require.False(t, SeverityAtLeast("", SeverityNote))
+}
+
+func TestGetCodeScanningAnalyses(t *testing.T) {
+	t.Parallel()
+
+	committed := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	analysis := func(id int64, sha string, created time.Time) *github.ScanningAnalysis {
+		return &github.ScanningAnalysis{
+			ID:        github.Ptr(id),
+			CommitSHA: github.Ptr(sha),
+			Ref:       github.Ptr("refs/heads/main"),
+			Tool:      &github.Tool{Name: github.Ptr("CodeQL")},
+			CreatedAt: &github.Timestamp{Time: created},
+		}
+	}
+	analyses := []*github.ScanningAnalysis{
+		analysis(1, "abc123def", committed.Add(time.Minute)),
+		analysis(2, "0000000", committed.Add(time.Minute)),
+	}
+
+	commit := func(sha string) mock.MockBackendOption {
+		return mock.WithRequestMatch(
+			mock.GetReposCommitsByOwnerByRepoByRef,
+			github.RepositoryCommit{
+				SHA:    github.Ptr(sha),
+				Commit: &github.Commit{Committer: &github.CommitAuthor{Date: &github.Timestamp{Time: committed}}},
+			},
+		)
+	}
+
+	t.Run("by commit SHA", func(t *testing.T) {
+		t.Parallel()
+
+		mockedHTTPClient := mock.NewMockedHTTPClient(
+			commit("abc123def"),
+			mock.WithRequestMatchHandler(
+				mock.GetReposCodeScanningAnalysesByOwnerByRepo,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					require.Empty(t, r.URL.Query().Get("ref"))
+					_, _ = w.Write(mock.MustMarshal(analyses))
+				}),
+			),
+		)
+
+		ghClient := newClientFromMock(t, mockedHTTPClient, "")
+		got, err := ghClient.GetCodeScanningAnalyses(context.Background(), "owner", "repo", "abc123d")
+
+		require.NoError(t, err)
+		require.Equal(t, []CodeScanningAnalysis{
+			{ID: 1, Tool: "CodeQL", Ref: "refs/heads/main", CommitSHA: "abc123def"},
+		}, got)
+	})
+
+	t.Run("by ref", func(t *testing.T) {
+		t.Parallel()
+
+		mockedHTTPClient := mock.NewMockedHTTPClient(
+			commit("0000000"),
+			mock.WithRequestMatchHandler(
+				mock.GetReposCodeScanningAnalysesByOwnerByRepo,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					require.Equal(t, "refs/heads/main", r.URL.Query().Get("ref"))
+					_, _ = w.Write(mock.MustMarshal(analyses))
+				}),
+			),
+		)
+
+		ghClient := newClientFromMock(t, mockedHTTPClient, "")
+		got, err := ghClient.GetCodeScanningAnalyses(context.Background(), "owner", "repo", "refs/heads/main")
+
+		require.NoError(t, err)
+		require.Equal(t, []CodeScanningAnalysis{
+			{ID: 2, Tool: "CodeQL", Ref: "refs/heads/main", CommitSHA: "0000000"},
+		}, got)
+	})
+
+	t.Run("across pages", func(t *testing.T) {
+		t.Parallel()
+
+		mockedHTTPClient := mock.NewMockedHTTPClient(
+			commit("abc123def"),
+			mock.WithRequestMatchPages(
+				mock.GetReposCodeScanningAnalysesByOwnerByRepo,
+				// analyses of later commits
+				[]*github.ScanningAnalysis{analysis(3, "1111111", committed.Add(time.Hour))},
+				[]*github.ScanningAnalysis{
+					analysis(1, "abc123def", committed.Add(time.Minute)),
+					analysis(4, "2222222", committed.Add(-2*commitClockSkew)),
+				},
+				// never needed, as it's past the analyses of the commit
+				[]*github.ScanningAnalysis{analysis(5, "abc123def", committed.Add(-3*commitClockSkew))},
+			),
+		)
+
+		ghClient := newClientFromMock(t, mockedHTTPClient, "")
+		got, err := ghClient.GetCodeScanningAnalyses(context.Background(), "owner", "repo", "abc123d")
+
+		require.NoError(t, err)
+		require.Equal(t, []CodeScanningAnalysis{
+			{ID: 1, Tool: "CodeQL", Ref: "refs/heads/main", CommitSHA: "abc123def"},
+		}, got)
+	})
+
+	t.Run("no analyses", func(t *testing.T) {
+		t.Parallel()
+
+		mockedHTTPClient := mock.NewMockedHTTPClient(
+			commit("abc123def"),
+			mock.WithRequestMatchHandler(
+				mock.GetReposCodeScanningAnalysesByOwnerByRepo,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mock.WriteError(w, http.StatusNotFound, "no analysis found")
+				}),
+			),
+		)
+
+		ghClient := newClientFromMock(t, mockedHTTPClient, "")
+		got, err := ghClient.GetCodeScanningAnalyses(context.Background(), "owner", "repo", "abc123d")
+
+		require.NoError(t, err)
+		require.Empty(t, got)
+	})
+}
+
+func TestGetCodeScanningAlerts(t *testing.T) {
+	t.Parallel()
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetReposCodeScanningAlertsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "open", r.URL.Query().Get("state"))
+				require.Equal(t, "refs/pull/1/merge", r.URL.Query().Get("ref"))
+
+				_, _ = w.Write(mock.MustMarshal([]*github.Alert{
+					{
+						Number:  github.Ptr(1),
+						Tool:    &github.Tool{Name: github.Ptr("CodeQL")},
+						Rule:    &github.Rule{ID: github.Ptr("go/sql-injection"), Severity: github.Ptr("error"), SecuritySeverityLevel: github.Ptr("High")},
+						HTMLURL: github.Ptr("https://github.com/owner/repo/security/code-scanning/1"),
+						MostRecentInstance: &github.MostRecentInstance{
+							Location: &github.Location{Path: github.Ptr("main.go"), StartLine: github.Ptr(10)},
+						},
+					},
+					{
+						Number: github.Ptr(2),
+						Tool:   &github.Tool{Name: github.Ptr("CodeQL")},
+						Rule:   &github.Rule{ID: github.Ptr("go/unused"), Severity: github.Ptr("note")},
+					},
+				}))
+			}),
+		),
+	)
+
+	ghClient := newClientFromMock(t, mockedHTTPClient, "")
+	got, err := ghClient.GetCodeScanningAlerts(context.Background(), "owner", "repo", "refs/pull/1/merge")
+
+	require.NoError(t, err)
+	require.Equal(t, []CodeScanningAlert{
+		{Number: 1, Tool: "CodeQL", RuleID: "go/sql-injection", Severity: SeverityHigh, Path: "main.go", Line: 10, URL: "https://github.com/owner/repo/security/code-scanning/1"},
+		{Number: 2, Tool: "CodeQL", RuleID: "go/unused", Severity: SeverityNote},
+	}, got)
+}
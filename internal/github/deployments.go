@@ -0,0 +1,94 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/grafana/wait-for-github/internal/metrics"
+)
+
+type GetDeploymentStatus interface {
+	GetDeploymentStatus(ctx context.Context, owner, repo, sha, environment string) (*Deployment, error)
+}
+
+// Deployment states, as reported by the latest status of a deployment. A
+// deployment without any statuses yet is pending.
+// See: https://docs.github.com/en/rest/deployments/statuses#create-a-deployment-status
+const (
+	DeploymentStateError      = "error"
+	DeploymentStateFailure    = "failure"
+	DeploymentStateInactive   = "inactive"
+	DeploymentStateInProgress = "in_progress"
+	DeploymentStateQueued     = "queued"
+	DeploymentStatePending    = "pending"
+	DeploymentStateSuccess    = "success"
+)
+
+// Deployment is the newest deployment of a commit to an environment.
+type Deployment struct {
+	ID             int64
+	Environment    string
+	State          string
+	EnvironmentURL string
+}
+
+// GetDeploymentStatus returns the newest deployment of the commit sha to
+// environment, with the state of its latest status, or nil if the commit
+// hasn't been deployed there yet.
+func (c GHClient) GetDeploymentStatus(ctx context.Context, owner, repoName, sha, environment string) (*Deployment, error) {
+	// deployments are listed newest first
+	deployments, resp, err := c.client.Repositories.ListDeployments(metrics.WithOperation(ctx, "ListDeployments"), owner, repoName, &github.DeploymentsListOptions{
+		SHA:         sha,
+		Environment: environment,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "ListDeployments", owner, repoName); respErr != nil {
+			return nil, respErr
+		}
+		return nil, fmt.Errorf("failed to list deployments of %s to %s: %w", sha, environment, err)
+	}
+
+	if len(deployments) == 0 {
+		return nil, nil
+	}
+	deployment := deployments[0]
+
+	// and so are their statuses
+	statuses, resp, err := c.client.Repositories.ListDeploymentStatuses(metrics.WithOperation(ctx, "ListDeploymentStatuses"), owner, repoName, deployment.GetID(), &github.ListOptions{PerPage: 1})
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "ListDeploymentStatuses", owner, repoName); respErr != nil {
+			return nil, respErr
+		}
+		return nil, fmt.Errorf("failed to list statuses of deployment %d: %w", deployment.GetID(), err)
+	}
+
+	found := &Deployment{
+		ID:          deployment.GetID(),
+		Environment: deployment.GetEnvironment(),
+		State:       DeploymentStatePending,
+	}
+	if len(statuses) > 0 {
+		found.State = statuses[0].GetState()
+		found.EnvironmentURL = statuses[0].GetEnvironmentURL()
+	}
+
+	return found, nil
+}
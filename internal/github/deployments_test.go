@@ -0,0 +1,92 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDeploymentStatus(t *testing.T) {
+	t.Parallel()
+
+	deployments := func(found ...*github.Deployment) mock.MockBackendOption {
+		return mock.WithRequestMatchHandler(
+			mock.GetReposDeploymentsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "abc123", r.URL.Query().Get("sha"))
+				require.Equal(t, "production", r.URL.Query().Get("environment"))
+				_, _ = w.Write(mock.MustMarshal(found))
+			}),
+		)
+	}
+	production := &github.Deployment{ID: github.Ptr(int64(2)), Environment: github.Ptr("production")}
+
+	tests := []struct {
+		name     string
+		mock     []mock.MockBackendOption
+		expected *Deployment
+	}{
+		{
+			name:     "not deployed yet",
+			mock:     []mock.MockBackendOption{deployments()},
+			expected: nil,
+		},
+		{
+			name: "no statuses yet",
+			mock: []mock.MockBackendOption{
+				deployments(production),
+				mock.WithRequestMatch(
+					mock.GetReposDeploymentsStatusesByOwnerByRepoByDeploymentId,
+					[]*github.DeploymentStatus{},
+				),
+			},
+			expected: &Deployment{ID: 2, Environment: "production", State: DeploymentStatePending},
+		},
+		{
+			name: "latest status",
+			mock: []mock.MockBackendOption{
+				deployments(production),
+				mock.WithRequestMatch(
+					mock.GetReposDeploymentsStatusesByOwnerByRepoByDeploymentId,
+					[]*github.DeploymentStatus{
+						{State: github.Ptr(DeploymentStateSuccess), EnvironmentURL: github.Ptr("https://example.com")},
+						{State: github.Ptr(DeploymentStateInProgress)},
+					},
+				),
+			},
+			expected: &Deployment{ID: 2, Environment: "production", State: DeploymentStateSuccess, EnvironmentURL: "https://example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ghClient := newClientFromMock(t, mock.NewMockedHTTPClient(tt.mock...), "")
+			got, err := ghClient.GetDeploymentStatus(context.Background(), "owner", "repo", "abc123", "production")
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/grafana/wait-for-github/internal/metrics"
+	"github.com/grafana/wait-for-github/internal/tracing"
+)
+
+// downloadClient fetches artifacts and logs from where GitHub redirects to,
+// which is blob storage with a pre-signed URL. It doesn't have the
+// credentials for GitHub, so that they aren't sent anywhere else, and the
+// downloads aren't cached, as they can be large.
+var downloadClient = &http.Client{Transport: tracing.Transport(metrics.Transport(http.DefaultTransport))}
+
+type noRedirectsKey struct{}
+
+// withoutRedirects returns a context which makes the client return redirects
+// rather than follow them, so that the URL they're to can be fetched without
+// the credentials for GitHub.
+func withoutRedirects(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRedirectsKey{}, true)
+}
+
+// checkRedirect is the CheckRedirect of the client which makes requests to
+// GitHub, which follows redirects unless the context says not to.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if noRedirects, _ := req.Context().Value(noRedirectsKey{}).(bool); noRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	// the same limit as the default
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after %d redirects", len(via))
+	}
+
+	return nil
+}
+
+// download returns the body at u, which GitHub redirected to. The caller must
+// close it.
+func download(ctx context.Context, operation string, u *url.URL) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, operation), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status downloading from %s: %s", u.Host, resp.Status)
+	}
+
+	return resp.Body, nil
+}
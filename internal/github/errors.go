@@ -1,10 +1,20 @@
 package github
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v89/github"
 )
 
+// ErrMergeConflict is returned by MergePR when the PR conflicts with its base
+// branch, so merging it can't succeed until someone resolves that.
+var ErrMergeConflict = errors.New("PR has merge conflicts")
+
 type GitHubRateLimitError struct {
 	Operation string
 	Owner     string
@@ -57,11 +67,12 @@ func (e *GitHubAcceptedError) Unwrap() error {
 }
 
 type GitHubAPIError struct {
-	Operation string
-	Owner     string
-	Repo      string
-	Status    string
-	Err       error
+	Operation  string
+	Owner      string
+	Repo       string
+	Status     string
+	StatusCode int
+	Err        error
 }
 
 func (e *GitHubAPIError) Error() string {
@@ -72,3 +83,64 @@ func (e *GitHubAPIError) Error() string {
 func (e *GitHubAPIError) Unwrap() error {
 	return e.Err
 }
+
+// GitHubTransientError is GitHub failing to answer for a reason which should
+// go away by itself, like a GraphQL query timing out.
+type GitHubTransientError struct {
+	Operation string
+	Owner     string
+	Repo      string
+	Message   string
+	Err       error
+}
+
+func (e *GitHubTransientError) Error() string {
+	return fmt.Sprintf("GitHub failed the %s operation on %s/%s: %s",
+		e.Operation, e.Owner, e.Repo, e.Message)
+}
+
+func (e *GitHubTransientError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthError returns whether err is GitHub rejecting a request because the
+// credentials are invalid, or don't have permission for it.
+func IsAuthError(err error) bool {
+	isAuthStatus := func(code int) bool {
+		return code == http.StatusUnauthorized || code == http.StatusForbidden
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		return isAuthStatus(respErr.Response.StatusCode)
+	}
+
+	var apiErr *GitHubAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		return isAuthStatus(apiErr.StatusCode)
+	}
+
+	// from exchanging the app's credentials for an installation token
+	var installationErr *ghinstallation.HTTPError
+	if errors.As(err, &installationErr) && installationErr.Response != nil {
+		return isAuthStatus(installationErr.Response.StatusCode)
+	}
+
+	// the GraphQL client doesn't have typed errors for HTTP failures, which
+	// are only turned into a GitHubAPIError when the response was kept
+	if err != nil {
+		msg := err.Error()
+		return strings.Contains(msg, "non-200 OK status code: 401") || strings.Contains(msg, "non-200 OK status code: 403")
+	}
+
+	return false
+}
+
+// isRateLimitError returns whether err is GitHub rejecting a request because a
+// rate limit was hit.
+func isRateLimitError(err error) bool {
+	var rle *GitHubRateLimitError
+	var arle *GitHubAbuseRateLimitError
+
+	return errors.As(err, &rle) || errors.As(err, &arle)
+}
@@ -0,0 +1,222 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/grafana/wait-for-github/internal/metrics"
+)
+
+type GetFailureDetails interface {
+	GetFailedStepLog(ctx context.Context, owner, repo string, jobID int64, maxLines int) (FailedStepLog, error)
+	GetCheckRunOutput(ctx context.Context, owner, repo string, checkRunID int64) (CheckRunOutput, error)
+}
+
+type GetCheckRunAnnotations interface {
+	GetCheckRunAnnotations(ctx context.Context, owner, repo string, checkRunID int64) ([]CheckRunAnnotation, error)
+}
+
+// Check run annotation levels.
+const (
+	AnnotationLevelNotice  = "notice"
+	AnnotationLevelWarning = "warning"
+	AnnotationLevelFailure = "failure"
+)
+
+// FailedStepLog is the end of the log of the step a GitHub Actions job failed
+// at.
+type FailedStepLog struct {
+	Job string
+	// Step is the step which failed. It's empty if there's no failed step, in
+	// which case Lines is the end of the whole job's log.
+	Step  string
+	Lines []string
+}
+
+// CheckRunOutput is what a check run reports besides its conclusion and
+// annotations.
+type CheckRunOutput struct {
+	Title   string
+	Summary string
+}
+
+// CheckRunAnnotation is a note a check run left on some lines of a file.
+type CheckRunAnnotation struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Level     string
+	Title     string
+	Message   string
+}
+
+// maxLogLineLength is the longest log line which can be read. Longer lines
+// fail the download rather than being cut.
+const maxLogLineLength = 1024 * 1024
+
+// GetFailedStepLog returns the last maxLines lines of the log of the step the
+// GitHub Actions job failed at.
+func (c GHClient) GetFailedStepLog(ctx context.Context, owner, repoName string, jobID int64, maxLines int) (FailedStepLog, error) {
+	job, resp, err := c.client.Actions.GetWorkflowJobByID(metrics.WithOperation(ctx, "GetWorkflowJobByID"), owner, repoName, jobID)
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "GetWorkflowJobByID", owner, repoName); respErr != nil {
+			return FailedStepLog{}, respErr
+		}
+		return FailedStepLog{}, fmt.Errorf("failed to get job %d: %w", jobID, err)
+	}
+
+	result := FailedStepLog{Job: job.GetName()}
+
+	var failed *github.TaskStep
+	for _, step := range job.Steps {
+		if strings.ToLower(step.GetConclusion()) == RunConclusionFailure {
+			failed = step
+			break
+		}
+	}
+	if failed != nil {
+		result.Step = failed.GetName()
+	}
+
+	// like artifacts, logs are behind a redirect to blob storage, which is
+	// fetched without the credentials for GitHub
+	u, resp, err := c.client.Actions.GetWorkflowJobLogs(withoutRedirects(metrics.WithOperation(ctx, "DownloadJobLogs")), owner, repoName, jobID, 0)
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "DownloadJobLogs", owner, repoName); respErr != nil {
+			return FailedStepLog{}, respErr
+		}
+		return FailedStepLog{}, fmt.Errorf("failed to download logs of job %d: %w", jobID, err)
+	}
+
+	body, err := download(ctx, "DownloadJobLogsBlob", u)
+	if err != nil {
+		return FailedStepLog{}, fmt.Errorf("failed to download logs of job %d: %w", jobID, err)
+	}
+	defer body.Close()
+
+	lines, err := tailStepLog(body, failed, maxLines)
+	if err != nil {
+		return FailedStepLog{}, fmt.Errorf("failed to read logs of job %d: %w", jobID, err)
+	}
+	result.Lines = lines
+
+	return result, nil
+}
+
+// tailStepLog returns the last maxLines lines of a job log which were written
+// while step was running, or of the whole log if step is nil. Each line of the
+// log starts with a timestamp, which is removed.
+func tailStepLog(r io.Reader, step *github.TaskStep, maxLines int) ([]string, error) {
+	var start, end time.Time
+	if step != nil && step.StartedAt != nil && step.CompletedAt != nil {
+		// step times are to the second, log times much more precise
+		start = step.StartedAt.Truncate(time.Second)
+		end = step.CompletedAt.Truncate(time.Second).Add(time.Second)
+	}
+
+	var lines []string
+	inStep := start.IsZero()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLogLineLength)
+	for scanner.Scan() {
+		// the log starts with a byte order mark
+		line := strings.TrimPrefix(scanner.Text(), "\ufeff")
+
+		// lines without a timestamp continue the previous one
+		if timestamp, rest, ok := strings.Cut(line, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+				line = rest
+				if !start.IsZero() {
+					inStep = !t.Before(start) && t.Before(end)
+				}
+			}
+		}
+
+		if !inStep {
+			continue
+		}
+
+		lines = append(lines, line)
+		if len(lines) > maxLines {
+			lines = lines[1:]
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// GetCheckRunOutput returns the output of a check run.
+func (c GHClient) GetCheckRunOutput(ctx context.Context, owner, repoName string, checkRunID int64) (CheckRunOutput, error) {
+	checkRun, resp, err := c.client.Checks.GetCheckRun(metrics.WithOperation(ctx, "GetCheckRun"), owner, repoName, checkRunID)
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "GetCheckRun", owner, repoName); respErr != nil {
+			return CheckRunOutput{}, respErr
+		}
+		return CheckRunOutput{}, fmt.Errorf("failed to get check run %d: %w", checkRunID, err)
+	}
+
+	return CheckRunOutput{
+		Title:   checkRun.GetOutput().GetTitle(),
+		Summary: checkRun.GetOutput().GetSummary(),
+	}, nil
+}
+
+// GetCheckRunAnnotations returns all the annotations of a check run.
+func (c GHClient) GetCheckRunAnnotations(ctx context.Context, owner, repoName string, checkRunID int64) ([]CheckRunAnnotation, error) {
+	var result []CheckRunAnnotation
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		annotations, resp, err := c.client.Checks.ListCheckRunAnnotations(metrics.WithOperation(ctx, "ListCheckRunAnnotations"), owner, repoName, checkRunID, opts)
+		if err != nil {
+			if respErr := c.handleResponseError(resp, "ListCheckRunAnnotations", owner, repoName); respErr != nil {
+				return nil, respErr
+			}
+			return nil, fmt.Errorf("failed to list annotations of check run %d: %w", checkRunID, err)
+		}
+
+		for _, annotation := range annotations {
+			result = append(result, CheckRunAnnotation{
+				Path:      annotation.GetPath(),
+				StartLine: annotation.GetStartLine(),
+				EndLine:   annotation.GetEndLine(),
+				Level:     annotation.GetAnnotationLevel(),
+				Title:     annotation.GetTitle(),
+				Message:   annotation.GetMessage(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return result, nil
+}
@@ -0,0 +1,140 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const jobLog = "\ufeff2026-05-01T10:00:00.1000000Z ##[group]Run actions/checkout@v4\n" +
+	"2026-05-01T10:00:01.2000000Z checked out\n" +
+	"2026-05-01T10:00:02.0000000Z ##[group]Run make test\n" +
+	"2026-05-01T10:00:03.5000000Z --- FAIL: TestSomething\n" +
+	"    expected 1, got 2\n" +
+	"2026-05-01T10:00:04.9000000Z FAIL\n" +
+	"2026-05-01T10:00:05.1000000Z ##[error]Process completed with exit code 1.\n" +
+	"2026-05-01T10:00:06.0000000Z Post job cleanup.\n"
+
+func timestamp(s string) *github.Timestamp {
+	t, _ := time.Parse(time.RFC3339, s)
+	return &github.Timestamp{Time: t}
+}
+
+func TestGetFailedStepLog(t *testing.T) {
+	t.Parallel()
+
+	job := github.WorkflowJob{
+		Name: github.Ptr("test"),
+		Steps: []*github.TaskStep{
+			{Name: github.Ptr("Checkout"), Conclusion: github.Ptr("success"), StartedAt: timestamp("2026-05-01T10:00:00Z"), CompletedAt: timestamp("2026-05-01T10:00:01Z")},
+			{Name: github.Ptr("Test"), Conclusion: github.Ptr("failure"), StartedAt: timestamp("2026-05-01T10:00:02Z"), CompletedAt: timestamp("2026-05-01T10:00:05Z")},
+			{Name: github.Ptr("Post"), Conclusion: github.Ptr("success"), StartedAt: timestamp("2026-05-01T10:00:06Z"), CompletedAt: timestamp("2026-05-01T10:00:06Z")},
+		},
+	}
+
+	server, _ := newRedirectingServers(t, "repos/owner/repo/actions/jobs/10/logs", jobLog, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/repos/owner/repo/actions/jobs/10", r.URL.Path)
+		_, _ = w.Write(mock.MustMarshal(job))
+	})
+
+	ghClient, err := AuthenticateWithToken(context.Background(), testLogger, "token", server.URL)
+	require.NoError(t, err)
+
+	got, err := ghClient.GetFailedStepLog(context.Background(), "owner", "repo", 10, 3)
+
+	require.NoError(t, err)
+	require.Equal(t, FailedStepLog{
+		Job:   "test",
+		Step:  "Test",
+		Lines: []string{"    expected 1, got 2", "FAIL", "##[error]Process completed with exit code 1."},
+	}, got)
+}
+
+func TestTailStepLog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no failed step", func(t *testing.T) {
+		t.Parallel()
+
+		lines, err := tailStepLog(strings.NewReader(jobLog), nil, 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"##[error]Process completed with exit code 1.", "Post job cleanup."}, lines)
+	})
+
+	t.Run("step without times", func(t *testing.T) {
+		t.Parallel()
+
+		lines, err := tailStepLog(strings.NewReader(jobLog), &github.TaskStep{Name: github.Ptr("Test")}, 1)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Post job cleanup."}, lines)
+	})
+}
+
+func TestGetCheckRunOutput(t *testing.T) {
+	t.Parallel()
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposCheckRunsByOwnerByRepoByCheckRunId,
+			github.CheckRun{
+				Output: &github.CheckRunOutput{
+					Title:   github.Ptr("2 problems"),
+					Summary: github.Ptr("Lint found 2 problems"),
+				},
+			},
+		),
+	)
+
+	ghClient := newClientFromMock(t, mockedHTTPClient, "")
+	got, err := ghClient.GetCheckRunOutput(context.Background(), "owner", "repo", 20)
+
+	require.NoError(t, err)
+	require.Equal(t, CheckRunOutput{Title: "2 problems", Summary: "Lint found 2 problems"}, got)
+}
+
+func TestGetCheckRunAnnotations(t *testing.T) {
+	t.Parallel()
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchPages(
+			mock.GetReposCheckRunsAnnotationsByOwnerByRepoByCheckRunId,
+			[]*github.CheckRunAnnotation{
+				{Path: github.Ptr("main.go"), StartLine: github.Ptr(3), EndLine: github.Ptr(3), AnnotationLevel: github.Ptr("failure"), Message: github.Ptr("unused variable")},
+			},
+			[]*github.CheckRunAnnotation{
+				{Path: github.Ptr("util.go"), StartLine: github.Ptr(10), EndLine: github.Ptr(12), AnnotationLevel: github.Ptr("warning"), Title: github.Ptr("gocyclo"), Message: github.Ptr("too complex")},
+			},
+		),
+	)
+
+	ghClient := newClientFromMock(t, mockedHTTPClient, "")
+	got, err := ghClient.GetCheckRunAnnotations(context.Background(), "owner", "repo", 20)
+
+	require.NoError(t, err)
+	require.Equal(t, []CheckRunAnnotation{
+		{Path: "main.go", StartLine: 3, EndLine: 3, Level: AnnotationLevelFailure, Message: "unused variable"},
+		{Path: "util.go", StartLine: 10, EndLine: 12, Level: AnnotationLevelWarning, Title: "gocyclo", Message: "too complex"},
+	}, got)
+}
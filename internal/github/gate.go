@@ -0,0 +1,157 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/grafana/wait-for-github/internal/metrics"
+)
+
+type ReportGate interface {
+	ReportGateCheckRun(ctx context.Context, owner, repo, sha string, checkRunID int64, gate Gate) (int64, error)
+	ReportGateStatus(ctx context.Context, owner, repo, sha string, gate Gate) error
+}
+
+// Gate is the state of a check which stands in for the checks a wait is
+// waiting for, so that branch protection can require just the one.
+type Gate struct {
+	Name string
+	// Status is pending while waiting, and passed or failed once CI has
+	// finished. Anything else means the wait stopped before CI finished.
+	Status  CIStatus
+	Title   string
+	Summary string
+}
+
+type gateKey struct{}
+
+// WithGate returns a context which makes GetCIStatus ignore the gate called
+// name while it's pending, since it only finishes once the wait does.
+func WithGate(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, gateKey{}, name)
+}
+
+// gateFromContext returns the name of the gate set with WithGate, if any.
+func gateFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(gateKey{}).(string)
+	return name, ok
+}
+
+// maxStatusDescriptionLength is the longest description a commit status can
+// have.
+const maxStatusDescriptionLength = 140
+
+// ReportGateCheckRun creates the gate's check run on sha if checkRunID is 0,
+// and updates it otherwise. It returns the ID of the check run. Only GitHub
+// Apps can create check runs.
+func (c GHClient) ReportGateCheckRun(ctx context.Context, owner, repoName, sha string, checkRunID int64, gate Gate) (int64, error) {
+	status := RunStatusInProgress
+	var conclusion *string
+	var completedAt *github.Timestamp
+	if gate.Status != CIStatusPending {
+		status = RunStatusCompleted
+		completedAt = &github.Timestamp{Time: time.Now()}
+
+		switch gate.Status {
+		case CIStatusPassed:
+			conclusion = github.Ptr(RunConclusionSuccess)
+		case CIStatusFailed:
+			conclusion = github.Ptr(RunConclusionFailure)
+		default:
+			conclusion = github.Ptr(RunConclusionCancelled)
+		}
+	}
+
+	output := &github.CheckRunOutput{
+		Title:   github.Ptr(gate.Title),
+		Summary: github.Ptr(gate.Summary),
+	}
+
+	if checkRunID == 0 {
+		checkRun, resp, err := c.client.Checks.CreateCheckRun(metrics.WithOperation(ctx, "CreateCheckRun"), owner, repoName, github.CreateCheckRunOptions{
+			Name:        gate.Name,
+			HeadSHA:     sha,
+			Status:      github.Ptr(status),
+			Conclusion:  conclusion,
+			CompletedAt: completedAt,
+			Output:      output,
+		})
+		if err != nil {
+			if respErr := c.handleResponseError(resp, "CreateCheckRun", owner, repoName); respErr != nil {
+				return 0, respErr
+			}
+			return 0, fmt.Errorf("failed to create check run %q: %w", gate.Name, err)
+		}
+
+		return checkRun.GetID(), nil
+	}
+
+	_, resp, err := c.client.Checks.UpdateCheckRun(metrics.WithOperation(ctx, "UpdateCheckRun"), owner, repoName, checkRunID, github.UpdateCheckRunOptions{
+		Name:        gate.Name,
+		Status:      github.Ptr(status),
+		Conclusion:  conclusion,
+		CompletedAt: completedAt,
+		Output:      output,
+	})
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "UpdateCheckRun", owner, repoName); respErr != nil {
+			return 0, respErr
+		}
+		return 0, fmt.Errorf("failed to update check run %q: %w", gate.Name, err)
+	}
+
+	return checkRunID, nil
+}
+
+// ReportGateStatus sets the gate's commit status on sha. Statuses have no
+// room for a summary, so only the title is used.
+func (c GHClient) ReportGateStatus(ctx context.Context, owner, repoName, sha string, gate Gate) error {
+	var state string
+	switch gate.Status {
+	case CIStatusPending:
+		state = StatusStatePending
+	case CIStatusPassed:
+		state = StatusStateSuccess
+	case CIStatusFailed:
+		state = StatusStateFailure
+	default:
+		state = StatusStateError
+	}
+
+	description := gate.Title
+	if runes := []rune(description); len(runes) > maxStatusDescriptionLength {
+		description = string(runes[:maxStatusDescriptionLength-3]) + "..."
+	}
+
+	_, resp, err := c.client.Repositories.CreateStatus(metrics.WithOperation(ctx, "CreateStatus"), owner, repoName, sha, github.RepoStatus{
+		State:       github.Ptr(state),
+		Context:     github.Ptr(gate.Name),
+		Description: github.Ptr(description),
+	})
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "CreateStatus", owner, repoName); respErr != nil {
+			return respErr
+		}
+		return fmt.Errorf("failed to set commit status %q: %w", gate.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportGateCheckRun(t *testing.T) {
+	t.Parallel()
+
+	var created github.CreateCheckRunOptions
+	var updated github.UpdateCheckRunOptions
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.PostReposCheckRunsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write(mock.MustMarshal(github.CheckRun{ID: github.Ptr[int64](42)}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PatchReposCheckRunsByOwnerByRepoByCheckRunId,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/repos/owner/repo/check-runs/42", r.URL.Path)
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&updated))
+				_, _ = w.Write(mock.MustMarshal(github.CheckRun{ID: github.Ptr[int64](42)}))
+			}),
+		),
+	)
+
+	ghClient := newClientFromMock(t, mockedHTTPClient, "")
+
+	id, err := ghClient.ReportGateCheckRun(context.Background(), "owner", "repo", "abc123", 0, Gate{
+		Name:    "wfg/gate",
+		Status:  CIStatusPending,
+		Title:   "Waiting for 2 checks",
+		Summary: "| Check | Status |",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(42), id)
+	require.Equal(t, "wfg/gate", created.Name)
+	require.Equal(t, "abc123", created.HeadSHA)
+	require.Equal(t, RunStatusInProgress, created.GetStatus())
+	require.Nil(t, created.Conclusion)
+	require.Equal(t, "Waiting for 2 checks", created.GetOutput().GetTitle())
+
+	id, err = ghClient.ReportGateCheckRun(context.Background(), "owner", "repo", "abc123", 42, Gate{
+		Name:   "wfg/gate",
+		Status: CIStatusFailed,
+		Title:  "1 check failed",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(42), id)
+	require.Equal(t, RunStatusCompleted, updated.GetStatus())
+	require.Equal(t, RunConclusionFailure, updated.GetConclusion())
+	require.NotNil(t, updated.CompletedAt)
+}
+
+func TestReportGateStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		status              CIStatus
+		title               string
+		expectedState       string
+		expectedDescription string
+	}{
+		{
+			name:                "pending",
+			status:              CIStatusPending,
+			title:               "Waiting for 2 checks",
+			expectedState:       StatusStatePending,
+			expectedDescription: "Waiting for 2 checks",
+		},
+		{
+			name:                "passed",
+			status:              CIStatusPassed,
+			title:               "All checks passed",
+			expectedState:       StatusStateSuccess,
+			expectedDescription: "All checks passed",
+		},
+		{
+			name:                "stopped waiting",
+			status:              CIStatusUnknown,
+			title:               strings.Repeat("x", 200),
+			expectedState:       StatusStateError,
+			expectedDescription: strings.Repeat("x", 137) + "...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var status github.RepoStatus
+			mockedHTTPClient := mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					mock.PostReposStatusesByOwnerByRepoBySha,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						require.Equal(t, "/repos/owner/repo/statuses/abc123", r.URL.Path)
+						require.NoError(t, json.NewDecoder(r.Body).Decode(&status))
+						w.WriteHeader(http.StatusCreated)
+						_, _ = w.Write(mock.MustMarshal(status))
+					}),
+				),
+			)
+
+			ghClient := newClientFromMock(t, mockedHTTPClient, "")
+			err := ghClient.ReportGateStatus(context.Background(), "owner", "repo", "abc123", Gate{
+				Name:   "wfg/gate",
+				Status: tt.status,
+				Title:  tt.title,
+			})
+
+			require.NoError(t, err)
+			require.Equal(t, "wfg/gate", status.GetContext())
+			require.Equal(t, tt.expectedState, status.GetState())
+			require.Equal(t, tt.expectedDescription, status.GetDescription())
+		})
+	}
+}
@@ -20,19 +20,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/fatih/color"
 	"github.com/google/go-github/v89/github"
 	"github.com/grafana/wait-for-github/internal/logging"
+	"github.com/grafana/wait-for-github/internal/metrics"
+	"github.com/grafana/wait-for-github/internal/tracing"
 	"github.com/gregjones/httpcache"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/shurcooL/graphql"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/oauth2"
 )
 
@@ -53,6 +59,10 @@ type GetPRHeadSHA interface {
 	GetPRHeadSHA(ctx context.Context, owner, repo string, pr int) (string, error)
 }
 
+type GetPRBaseRef interface {
+	GetPRBaseRef(ctx context.Context, owner, repo string, pr int) (string, error)
+}
+
 type CheckOverallCIStatus interface {
 	GetCIStatus(ctx context.Context, owner, repo string, commitHash string, excludes []string) (CIStatus, error)
 }
@@ -69,10 +79,31 @@ type RerunFailedWorkflows interface {
 	RerunFailedWorkflowsForCommit(ctx context.Context, owner, repo, commitHash string) (int, bool, error)
 }
 
+type FindArtifact interface {
+	FindArtifactForCommit(ctx context.Context, owner, repo, commitHash, name string) (*Artifact, bool, error)
+	FindArtifactForRun(ctx context.Context, owner, repo string, runID int64, name string) (*Artifact, bool, error)
+}
+
+type DownloadArtifact interface {
+	DownloadArtifact(ctx context.Context, owner, repo string, artifactID int64) (io.ReadCloser, error)
+}
+
+type GetDefaultBranchFile interface {
+	GetDefaultBranchFile(ctx context.Context, owner, repo, path string) ([]byte, bool, error)
+}
+
+type ResolveRef interface {
+	ResolveRef(ctx context.Context, owner, repo, ref string) (string, error)
+}
+
 type MergePR interface {
 	MergePR(ctx context.Context, owner, repo string, pr int, sha, mergeMethod string) error
 }
 
+type UpsertPRComment interface {
+	UpsertPRComment(ctx context.Context, owner, repo string, pr int, marker, body string) error
+}
+
 type CheckCIStatus interface {
 	CheckOverallCIStatus
 	CheckCIStatusForChecks
@@ -84,13 +115,17 @@ type AuthInfo struct {
 	PrivateKey     []byte
 
 	GithubToken string
+
+	// BaseURL is the URL of the GitHub Enterprise Server or GHE.com instance
+	// to use, e.g. https://github.example.com. If empty, github.com is used.
+	BaseURL string
 }
 
 type GHClient struct {
-	logger             *slog.Logger
-	client             *github.Client
-	graphQLClient      *graphql.Client
-	pendingRecheckTime time.Duration
+	logger        *slog.Logger
+	client        *github.Client
+	graphQLClient *graphql.Client
+	runAttempts   *runAttemptCache
 }
 
 type CIStatus uint
@@ -101,6 +136,9 @@ const (
 	CIStatusPending
 	CIStatusUnknown
 	CIStatusSkipped
+	// CIStatusNoChecks means the commit has no checks or statuses at all, or
+	// the commit doesn't exist. Callers decide whether that counts as success.
+	CIStatusNoChecks
 )
 
 // Workflow run and check run status values.
@@ -141,17 +179,35 @@ const (
 func (c CIStatus) String() string {
 	switch c {
 	case CIStatusPassed:
-		return fgGreen("passed")
+		return fgGreen(c.Name())
+	case CIStatusFailed:
+		return fgRed(c.Name())
+	case CIStatusPending:
+		return fgYellow(c.Name())
+	case CIStatusSkipped, CIStatusNoChecks:
+		return fgHiBlack(c.Name())
+	default:
+		return fgWhite(c.Name())
+	}
+}
+
+// Name returns the status without any colors, for machine-readable output.
+func (c CIStatus) Name() string {
+	switch c {
+	case CIStatusPassed:
+		return "passed"
 	case CIStatusFailed:
-		return fgRed("failed")
+		return "failed"
 	case CIStatusPending:
-		return fgYellow("pending")
+		return "pending"
 	case CIStatusSkipped:
-		return fgHiBlack("skipped")
+		return "skipped"
+	case CIStatusNoChecks:
+		return "no checks"
 	case CIStatusUnknown:
 		fallthrough
 	default:
-		return fgWhite("unknown")
+		return "unknown"
 	}
 }
 
@@ -174,18 +230,25 @@ type AppInfo struct {
 	Name string
 }
 
+type WorkflowRunInfo struct {
+	DatabaseID int64 `graphql:"databaseId"`
+	Workflow   WorkflowInfo
+}
+
 type CheckSuiteInfo struct {
 	App         AppInfo
-	WorkflowRun struct {
-		Workflow WorkflowInfo
-	}
+	WorkflowRun WorkflowRunInfo
 }
 
 type CheckRun struct {
-	Name       string
-	Status     string
-	Conclusion string
-	CheckSuite CheckSuiteInfo
+	DatabaseID  int64 `graphql:"databaseId"`
+	Name        string
+	Status      string
+	Conclusion  string
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	DetailsURL  string `graphql:"detailsUrl"`
+	CheckSuite  CheckSuiteInfo
 }
 
 func (c CheckRun) String() string {
@@ -225,9 +288,20 @@ func (c CheckRun) Type() string {
 	return "Check Run"
 }
 
+// CheckRunDetails is a CheckRun with details which aren't part of the status
+// check rollup, as returned by GetDetailedCIStatus.
+type CheckRunDetails struct {
+	CheckRun
+	// RunAttempt is the attempt of the GitHub Actions workflow run the check
+	// belongs to, or 0 if it doesn't belong to one or it couldn't be found.
+	RunAttempt int
+}
+
 type StatusContext struct {
-	Context string
-	State   string
+	Context   string
+	State     string
+	CreatedAt *time.Time
+	TargetURL string `graphql:"targetUrl"`
 }
 
 func (s StatusContext) String() string {
@@ -312,11 +386,12 @@ func (c GHClient) handleResponseError(resp *github.Response, operation, owner, r
 
 		default:
 			return &GitHubAPIError{
-				Operation: operation,
-				Owner:     owner,
-				Repo:      repo,
-				Status:    resp.Response.Status,
-				Err:       apiErr,
+				Operation:  operation,
+				Owner:      owner,
+				Repo:       repo,
+				Status:     resp.Response.Status,
+				StatusCode: resp.Response.StatusCode,
+				Err:        apiErr,
 			}
 		}
 	}
@@ -336,72 +411,148 @@ func cachingRetryableTransport(logger *slog.Logger) http.RoundTripper {
 	retryableClient := retryablehttp.NewClient()
 	retryableClient.Logger = logger
 
+	// each attempt at a request gets its own span, so retries show up as the
+	// request's span repeated, and are noted on the span of the poll too
+	retryableClient.RequestLogHook = func(_ retryablehttp.Logger, req *http.Request, attempt int) {
+		if attempt > 0 {
+			tracing.AddEvent(req.Context(), "retry",
+				attribute.String("github.operation", metrics.Operation(req.Context())),
+				attribute.Int("attempt", attempt))
+		}
+	}
+
+	// requests are recorded below the cache, so that only those which reach
+	// GitHub are counted, in the metrics and against the rate limit, but they
+	// all get a span, which says whether the response came from the cache
 	httpCache := httpcache.NewMemoryCacheTransport()
-	retryableClient.HTTPClient.Transport = httpCache
+	httpCache.Transport = metrics.Transport(DefaultRateBudget.Transport(http.DefaultTransport))
+	retryableClient.HTTPClient.Transport = tracing.Transport(httpCache)
+	retryableClient.HTTPClient.CheckRedirect = checkRedirect
 
 	return &retryablehttp.RoundTripper{
 		Client: retryableClient,
 	}
 }
 
-func NewGithubClient(ctx context.Context, logger *slog.Logger, authInfo AuthInfo, pendingRecheckTime time.Duration) (GHClient, error) {
+func NewGithubClient(ctx context.Context, logger *slog.Logger, authInfo AuthInfo) (GHClient, error) {
 	// If a GitHub token is provided, use it to authenticate in preference to App authentication.
 	if authInfo.GithubToken != "" {
 		logger.InfoContext(ctx, "using github token for authentication")
-		return AuthenticateWithToken(ctx, logger, authInfo.GithubToken, pendingRecheckTime)
+		return AuthenticateWithToken(ctx, logger, authInfo.GithubToken, authInfo.BaseURL)
 	}
 
 	logger.InfoContext(ctx, "using github app for authentication")
-	return AuthenticateWithApp(ctx, logger, authInfo.PrivateKey, authInfo.AppID, authInfo.InstallationID, pendingRecheckTime)
+	return AuthenticateWithApp(ctx, logger, authInfo.PrivateKey, authInfo.AppID, authInfo.InstallationID, authInfo.BaseURL)
+}
+
+// newClients returns REST and GraphQL clients which talk to the GitHub instance
+// at baseURL, or to github.com if baseURL is empty.
+func newClients(httpClient *http.Client, baseURL string) (*github.Client, *graphql.Client, error) {
+	if baseURL == "" {
+		restClient, err := github.NewClient(github.WithHTTPClient(httpClient))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create REST client: %w", err)
+		}
+
+		return restClient, newGraphQLClient("https://api.github.com/graphql", httpClient), nil
+	}
+
+	api := apiURLs(baseURL)
+	restClient, err := github.NewClient(
+		github.WithHTTPClient(httpClient),
+		github.WithEnterpriseURLs(api.rest+"/", api.uploads+"/"),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	return restClient, newGraphQLClient(api.graphQL, httpClient), nil
+}
+
+// apiEndpoints are the URLs of a GitHub instance's APIs.
+type apiEndpoints struct {
+	rest    string
+	uploads string
+	graphQL string
+}
+
+// apiURLs returns the URLs of the APIs of the GitHub instance at baseURL.
+// GitHub Enterprise Server has them under /api on the same host, while GitHub
+// Enterprise Cloud with data residency, on a subdomain of ghe.com, has them
+// on their own hosts.
+// See: https://docs.github.com/en/enterprise-cloud@latest/admin/data-residency/network-details-for-ghecom
+func apiURLs(baseURL string) apiEndpoints {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	if u, err := url.Parse(baseURL); err == nil && strings.HasSuffix(u.Hostname(), ".ghe.com") {
+		origin := func(prefix string) string {
+			return u.Scheme + "://" + prefix + "." + u.Host
+		}
+		return apiEndpoints{
+			rest:    origin("api"),
+			uploads: origin("uploads"),
+			graphQL: origin("api") + "/graphql",
+		}
+	}
+
+	return apiEndpoints{
+		rest:    baseURL + "/api/v3",
+		uploads: baseURL + "/api/uploads",
+		graphQL: baseURL + "/api/graphql",
+	}
 }
 
-// AuthenticateWithToken authenticates with a GitHub token.
-func AuthenticateWithToken(ctx context.Context, logger *slog.Logger, token string, pendingRecheckTime time.Duration) (GHClient, error) {
+// AuthenticateWithToken authenticates with a GitHub token. baseURL is the
+// GitHub Enterprise Server instance to use, or empty for github.com.
+func AuthenticateWithToken(ctx context.Context, logger *slog.Logger, token, baseURL string) (GHClient, error) {
 	src := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
 	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: cachingRetryableTransport(logger)})
 	httpClient := oauth2.NewClient(ctx, src)
 
-	restClient, err := github.NewClient(github.WithHTTPClient(httpClient))
+	restClient, graphQLClient, err := newClients(httpClient, baseURL)
 	if err != nil {
-		return GHClient{}, fmt.Errorf("failed to create REST client: %w", err)
+		return GHClient{}, err
 	}
-	graphQLClient := graphql.NewClient("https://api.github.com/graphql", httpClient)
 
 	return GHClient{
-		logger:             logger,
-		client:             restClient,
-		graphQLClient:      graphQLClient,
-		pendingRecheckTime: pendingRecheckTime,
+		logger:        logger,
+		client:        restClient,
+		graphQLClient: graphQLClient,
+		runAttempts:   &runAttemptCache{},
 	}, nil
 }
 
-// AuthenticateWithApp authenticates with a GitHub App
-func AuthenticateWithApp(ctx context.Context, logger *slog.Logger, privateKey []byte, appID, installationID int64, pendingRecheckTime time.Duration) (GHClient, error) {
+// AuthenticateWithApp authenticates with a GitHub App. baseURL is the GitHub
+// Enterprise Server instance to use, or empty for github.com.
+func AuthenticateWithApp(ctx context.Context, logger *slog.Logger, privateKey []byte, appID, installationID int64, baseURL string) (GHClient, error) {
 	itr, err := ghinstallation.New(cachingRetryableTransport(logger), appID, installationID, privateKey)
 	if err != nil {
 		return GHClient{}, fmt.Errorf("failed to create transport: %w", err)
 	}
+	if baseURL != "" {
+		// installation tokens come from the same instance
+		itr.BaseURL = apiURLs(baseURL).rest
+	}
 
 	httpClient := &http.Client{Transport: itr}
 
-	restClient, err := github.NewClient(github.WithHTTPClient(httpClient))
+	restClient, graphQLClient, err := newClients(httpClient, baseURL)
 	if err != nil {
-		return GHClient{}, fmt.Errorf("failed to create REST client: %w", err)
+		return GHClient{}, err
 	}
-	graphQLClient := graphql.NewClient("https://api.github.com/graphql", httpClient)
 
 	return GHClient{
-		logger:             logger,
-		client:             restClient,
-		graphQLClient:      graphQLClient,
-		pendingRecheckTime: pendingRecheckTime,
+		logger:        logger,
+		client:        restClient,
+		graphQLClient: graphQLClient,
+		runAttempts:   &runAttemptCache{},
 	}, nil
 }
 
 func (c GHClient) IsPRMergedOrClosed(ctx context.Context, owner, repo string, prNumber int) (string, bool, int64, error) {
-	pr, resp, err := c.client.PullRequests.Get(ctx, owner, repo, prNumber)
+	pr, resp, err := c.client.PullRequests.Get(metrics.WithOperation(ctx, "GetPullRequest"), owner, repo, prNumber)
 	if err != nil {
 		return "", false, -1, fmt.Errorf("failed to query GitHub: %w", err)
 	}
@@ -425,7 +576,7 @@ func (c GHClient) IsPRMergedOrClosed(ctx context.Context, owner, repo string, pr
 }
 
 func (c GHClient) GetPRHeadSHA(ctx context.Context, owner, repo string, prNumber int) (string, error) {
-	pr, resp, err := c.client.PullRequests.Get(ctx, owner, repo, prNumber)
+	pr, resp, err := c.client.PullRequests.Get(metrics.WithOperation(ctx, "GetPullRequest"), owner, repo, prNumber)
 	if err != nil {
 		return "", fmt.Errorf("failed to query GitHub for PR HEAD SHA: %w", err)
 	}
@@ -438,8 +589,36 @@ func (c GHClient) GetPRHeadSHA(ctx context.Context, owner, repo string, prNumber
 	return pr.GetHead().GetSHA(), nil
 }
 
+// GetPRBaseRef returns the ref of the branch the PR is to be merged into, e.g.
+// refs/heads/main.
+func (c GHClient) GetPRBaseRef(ctx context.Context, owner, repo string, prNumber int) (string, error) {
+	pr, resp, err := c.client.PullRequests.Get(metrics.WithOperation(ctx, "GetPullRequest"), owner, repo, prNumber)
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "GetPullRequest", owner, repo); respErr != nil {
+			return "", respErr
+		}
+		return "", fmt.Errorf("failed to get base of PR %d: %w", prNumber, err)
+	}
+
+	return "refs/heads/" + pr.GetBase().GetRef(), nil
+}
+
+// ResolveRef returns the SHA of the commit ref points to. ref can be a SHA, a
+// branch or tag name, or a full ref like `refs/pull/1/head`.
+func (c GHClient) ResolveRef(ctx context.Context, owner, repo, ref string) (string, error) {
+	sha, resp, err := c.client.Repositories.GetCommitSHA1(metrics.WithOperation(ctx, "GetCommitSHA1"), owner, repo, ref, "")
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "GetCommitSHA1", owner, repo); respErr != nil {
+			return "", respErr
+		}
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+
+	return sha, nil
+}
+
 func (c GHClient) MergePR(ctx context.Context, owner, repo string, prNumber int, sha, mergeMethod string) error {
-	result, resp, err := c.client.PullRequests.Merge(ctx, owner, repo, prNumber, "", &github.PullRequestOptions{
+	result, resp, err := c.client.PullRequests.Merge(metrics.WithOperation(ctx, "MergePullRequest"), owner, repo, prNumber, "", &github.PullRequestOptions{
 		SHA:         sha,
 		MergeMethod: mergeMethod,
 	})
@@ -462,6 +641,11 @@ func (c GHClient) MergePR(ctx context.Context, owner, repo string, prNumber int,
 				)
 			}
 			c.logger.ErrorContext(ctx, "merge PR API error", attrs...)
+
+			// GitHub says the PR can't be merged, without saying why
+			if ghErr.Response.StatusCode == http.StatusMethodNotAllowed && c.hasMergeConflicts(ctx, owner, repo, prNumber) {
+				return fmt.Errorf("failed to merge PR: %w", ErrMergeConflict)
+			}
 		}
 		return fmt.Errorf("failed to merge PR: %w", err)
 	}
@@ -477,15 +661,128 @@ func (c GHClient) MergePR(ctx context.Context, owner, repo string, prNumber int,
 	return nil
 }
 
-func (c GHClient) getStatusCheckRollup(ctx context.Context, owner, repoName, ref string) (*StatusCheckRollup, []RollupContextNode, error) {
+// hasMergeConflicts returns whether the PR conflicts with its base branch. If
+// that can't be found out, it returns false.
+func (c GHClient) hasMergeConflicts(ctx context.Context, owner, repo string, prNumber int) bool {
+	pr, _, err := c.client.PullRequests.Get(metrics.WithOperation(ctx, "GetPullRequest"), owner, repo, prNumber)
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to check PR for merge conflicts", "error", err)
+		return false
+	}
+
+	return pr.GetMergeableState() == "dirty"
+}
+
+// UpsertPRComment updates the PR's comment which contains marker to body, or
+// posts body as a new comment if there isn't one. marker is usually a hidden
+// HTML comment in body. Only comments posted with the same credentials are
+// updated, so that ones quoting the marker are left alone.
+func (c GHClient) UpsertPRComment(ctx context.Context, owner, repo string, prNumber int, marker, body string) error {
+	comment := &github.IssueComment{Body: github.Ptr(body)}
+
+	id, err := c.findOwnPRComment(ctx, owner, repo, prNumber, marker)
+	if err != nil {
+		return err
+	}
+
+	if id != 0 {
+		_, resp, err := c.client.Issues.EditComment(metrics.WithOperation(ctx, "EditComment"), owner, repo, id, comment)
+		if err != nil {
+			if respErr := c.handleResponseError(resp, "EditComment", owner, repo); respErr != nil {
+				return respErr
+			}
+			return fmt.Errorf("failed to update PR comment: %w", err)
+		}
+
+		return nil
+	}
+
+	_, resp, err := c.client.Issues.CreateComment(metrics.WithOperation(ctx, "CreateComment"), owner, repo, prNumber, comment)
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "CreateComment", owner, repo); respErr != nil {
+			return respErr
+		}
+		return fmt.Errorf("failed to post PR comment: %w", err)
+	}
+
+	return nil
+}
+
+// findOwnPRComment returns the ID of the PR's comment which contains marker
+// and was posted with the client's credentials, or 0 if there isn't one. The
+// GraphQL API says which comments those are whatever the credentials are, even
+// for GitHub App installations, which can't look up who they are.
+func (c GHClient) findOwnPRComment(ctx context.Context, owner, repo string, prNumber int, marker string) (int64, error) {
+	var query struct {
+		Repository struct {
+			PullRequest struct {
+				Comments struct {
+					Nodes []struct {
+						DatabaseID      int64 `graphql:"databaseId"`
+						Body            string
+						ViewerDidAuthor bool
+					}
+					PageInfo PageInfo
+				} `graphql:"comments(first: 100, after: $cursor)"`
+			} `graphql:"pullRequest(number: $number)"`
+		} `graphql:"repository(owner: $owner, name: $repository)"`
+		// see getStatusCheckRollup
+		RateLimit struct {
+			Cost    int       `graphql:"cost"`
+			ResetAt time.Time `graphql:"resetAt"`
+		} `graphql:"rateLimit"`
+	}
+
+	vars := map[string]interface{}{
+		"owner":      graphql.String(owner),
+		"repository": graphql.String(repo),
+		"number":     graphql.Int(prNumber),
+		"cursor":     (*graphql.String)(nil),
+	}
+
+	for {
+		var resp graphQLResponse
+		if err := c.graphQLClient.Query(withGraphQLResponse(metrics.WithOperation(ctx, "ListComments"), &resp), &query, vars); err != nil {
+			if respErr := resp.responseError(err, "ListComments", owner, repo); respErr != nil {
+				return 0, respErr
+			}
+			return 0, fmt.Errorf("failed to list PR comments: %w", err)
+		}
+
+		comments := query.Repository.PullRequest.Comments
+		for _, comment := range comments.Nodes {
+			if comment.ViewerDidAuthor && strings.Contains(comment.Body, marker) {
+				return comment.DatabaseID, nil
+			}
+		}
+
+		if !comments.PageInfo.HasNextPage {
+			return 0, nil
+		}
+
+		vars["cursor"] = graphql.String(*comments.PageInfo.EndCursor)
+	}
+}
+
+// getStatusCheckRollup returns the status check rollup for a ref, and all of
+// its contexts. The rollup is nil if the commit has no checks or statuses, or
+// doesn't exist, in which case found reports whether the commit exists.
+func (c GHClient) getStatusCheckRollup(ctx context.Context, owner, repoName, ref string) (rollup *StatusCheckRollup, allNodes []RollupContextNode, found bool, err error) {
 	var query struct {
 		Repository struct {
 			Object struct {
 				Commit struct {
+					Oid               string             `graphql:"oid"`
 					StatusCheckRollup *StatusCheckRollup `graphql:"statusCheckRollup"`
 				} `graphql:"... on Commit"`
 			} `graphql:"object(expression: $ref)"`
 		} `graphql:"repository(owner: $owner, name: $repository)"`
+		// asked for so that, if the query is rate limited, the response says
+		// when the limit resets, and so that what it cost can be budgeted
+		RateLimit struct {
+			Cost    int       `graphql:"cost"`
+			ResetAt time.Time `graphql:"resetAt"`
+		} `graphql:"rateLimit"`
 	}
 
 	vars := map[string]interface{}{
@@ -495,18 +792,18 @@ func (c GHClient) getStatusCheckRollup(ctx context.Context, owner, repoName, ref
 		"cursor":     (*graphql.String)(nil),
 	}
 
-	var allNodes []RollupContextNode
-	retried := false
-
-	var rollup *StatusCheckRollup
-
 	for {
-		if err := c.graphQLClient.Query(ctx, &query, vars); err != nil {
-			return nil, nil, fmt.Errorf("failed to query GitHub: %w", err)
+		var resp graphQLResponse
+		if err := c.graphQLClient.Query(withGraphQLResponse(metrics.WithOperation(ctx, "GetStatusCheckRollup"), &resp), &query, vars); err != nil {
+			if respErr := resp.responseError(err, "GetStatusCheckRollup", owner, repoName); respErr != nil {
+				return nil, nil, false, respErr
+			}
+			return nil, nil, false, fmt.Errorf("failed to query GitHub: %w", err)
 		}
 
+		found = query.Repository.Object.Commit.Oid != ""
 		if rollup = query.Repository.Object.Commit.StatusCheckRollup; rollup == nil {
-			return nil, allNodes, nil
+			return nil, allNodes, found, nil
 		}
 
 		contexts := rollup.Contexts
@@ -515,22 +812,13 @@ func (c GHClient) getStatusCheckRollup(ctx context.Context, owner, repoName, ref
 		allNodes = append(allNodes, contexts.Nodes...)
 
 		if !pageInfo.HasNextPage {
-			if (!hasChecksOrStatuses(rollup)) && !retried {
-				c.logger.InfoContext(ctx, "did not find any checks and/or statuses. retrying", "retry_in", c.pendingRecheckTime)
-				time.Sleep(c.pendingRecheckTime)
-				retried = true
-				vars["cursor"] = (*graphql.String)(nil)
-				allNodes = nil
-				continue
-			}
-
 			break
 		}
 
 		vars["cursor"] = graphql.String(*pageInfo.EndCursor)
 	}
 
-	return rollup, allNodes, nil
+	return rollup, allNodes, found, nil
 }
 
 func hasChecksOrStatuses(rollup *StatusCheckRollup) bool {
@@ -539,13 +827,25 @@ func hasChecksOrStatuses(rollup *StatusCheckRollup) bool {
 
 func (c GHClient) GetCIStatus(ctx context.Context, owner, repoName, ref string, excludes []string) (CIStatus, error) {
 	logger := c.logger.With(logging.OwnerAttr(owner), logging.RepoAttr(repoName), logging.RefAttr(ref))
-	rollup, nodes, err := c.getStatusCheckRollup(ctx, owner, repoName, ref)
+	rollup, nodes, found, err := c.getStatusCheckRollup(ctx, owner, repoName, ref)
 	if err != nil {
 		return CIStatusUnknown, err
 	}
 
+	for _, node := range nodes {
+		if name, outcome, ok := nodeOutcome(node); ok && !slices.Contains(excludes, name) {
+			recordCheckStatus(ctx, owner, repoName, name, outcome)
+		}
+	}
+
+	if !found {
+		logger.WarnContext(ctx, "commit not found, check that the ref is correct and has been pushed")
+		return CIStatusNoChecks, nil
+	}
+
 	if rollup == nil {
-		return CIStatusPassed, nil
+		logger.DebugContext(ctx, "no checks or statuses found")
+		return CIStatusNoChecks, nil
 	}
 
 	isSuccess := strings.ToLower(rollup.State) == StatusStateSuccess
@@ -558,11 +858,14 @@ func (c GHClient) GetCIStatus(ctx context.Context, owner, repoName, ref string,
 	}
 
 	if !hasChecksOrStatuses(rollup) {
-		logger.DebugContext(ctx, "no checks or statuses found after retry, assuming success")
-		return CIStatusPassed, nil
+		logger.DebugContext(ctx, "no checks or statuses found")
+		return CIStatusNoChecks, nil
 	}
 
 	if isPending {
+		if gate, ok := gateFromContext(ctx); ok {
+			return statusIgnoringGate(nodes, gate, excludes), nil
+		}
 		return CIStatusPending, nil
 	}
 
@@ -613,6 +916,49 @@ func (c GHClient) GetCIStatus(ctx context.Context, owner, repoName, ref string,
 	return CIStatusUnknown, nil
 }
 
+// nodeOutcome returns the name and outcome of a check run or status in the
+// rollup.
+func nodeOutcome(node RollupContextNode) (string, CIStatus, bool) {
+	switch node.Typename {
+	case "CheckRun":
+		return node.CheckRun.Name, node.CheckRun.Outcome(), true
+	case "StatusContext":
+		outcome := node.StatusContext.Outcome()
+		// statuses which haven't been reported yet are expected
+		if outcome == CIStatusUnknown {
+			outcome = CIStatusPending
+		}
+		return node.StatusContext.Context, outcome, true
+	default:
+		return "", CIStatusUnknown, false
+	}
+}
+
+// statusIgnoringGate returns the overall status of the checks and statuses
+// apart from the gate, for when it's what makes the rollup pending. Like the
+// rollup, excluded checks hold the wait up while they're pending, but their
+// failures don't count.
+func statusIgnoringGate(nodes []RollupContextNode, gate string, excludes []string) CIStatus {
+	status := CIStatusPassed
+	for _, node := range nodes {
+		name, outcome, ok := nodeOutcome(node)
+		if !ok || name == gate {
+			continue
+		}
+
+		switch outcome {
+		case CIStatusPending:
+			return CIStatusPending
+		case CIStatusFailed:
+			if !slices.Contains(excludes, name) {
+				status = CIStatusFailed
+			}
+		}
+	}
+
+	return status
+}
+
 func (c GHClient) getOneStatus(ctx context.Context, owner, repoName, ref, check string) (CIStatus, error) {
 	listOptions := github.ListOptions{
 		PerPage: 100,
@@ -626,7 +972,7 @@ func (c GHClient) getOneStatus(ctx context.Context, owner, repoName, ref, check
 
 	var checkRuns []*github.CheckRun
 	for {
-		runs, resp, err := c.client.Checks.ListCheckRunsForRef(ctx, owner, repoName, ref, opt)
+		runs, resp, err := c.client.Checks.ListCheckRunsForRef(metrics.WithOperation(ctx, "ListCheckRunsForRef"), owner, repoName, ref, opt)
 		if err != nil {
 			return CIStatusUnknown, fmt.Errorf("failed to query GitHub: %w", err)
 		}
@@ -673,7 +1019,7 @@ func (c GHClient) getOneStatus(ctx context.Context, owner, repoName, ref, check
 	// didn't find the check run, so list statuses. we can't filter by status
 	// name like we can for checks, so retrieve all results the first time
 	for {
-		s, resp, err := c.client.Repositories.ListStatuses(ctx, owner, repoName, ref, &listOptions)
+		s, resp, err := c.client.Repositories.ListStatuses(metrics.WithOperation(ctx, "ListStatuses"), owner, repoName, ref, &listOptions)
 		if err != nil {
 			return CIStatusUnknown, fmt.Errorf("failed to query GitHub: %w", err)
 		}
@@ -729,6 +1075,7 @@ func (c GHClient) GetCIStatusForChecks(ctx context.Context, owner, repoName stri
 		if err != nil {
 			return CIStatusUnknown, nil, fmt.Errorf("failed to get CI status for check %s: %w", checkName, err)
 		}
+		recordCheckStatus(ctx, owner, repoName, checkName, status)
 
 		if status == CIStatusFailed {
 			return status, []string{checkName}, nil
@@ -755,7 +1102,12 @@ func (c GHClient) GetCIStatusForChecks(ctx context.Context, owner, repoName stri
 }
 
 func (c GHClient) GetDetailedCIStatus(ctx context.Context, owner, repoName, ref string) ([]CICheckStatus, error) {
-	_, nodes, err := c.getStatusCheckRollup(ctx, owner, repoName, ref)
+	_, nodes, _, err := c.getStatusCheckRollup(ctx, owner, repoName, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := c.workflowRunAttempts(ctx, owner, repoName, nodes)
 	if err != nil {
 		return nil, err
 	}
@@ -764,7 +1116,10 @@ func (c GHClient) GetDetailedCIStatus(ctx context.Context, owner, repoName, ref
 	for _, node := range nodes {
 		switch node.Typename {
 		case "CheckRun":
-			allChecks = append(allChecks, node.CheckRun)
+			allChecks = append(allChecks, CheckRunDetails{
+				CheckRun:   node.CheckRun,
+				RunAttempt: attempts[node.CheckRun.CheckSuite.WorkflowRun.DatabaseID],
+			})
 		case "StatusContext":
 			if node.StatusContext.Context != "" && node.StatusContext.State != "" {
 				allChecks = append(allChecks, node.StatusContext)
@@ -779,9 +1134,96 @@ func (c GHClient) GetDetailedCIStatus(ctx context.Context, owner, repoName, ref
 	return allChecks, nil
 }
 
-// RerunFailedWorkflowsForCommit finds all failed GitHub Actions workflow runs for a commit and re-runs them.
-// Returns the number of workflows that were re-run and whether any runs are still incomplete (e.g. in_progress, queued, waiting).
-func (c GHClient) RerunFailedWorkflowsForCommit(ctx context.Context, owner, repoName, commitHash string) (int, bool, error) {
+// workflowRunAttempts returns the attempt of each workflow run the check runs
+// belong to, keyed by the run's ID. The GraphQL API doesn't have attempts, so
+// they come from the REST API, and are remembered for as long as the run isn't
+// re-run. As they're only informational, runs which can't be fetched, e.g.
+// without the actions:read permission, are left out, unless it's because of a
+// rate limit.
+func (c GHClient) workflowRunAttempts(ctx context.Context, owner, repoName string, nodes []RollupContextNode) (map[int64]int, error) {
+	// re-running a workflow run creates new check runs, so the newest check
+	// run of each workflow run tells whether it's been re-run
+	latestCheckRuns := make(map[int64]int64)
+	for _, node := range nodes {
+		runID := node.CheckRun.CheckSuite.WorkflowRun.DatabaseID
+		if node.Typename != "CheckRun" || runID == 0 {
+			continue
+		}
+		latestCheckRuns[runID] = max(latestCheckRuns[runID], node.CheckRun.DatabaseID)
+	}
+
+	attempts := make(map[int64]int, len(latestCheckRuns))
+	for runID, latestCheckRun := range latestCheckRuns {
+		if attempt, ok := c.runAttempts.get(runID, latestCheckRun); ok {
+			attempts[runID] = attempt
+			continue
+		}
+
+		run, resp, err := c.client.Actions.GetWorkflowRunByID(metrics.WithOperation(ctx, "GetWorkflowRunByID"), owner, repoName, runID)
+		if err != nil {
+			if respErr := c.handleResponseError(resp, "GetWorkflowRunByID", owner, repoName); isRateLimitError(respErr) {
+				return nil, respErr
+			}
+
+			c.logger.WarnContext(ctx, "failed to get workflow run attempt", "run_id", runID, "error", err)
+			continue
+		}
+
+		attempts[runID] = run.GetRunAttempt()
+		c.runAttempts.set(runID, latestCheckRun, run.GetRunAttempt())
+	}
+
+	return attempts, nil
+}
+
+// runAttemptCache remembers the attempts of workflow runs, along with the
+// newest of their check runs when they were fetched. A nil cache remembers
+// nothing.
+type runAttemptCache struct {
+	mu   sync.Mutex
+	runs map[int64]runAttempt
+}
+
+type runAttempt struct {
+	attempt        int
+	latestCheckRun int64
+}
+
+// get returns the attempt of the workflow run with the ID runID, if it's known
+// and the run hasn't had check runs newer than latestCheckRun since.
+func (c *runAttemptCache) get(runID, latestCheckRun int64) (int, bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	run, ok := c.runs[runID]
+	if !ok || latestCheckRun > run.latestCheckRun {
+		return 0, false
+	}
+
+	return run.attempt, true
+}
+
+func (c *runAttemptCache) set(runID, latestCheckRun int64, attempt int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.runs == nil {
+		c.runs = make(map[int64]runAttempt)
+	}
+	c.runs[runID] = runAttempt{attempt: attempt, latestCheckRun: latestCheckRun}
+}
+
+// listWorkflowRunsForCommit returns all GitHub Actions workflow runs for a
+// commit, with any duplicates returned across pages removed.
+func (c GHClient) listWorkflowRunsForCommit(ctx context.Context, owner, repoName, commitHash string) ([]*github.WorkflowRun, error) {
 	listOptions := github.ListOptions{
 		PerPage: 100,
 	}
@@ -791,19 +1233,18 @@ func (c GHClient) RerunFailedWorkflowsForCommit(ctx context.Context, owner, repo
 		ListOptions: listOptions,
 	}
 
-	var failedRunIDs []int64
+	var allRuns []*github.WorkflowRun
 	seenRuns := make(map[int64]bool)
-	hasIncompleteRuns := false
 
 	for {
-		runs, resp, err := c.client.Actions.ListRepositoryWorkflowRuns(ctx, owner, repoName, opts)
+		runs, resp, err := c.client.Actions.ListRepositoryWorkflowRuns(metrics.WithOperation(ctx, "ListRepositoryWorkflowRuns"), owner, repoName, opts)
 		if err != nil {
-			return 0, false, fmt.Errorf("failed to list workflow runs: %w", err)
+			return nil, fmt.Errorf("failed to list workflow runs: %w", err)
 		}
 
 		respErr := c.handleResponseError(resp, "ListRepositoryWorkflowRuns", owner, repoName)
 		if respErr != nil {
-			return 0, false, respErr
+			return nil, respErr
 		}
 
 		for _, run := range runs.WorkflowRuns {
@@ -811,17 +1252,7 @@ func (c GHClient) RerunFailedWorkflowsForCommit(ctx context.Context, owner, repo
 				continue
 			}
 			seenRuns[run.GetID()] = true
-
-			status := strings.ToLower(run.GetStatus())
-			if status != RunStatusCompleted {
-				hasIncompleteRuns = true
-			}
-
-			conclusion := strings.ToLower(run.GetConclusion())
-			retryableConclusions := []string{RunConclusionFailure, RunConclusionTimedOut}
-			if slices.Contains(retryableConclusions, conclusion) {
-				failedRunIDs = append(failedRunIDs, run.GetID())
-			}
+			allRuns = append(allRuns, run)
 		}
 
 		if resp.NextPage == 0 {
@@ -830,10 +1261,37 @@ func (c GHClient) RerunFailedWorkflowsForCommit(ctx context.Context, owner, repo
 		opts.Page = resp.NextPage
 	}
 
+	return allRuns, nil
+}
+
+// RerunFailedWorkflowsForCommit finds all failed GitHub Actions workflow runs for a commit and re-runs them.
+// Returns the number of workflows that were re-run and whether any runs are still incomplete (e.g. in_progress, queued, waiting).
+func (c GHClient) RerunFailedWorkflowsForCommit(ctx context.Context, owner, repoName, commitHash string) (int, bool, error) {
+	runs, err := c.listWorkflowRunsForCommit(ctx, owner, repoName, commitHash)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var failedRunIDs []int64
+	hasIncompleteRuns := false
+
+	for _, run := range runs {
+		status := strings.ToLower(run.GetStatus())
+		if status != RunStatusCompleted {
+			hasIncompleteRuns = true
+		}
+
+		conclusion := strings.ToLower(run.GetConclusion())
+		retryableConclusions := []string{RunConclusionFailure, RunConclusionTimedOut}
+		if slices.Contains(retryableConclusions, conclusion) {
+			failedRunIDs = append(failedRunIDs, run.GetID())
+		}
+	}
+
 	rerunCount := 0
 	for _, runID := range failedRunIDs {
 		c.logger.InfoContext(ctx, "re-running failed workflow", "run_id", runID)
-		resp, err := c.client.Actions.RerunFailedJobsByID(ctx, owner, repoName, runID)
+		resp, err := c.client.Actions.RerunFailedJobsByID(metrics.WithOperation(ctx, "RerunFailedJobsByID"), owner, repoName, runID)
 		if err != nil {
 			return rerunCount, hasIncompleteRuns, fmt.Errorf("failed to rerun workflow %d: %w", runID, err)
 		}
@@ -847,3 +1305,159 @@ func (c GHClient) RerunFailedWorkflowsForCommit(ctx context.Context, owner, repo
 
 	return rerunCount, hasIncompleteRuns, nil
 }
+
+// Artifact is a GitHub Actions artifact uploaded by a workflow run.
+type Artifact struct {
+	ID          int64
+	Name        string
+	RunID       int64
+	SizeInBytes int64
+	Expired     bool
+}
+
+// findRunArtifact returns the newest artifact called name which was uploaded
+// by the given workflow run, or nil if there isn't one.
+func (c GHClient) findRunArtifact(ctx context.Context, owner, repoName string, runID int64, name string) (*Artifact, error) {
+	opts := &github.ListOptions{
+		PerPage: 100,
+	}
+
+	var found *Artifact
+	for {
+		artifacts, resp, err := c.client.Actions.ListWorkflowRunArtifacts(metrics.WithOperation(ctx, "ListWorkflowRunArtifacts"), owner, repoName, runID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts for workflow run %d: %w", runID, err)
+		}
+
+		respErr := c.handleResponseError(resp, "ListWorkflowRunArtifacts", owner, repoName)
+		if respErr != nil {
+			return nil, respErr
+		}
+
+		for _, artifact := range artifacts.Artifacts {
+			if artifact.GetName() != name {
+				continue
+			}
+
+			if found != nil && found.ID > artifact.GetID() {
+				continue
+			}
+
+			found = &Artifact{
+				ID:          artifact.GetID(),
+				Name:        artifact.GetName(),
+				RunID:       runID,
+				SizeInBytes: artifact.GetSizeInBytes(),
+				Expired:     artifact.GetExpired(),
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return found, nil
+}
+
+// FindArtifactForCommit looks for an artifact called name uploaded by any
+// workflow run for a commit. Returns the artifact, or nil if it hasn't been
+// found, and whether it might still appear: either no workflow runs have
+// started for the commit yet, or some are still incomplete.
+func (c GHClient) FindArtifactForCommit(ctx context.Context, owner, repoName, commitHash, name string) (*Artifact, bool, error) {
+	runs, err := c.listWorkflowRunsForCommit(ctx, owner, repoName, commitHash)
+	if err != nil {
+		return nil, false, err
+	}
+
+	pending := len(runs) == 0
+
+	var found *Artifact
+	for _, run := range runs {
+		if strings.ToLower(run.GetStatus()) != RunStatusCompleted {
+			pending = true
+		}
+
+		artifact, err := c.findRunArtifact(ctx, owner, repoName, run.GetID(), name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if artifact != nil && (found == nil || artifact.ID > found.ID) {
+			found = artifact
+		}
+	}
+
+	return found, pending, nil
+}
+
+// FindArtifactForRun looks for an artifact called name uploaded by a specific
+// workflow run. Returns the artifact, or nil if it hasn't been found, and
+// whether the run is still incomplete.
+func (c GHClient) FindArtifactForRun(ctx context.Context, owner, repoName string, runID int64, name string) (*Artifact, bool, error) {
+	run, resp, err := c.client.Actions.GetWorkflowRunByID(metrics.WithOperation(ctx, "GetWorkflowRunByID"), owner, repoName, runID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get workflow run %d: %w", runID, err)
+	}
+
+	respErr := c.handleResponseError(resp, "GetWorkflowRunByID", owner, repoName)
+	if respErr != nil {
+		return nil, false, respErr
+	}
+
+	artifact, err := c.findRunArtifact(ctx, owner, repoName, runID, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return artifact, strings.ToLower(run.GetStatus()) != RunStatusCompleted, nil
+}
+
+// DownloadArtifact returns the zip archive of an artifact. The caller must
+// close the returned reader.
+func (c GHClient) DownloadArtifact(ctx context.Context, owner, repoName string, artifactID int64) (io.ReadCloser, error) {
+	// GitHub redirects to a pre-signed URL for the archive, which is fetched
+	// without the credentials for GitHub
+	u, resp, err := c.client.Actions.DownloadArtifact(withoutRedirects(metrics.WithOperation(ctx, "DownloadArtifact")), owner, repoName, artifactID, 0)
+	if err != nil {
+		if respErr := c.handleResponseError(resp, "DownloadArtifact", owner, repoName); respErr != nil {
+			return nil, respErr
+		}
+		return nil, fmt.Errorf("failed to download artifact %d: %w", artifactID, err)
+	}
+
+	archive, err := download(ctx, "DownloadArtifactBlob", u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact %d: %w", artifactID, err)
+	}
+
+	return archive, nil
+}
+
+// GetDefaultBranchFile returns the contents of the file at path on the
+// repository's default branch. It returns false if there's no such file.
+func (c GHClient) GetDefaultBranchFile(ctx context.Context, owner, repoName, path string) ([]byte, bool, error) {
+	file, _, resp, err := c.client.Repositories.GetContents(metrics.WithOperation(ctx, "GetContents"), owner, repoName, path, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, false, nil
+		}
+
+		if respErr := c.handleResponseError(resp, "GetContents", owner, repoName); respErr != nil {
+			return nil, false, respErr
+		}
+		return nil, false, fmt.Errorf("failed to get %s: %w", path, err)
+	}
+
+	if file == nil {
+		return nil, false, fmt.Errorf("%s is a directory", path)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return []byte(content), true, nil
+}
@@ -0,0 +1,201 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/graphql"
+)
+
+// secondaryRateLimitWait is how long to wait after hitting a secondary rate
+// limit when GitHub doesn't say, as it suggests.
+// See: https://docs.github.com/en/graphql/overview/rate-limits-and-query-limits-for-the-graphql-api#exceeding-the-rate-limit
+const secondaryRateLimitWait = time.Minute
+
+// graphQLResponse is the response to a GraphQL query. The GraphQL client only
+// returns the messages of errors, so the response is kept to tell what they
+// were.
+type graphQLResponse struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+}
+
+type graphQLResponseKey struct{}
+
+// withGraphQLResponse returns a context which makes the GraphQL client keep
+// the response to its query in resp.
+func withGraphQLResponse(ctx context.Context, resp *graphQLResponse) context.Context {
+	return context.WithValue(ctx, graphQLResponseKey{}, resp)
+}
+
+type graphQLTransport struct {
+	next http.RoundTripper
+}
+
+func (t graphQLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	kept, ok := req.Context().Value(graphQLResponseKey{}).(*graphQLResponse)
+	if !ok {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	*kept = graphQLResponse{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+
+	return resp, nil
+}
+
+// newGraphQLClient returns a GraphQL client for the API at url, which makes
+// requests with httpClient.
+func newGraphQLClient(url string, httpClient *http.Client) *graphql.Client {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	client := *httpClient
+	client.Transport = graphQLTransport{next: next}
+
+	return graphql.NewClient(url, &client)
+}
+
+// responseError returns the error from errors.go which says why the query failed
+// with err, or nil if it isn't one of them or there's no response.
+func (r graphQLResponse) responseError(err error, operation, owner, repo string) error {
+	if r.StatusCode == 0 {
+		return nil
+	}
+
+	var body struct {
+		Data struct {
+			RateLimit struct {
+				ResetAt time.Time `json:"resetAt"`
+			} `json:"rateLimit"`
+		} `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		// errors with a status other than 200 are like the REST API's
+		Message string `json:"message"`
+	}
+	// the body isn't always JSON, e.g. from a proxy
+	_ = json.Unmarshal(r.Body, &body)
+
+	messages := []string{body.Message}
+	rateLimited := false
+	for _, e := range body.Errors {
+		messages = append(messages, e.Message)
+		rateLimited = rateLimited || e.Type == "RATE_LIMITED"
+	}
+	message := strings.TrimSpace(strings.Join(messages, " "))
+
+	limitStatus := r.StatusCode == http.StatusForbidden || r.StatusCode == http.StatusTooManyRequests
+	retryAfter, hasRetryAfter := r.retryAfter()
+
+	switch {
+	case strings.Contains(strings.ToLower(message), "secondary rate limit") || (limitStatus && hasRetryAfter):
+		if !hasRetryAfter {
+			retryAfter = secondaryRateLimitWait
+		}
+		return &GitHubAbuseRateLimitError{
+			Operation:  operation,
+			Owner:      owner,
+			Repo:       repo,
+			RetryAfter: retryAfter,
+			Err:        err,
+		}
+
+	case rateLimited || (limitStatus && r.Header.Get("X-RateLimit-Remaining") == "0"):
+		resetTime := body.Data.RateLimit.ResetAt
+		if resetTime.IsZero() {
+			if reset, err := strconv.ParseInt(r.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				resetTime = time.Unix(reset, 0)
+			}
+		}
+		remaining, _ := strconv.Atoi(r.Header.Get("X-RateLimit-Remaining"))
+
+		return &GitHubRateLimitError{
+			Operation: operation,
+			Owner:     owner,
+			Repo:      repo,
+			ResetTime: resetTime,
+			Remaining: remaining,
+			Err:       err,
+		}
+
+	case strings.Contains(message, "Something went wrong") || r.StatusCode >= http.StatusInternalServerError:
+		if message == "" {
+			message = r.Status
+		}
+		return &GitHubTransientError{
+			Operation: operation,
+			Owner:     owner,
+			Repo:      repo,
+			Message:   message,
+			Err:       err,
+		}
+
+	case r.StatusCode != http.StatusOK:
+		return &GitHubAPIError{
+			Operation:  operation,
+			Owner:      owner,
+			Repo:       repo,
+			Status:     r.Status,
+			StatusCode: r.StatusCode,
+			Err:        err,
+		}
+	}
+
+	return nil
+}
+
+// retryAfter returns how long the Retry-After header says to wait, if it's
+// there.
+func (r graphQLResponse) retryAfter() (time.Duration, bool) {
+	seconds, err := strconv.Atoi(r.Header.Get("Retry-After"))
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
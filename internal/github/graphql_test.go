@@ -0,0 +1,160 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLErrors(t *testing.T) {
+	t.Parallel()
+
+	reset := time.Now().Add(20 * time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		body    string
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name:    "rate limited",
+			status:  http.StatusOK,
+			headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)},
+			body:    `{"data": null, "errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded for installation ID 123."}]}`,
+			check: func(t *testing.T, err error) {
+				var rle *GitHubRateLimitError
+				require.ErrorAs(t, err, &rle)
+				require.Equal(t, "GetStatusCheckRollup", rle.Operation)
+				require.Equal(t, "owner", rle.Owner)
+				require.Equal(t, "repo", rle.Repo)
+				require.True(t, reset.Equal(rle.ResetTime))
+			},
+		},
+		{
+			name:   "rate limited with reset in the body",
+			status: http.StatusOK,
+			body:   `{"data": {"rateLimit": {"resetAt": "` + reset.UTC().Format(time.RFC3339) + `"}}, "errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`,
+			check: func(t *testing.T, err error) {
+				var rle *GitHubRateLimitError
+				require.ErrorAs(t, err, &rle)
+				require.True(t, reset.Equal(rle.ResetTime))
+			},
+		},
+		{
+			name:    "primary rate limit status",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)},
+			body:    `{"message": "API rate limit exceeded"}`,
+			check: func(t *testing.T, err error) {
+				var rle *GitHubRateLimitError
+				require.ErrorAs(t, err, &rle)
+				require.True(t, reset.Equal(rle.ResetTime))
+				require.False(t, IsAuthError(err))
+			},
+		},
+		{
+			name:    "secondary rate limit",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"Retry-After": "90"},
+			body:    `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`,
+			check: func(t *testing.T, err error) {
+				var arle *GitHubAbuseRateLimitError
+				require.ErrorAs(t, err, &arle)
+				require.Equal(t, 90*time.Second, arle.RetryAfter)
+			},
+		},
+		{
+			name:   "secondary rate limit without retry-after",
+			status: http.StatusOK,
+			body:   `{"errors": [{"message": "You have exceeded a secondary rate limit."}]}`,
+			check: func(t *testing.T, err error) {
+				var arle *GitHubAbuseRateLimitError
+				require.ErrorAs(t, err, &arle)
+				require.Equal(t, secondaryRateLimitWait, arle.RetryAfter)
+			},
+		},
+		{
+			name:   "something went wrong",
+			status: http.StatusOK,
+			body:   `{"data": null, "errors": [{"message": "Something went wrong while executing your query. This may be the result of a timeout."}]}`,
+			check: func(t *testing.T, err error) {
+				var te *GitHubTransientError
+				require.ErrorAs(t, err, &te)
+				require.Contains(t, te.Message, "Something went wrong")
+			},
+		},
+		{
+			name:   "bad credentials",
+			status: http.StatusUnauthorized,
+			body:   `{"message": "Bad credentials"}`,
+			check: func(t *testing.T, err error) {
+				var apiErr *GitHubAPIError
+				require.ErrorAs(t, err, &apiErr)
+				require.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+				require.True(t, IsAuthError(err))
+			},
+		},
+		{
+			name:   "other query error",
+			status: http.StatusOK,
+			body:   `{"data": null, "errors": [{"type": "NOT_FOUND", "message": "Could not resolve to a Repository with the name 'owner/repo'."}]}`,
+			check: func(t *testing.T, err error) {
+				require.ErrorContains(t, err, "failed to query GitHub: Could not resolve to a Repository")
+
+				var rle *GitHubRateLimitError
+				var arle *GitHubAbuseRateLimitError
+				var te *GitHubTransientError
+				require.False(t, errors.As(err, &rle) || errors.As(err, &arle) || errors.As(err, &te))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.headers {
+					w.Header().Set(k, v)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer mockServer.Close()
+
+			ghClient := GHClient{
+				graphQLClient: newGraphQLClient(mockServer.URL+"/graphql", http.DefaultClient),
+				logger:        testLogger,
+			}
+
+			_, err := ghClient.GetCIStatus(context.Background(), "owner", "repo", "abcdef12345", nil)
+			require.Error(t, err)
+			tt.check(t, err)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package github
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/grafana/wait-for-github/internal/metrics"
+)
+
+// CheckStates records the status of each check the client sees, so that
+// callers can tell when any of them change.
+type CheckStates struct {
+	mu       sync.Mutex
+	statuses map[string]CIStatus
+}
+
+// Statuses returns the statuses recorded, by repository and check name.
+func (s *CheckStates) Statuses() map[string]CIStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.statuses)
+}
+
+func (s *CheckStates) record(key string, status CIStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.statuses == nil {
+		s.statuses = make(map[string]CIStatus)
+	}
+	s.statuses[key] = status
+}
+
+type checkStatesKey struct{}
+
+// WithCheckStates returns a context which makes the client record the status
+// of the checks it sees into states, as well as into any CheckStates ctx
+// already had.
+func WithCheckStates(ctx context.Context, states *CheckStates) context.Context {
+	outer, _ := ctx.Value(checkStatesKey{}).([]*CheckStates)
+	return context.WithValue(ctx, checkStatesKey{}, append(slices.Clip(outer), states))
+}
+
+// recordCheckStatus records the status of a check in owner/repo, in the
+// metrics and in the context's CheckStates if there are any.
+func recordCheckStatus(ctx context.Context, owner, repo, check string, status CIStatus) {
+	metrics.SetCheckStatus(owner+"/"+repo, check, status.Name())
+
+	all, _ := ctx.Value(checkStatesKey{}).([]*CheckStates)
+	for _, states := range all {
+		states.record(owner+"/"+repo+": "+check, status)
+	}
+}
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 
@@ -31,34 +32,101 @@ func (l *Level) Get() any {
 	return l
 }
 
-func SetupLogger(defaultLevel slog.Level) *slog.Logger {
+// Log formats.
+const (
+	// FormatAuto picks a format for where the logs are going: coloured text
+	// on a terminal, GitHub Actions commands under Actions, and logfmt
+	// otherwise. Files get JSON.
+	FormatAuto    = "auto"
+	FormatText    = "text"
+	FormatJSON    = "json"
+	FormatLogfmt  = "logfmt"
+	FormatActions = "actions"
+)
+
+func Formats() []string {
+	return []string{FormatAuto, FormatText, FormatJSON, FormatLogfmt, FormatActions}
+}
+
+// Options say how to log.
+type Options struct {
+	Level slog.Level
+	// Format is the format of the logs written to File, or to stderr if
+	// there's no File.
+	Format string
+	// File, if set, is written the logs as well as stderr, which then gets
+	// them in FormatAuto.
+	File io.Writer
+}
+
+func SetupLogger(opts Options) *slog.Logger {
 	var lv slog.LevelVar
-	lv.Set(defaultLevel)
+	lv.Set(opts.Level)
+
+	if isActions() && os.Getenv("RUNNER_DEBUG") == "1" {
+		lv.Set(slog.LevelDebug)
+	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: &lv,
-	}))
+	if opts.File == nil {
+		return slog.New(redactHandler{next: newHandler(os.Stderr, opts.Format, &lv)})
+	}
+
+	format := opts.Format
+	if format == FormatAuto || format == "" {
+		format = FormatJSON
+	}
+
+	return slog.New(redactHandler{next: slog.NewMultiHandler(
+		newHandler(os.Stderr, FormatAuto, &lv),
+		newHandler(opts.File, format, &lv),
+	)})
+}
+
+func isActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
 
-	if os.Getenv("GITHUB_ACTIONS") == "true" {
-		logger = slog.New(&actionslog.Wrapper{
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// newHandler returns a handler which writes logs to w in format.
+func newHandler(w io.Writer, format string, level slog.Leveler) slog.Handler {
+	if format == FormatAuto || format == "" {
+		switch {
+		case isTerminal(w):
+			format = FormatText
+		case isActions():
+			format = FormatActions
+		default:
+			format = FormatLogfmt
+		}
+	}
+
+	switch format {
+	case FormatText:
+		return tint.NewHandler(w, &tint.Options{
+			AddSource: true,
+			Level:     level,
+			NoColor:   !isTerminal(w),
+		})
+	case FormatJSON:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: level,
+		})
+	case FormatActions:
+		return &actionslog.Wrapper{
 			Handler: (&human.Handler{
 				AddSource:   true,
 				ExcludeTime: true,
-				Level:       &lv,
+				Level:       level,
 			}).WithOutput,
-			Output: os.Stderr,
-		})
-		if os.Getenv("RUNNER_DEBUG") == "1" {
-			lv.Set(slog.LevelDebug)
+			Output: w,
 		}
+	default:
+		return slog.NewTextHandler(w, &slog.HandlerOptions{
+			Level: level,
+		})
 	}
-
-	if term.IsTerminal(int(os.Stderr.Fd())) {
-		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{
-			AddSource: true,
-			Level:     &lv,
-		}))
-	}
-
-	return logger
 }
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHandler(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatJSON, `"check_run":"build"`},
+		{FormatLogfmt, "check_run=build"},
+		{FormatText, "check_run=build"},
+		// not a terminal, and not under Actions
+		{FormatAuto, "level=INFO msg=waiting owner=grafana check_run=build"},
+		{FormatActions, "::notice ::waiting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(newHandler(&buf, tt.format, slog.LevelInfo))
+
+			logger.With(OwnerAttr("grafana")).Info("waiting", CheckRunAttr("build"))
+			logger.Debug("not logged")
+
+			require.Contains(t, buf.String(), tt.want)
+			require.NotContains(t, buf.String(), "not logged")
+		})
+	}
+}
+
+func TestSetupLoggerFile(t *testing.T) {
+	var file bytes.Buffer
+	logger := SetupLogger(Options{Level: slog.LevelDebug, File: &file})
+
+	logger.Debug("checking", RepoAttr("wait-for-github"), RefAttr("main"))
+
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, "DEBUG", record["level"])
+	require.Equal(t, "checking", record["msg"])
+	require.Equal(t, "wait-for-github", record["repo"])
+	require.Equal(t, "main", record["ref"])
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted is what secrets are replaced with.
+const Redacted = "[REDACTED]"
+
+// minSecretLength is the length below which secrets aren't redacted by value,
+// so that short values don't mangle unrelated text.
+const minSecretLength = 8
+
+var secretPatterns = []*regexp.Regexp{
+	// GitHub tokens: personal, OAuth, user-to-server, installation and
+	// refresh tokens, and fine-grained personal tokens
+	regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})`),
+	// PEM private keys, including cut off ones
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----(?s:.*?-----END [A-Z ]*PRIVATE KEY-----|.*)`),
+	// JWTs, such as the ones authenticating as a GitHub App
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+}
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// AddSecret makes loggers redact secret wherever it appears, in addition to
+// anything which looks like a token, private key or JWT.
+func AddSecret(secret string) {
+	secret = strings.TrimSpace(secret)
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secrets = append(secrets, secret)
+}
+
+// Redact returns s with any secrets in it replaced with Redacted.
+func Redact(s string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	secretsMu.RUnlock()
+
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, Redacted)
+	}
+
+	return s
+}
+
+// redactHandler redacts secrets from the messages and attributes of records
+// before passing them on. This includes what the HTTP client logs about
+// requests.
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+
+	return redactHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		// errors and the like are only turned into strings if they have
+		// something to redact, to keep them structured otherwise
+		s := v.String()
+		if r := Redact(s); r != s {
+			return slog.String(a.Key, r)
+		}
+	}
+
+	return slog.Attr{Key: a.Key, Value: v}
+}
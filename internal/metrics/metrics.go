@@ -0,0 +1,189 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package metrics records what wait-for-github is doing as Prometheus metrics,
+// and serves them for long waits to be watched.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are always recorded, as that's cheap, but only served if asked
+// to.
+var (
+	registry = prometheus.NewRegistry()
+	factory  = promauto.With(registry)
+
+	polls = factory.NewCounter(prometheus.CounterOpts{
+		Name: "wait_for_github_polls_total",
+		Help: "Number of times what's being waited for has been checked.",
+	})
+	apiRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "wait_for_github_api_requests_total",
+		Help: "Number of requests made to the GitHub API, by operation and response status code.",
+	}, []string{"operation", "code"})
+	apiRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wait_for_github_api_request_duration_seconds",
+		Help:    "How long requests to the GitHub API took, by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+	rateLimitRemaining = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wait_for_github_rate_limit_remaining",
+		Help: "Requests left in the current GitHub rate limit window, by rate limit resource.",
+	}, []string{"resource"})
+	rateLimitWaits = factory.NewCounter(prometheus.CounterOpts{
+		Name: "wait_for_github_rate_limit_waits_total",
+		Help: "Number of times checking was held off because of GitHub rate limits.",
+	})
+	workflowReruns = factory.NewCounter(prometheus.CounterOpts{
+		Name: "wait_for_github_workflow_reruns_total",
+		Help: "Number of failed GitHub Actions workflow runs rerun.",
+	})
+	checkStatus = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wait_for_github_check_status",
+		Help: "The status of each check being waited for, as 1 for its current status.",
+	}, []string{"repo", "check", "status"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Poll records that what's being waited for was checked.
+func Poll() {
+	polls.Inc()
+}
+
+// RateLimitWait records that checking was held off because of a rate limit.
+func RateLimitWait() {
+	rateLimitWaits.Inc()
+}
+
+// WorkflowsRerun records that n workflow runs were rerun.
+func WorkflowsRerun(n int) {
+	workflowReruns.Add(float64(n))
+}
+
+// SetCheckStatus records the status of a check in the repository owner/repo.
+func SetCheckStatus(repo, check, status string) {
+	checkStatus.DeletePartialMatch(prometheus.Labels{"repo": repo, "check": check})
+	checkStatus.WithLabelValues(repo, check, status).Set(1)
+}
+
+type operationKey struct{}
+
+// unknownOperation is the operation of requests which weren't given one, like
+// those authenticating a GitHub App.
+const unknownOperation = "unknown"
+
+// WithOperation returns a context for requests to the GitHub API which are
+// recorded as operation.
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// Operation returns the operation requests made with ctx are recorded as.
+func Operation(ctx context.Context) string {
+	if op, ok := ctx.Value(operationKey{}).(string); ok {
+		return op
+	}
+	return unknownOperation
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// Transport returns a transport which records the requests made through
+// next, and the rate limit GitHub says is left.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return transport{next: next}
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	op := Operation(req.Context())
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	apiRequestDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		apiRequests.WithLabelValues(op, "error").Inc()
+		return resp, err
+	}
+	apiRequests.WithLabelValues(op, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		resource := resp.Header.Get("X-RateLimit-Resource")
+		if resource == "" {
+			resource = "core"
+		}
+		rateLimitRemaining.WithLabelValues(resource).Set(float64(remaining))
+	}
+
+	return resp, nil
+}
+
+// Handler returns the handler which serves the metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve serves the metrics on addr at /metrics until ctx is done. It returns
+// once it's listening, so that a bad address is found out straight away.
+func Serve(ctx context.Context, logger *slog.Logger, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics requests: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.ErrorContext(ctx, "failed to serve metrics", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	logger.InfoContext(ctx, "serving metrics", "addr", listener.Addr().String())
+	return nil
+}
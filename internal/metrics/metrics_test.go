@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		w.Header().Set("X-RateLimit-Resource", "graphql")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+
+	get := func(ctx context.Context, path string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	get(WithOperation(context.Background(), "TestGet"), "/")
+	get(WithOperation(context.Background(), "TestGet"), "/")
+	get(WithOperation(context.Background(), "TestGet"), "/missing")
+	get(context.Background(), "/")
+
+	require.Equal(t, 2.0, testutil.ToFloat64(apiRequests.WithLabelValues("TestGet", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(apiRequests.WithLabelValues("TestGet", "404")))
+	require.Equal(t, 1.0, testutil.ToFloat64(apiRequests.WithLabelValues(unknownOperation, "200")))
+	require.Equal(t, 4321.0, testutil.ToFloat64(rateLimitRemaining.WithLabelValues("graphql")))
+	require.Equal(t, 2, testutil.CollectAndCount(apiRequestDuration, "wait_for_github_api_request_duration_seconds"))
+}
+
+func TestSetCheckStatus(t *testing.T) {
+	SetCheckStatus("owner/repo", "build", "pending")
+	SetCheckStatus("owner/repo", "build", "passed")
+	SetCheckStatus("owner/repo", "lint", "failed")
+
+	expected := `
+# HELP wait_for_github_check_status The status of each check being waited for, as 1 for its current status.
+# TYPE wait_for_github_check_status gauge
+wait_for_github_check_status{check="build",repo="owner/repo",status="passed"} 1
+wait_for_github_check_status{check="lint",repo="owner/repo",status="failed"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(checkStatus, strings.NewReader(expected)))
+}
+
+func TestHandler(t *testing.T) {
+	Poll()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "wait_for_github_polls_total")
+	require.Contains(t, rec.Body.String(), "go_goroutines")
+}
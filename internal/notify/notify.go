@@ -0,0 +1,231 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package notify sends notifications when a wait ends, to a generic webhook or
+// to Slack.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Outcome is how a wait ended.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeTimeout Outcome = "timeout"
+)
+
+// Outcomes returns the outcomes which can be notified about.
+func Outcomes() []string {
+	return []string{string(OutcomeSuccess), string(OutcomeFailure), string(OutcomeTimeout)}
+}
+
+// Event is a wait which has ended.
+type Event struct {
+	// Command is the command which was run, e.g. "pr".
+	Command string
+	// Target is what was waited for, e.g. "grafana/wait-for-github#1".
+	Target  string
+	Outcome Outcome
+	// Message is what the command exited with, e.g. "CI failed".
+	Message      string
+	FailedChecks []string
+	Duration     time.Duration
+}
+
+// Sink is somewhere notifications are sent to.
+type Sink interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Sink kinds, as given to ParseSink.
+const (
+	KindWebhook = "webhook"
+	KindSlack   = "slack"
+)
+
+// Kinds returns the kinds of sinks ParseSink accepts.
+func Kinds() []string {
+	return []string{KindWebhook, KindSlack}
+}
+
+// ParseSink returns the sink described by spec, which is a kind and a URL,
+// like "slack=https://hooks.slack.com/services/...".
+func ParseSink(spec string, client *http.Client) (Sink, error) {
+	// the URL is left out of errors, as webhook URLs have secrets in them
+	kind, endpoint, ok := strings.Cut(spec, "=")
+	if !ok || endpoint == "" {
+		return nil, fmt.Errorf("invalid notification: must be KIND=URL, where KIND is one of %s", strings.Join(Kinds(), ", "))
+	}
+
+	if !slices.Contains(Kinds(), kind) {
+		return nil, fmt.Errorf("invalid notification kind %q: must be one of %s", kind, strings.Join(Kinds(), ", "))
+	}
+
+	if !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		return nil, fmt.Errorf("invalid %s notification URL: must be http or https", kind)
+	}
+
+	if kind == KindSlack {
+		return Slack{URL: endpoint, Client: client}, nil
+	}
+	return Webhook{URL: endpoint, Client: client}, nil
+}
+
+// Webhook posts events as JSON to a URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+type webhookPayload struct {
+	Command         string   `json:"command"`
+	Target          string   `json:"target"`
+	Outcome         Outcome  `json:"outcome"`
+	Message         string   `json:"message"`
+	FailedChecks    []string `json:"failed_checks"`
+	DurationSeconds float64  `json:"duration_seconds"`
+}
+
+func (w Webhook) Notify(ctx context.Context, event Event) error {
+	failedChecks := event.FailedChecks
+	if failedChecks == nil {
+		failedChecks = []string{}
+	}
+
+	return post(ctx, w.Client, w.URL, webhookPayload{
+		Command:         event.Command,
+		Target:          event.Target,
+		Outcome:         event.Outcome,
+		Message:         event.Message,
+		FailedChecks:    failedChecks,
+		DurationSeconds: event.Duration.Round(time.Second).Seconds(),
+	})
+}
+
+// Slack posts events to a Slack incoming webhook, as Block Kit messages.
+// See: https://api.slack.com/messaging/webhooks
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackMessage struct {
+	// Text is shown in notifications, where blocks aren't
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (s Slack) Notify(ctx context.Context, event Event) error {
+	return post(ctx, s.Client, s.URL, slackMessageFor(event))
+}
+
+func slackMessageFor(event Event) slackMessage {
+	emoji := map[Outcome]string{
+		OutcomeSuccess: ":white_check_mark:",
+		OutcomeFailure: ":x:",
+		OutcomeTimeout: ":hourglass:",
+	}[event.Outcome]
+
+	title := fmt.Sprintf("wait-for-github %s %s: %s", event.Command, event.Target, event.Outcome)
+	blocks := []slackBlock{{
+		Type: "section",
+		Text: &slackText{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("%s `wait-for-github %s` *%s*: %s", emoji, event.Command, slackEscaper.Replace(event.Target), event.Outcome),
+		},
+	}}
+
+	if len(event.FailedChecks) > 0 {
+		checks := make([]string, 0, len(event.FailedChecks))
+		for _, check := range event.FailedChecks {
+			checks = append(checks, "• "+slackEscaper.Replace(check))
+		}
+
+		blocks = append(blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: "*Failed checks*\n" + strings.Join(checks, "\n")},
+		})
+	}
+
+	footer := fmt.Sprintf("Took %s", event.Duration.Round(time.Second))
+	if event.Message != "" {
+		footer = slackEscaper.Replace(event.Message) + " · " + footer
+	}
+	blocks = append(blocks, slackBlock{
+		Type:     "context",
+		Elements: []slackText{{Type: "mrkdwn", Text: footer}},
+	})
+
+	return slackMessage{Text: title, Blocks: blocks}
+}
+
+func post(ctx context.Context, client *http.Client, endpoint string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("failed to create notification request: invalid URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// the body usually says what's wrong, e.g. Slack's "invalid_token"
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to send notification: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var failedEvent = Event{
+	Command:      "pr",
+	Target:       "owner/repo#1",
+	Outcome:      OutcomeFailure,
+	Message:      "CI failed",
+	FailedChecks: []string{"test", "<lint>"},
+	Duration:     90*time.Second + 400*time.Millisecond,
+}
+
+// receive returns a server which records the body of the last request.
+func receive(t *testing.T, status int) (*httptest.Server, *[]byte) {
+	t.Helper()
+
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("invalid_token"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &body
+}
+
+func TestParseSink(t *testing.T) {
+	t.Parallel()
+
+	client := &http.Client{}
+
+	sink, err := ParseSink("webhook=https://example.com/hook", client)
+	require.NoError(t, err)
+	require.Equal(t, Webhook{URL: "https://example.com/hook", Client: client}, sink)
+
+	sink, err = ParseSink("slack=https://hooks.slack.com/services/T0/B0/secret", client)
+	require.NoError(t, err)
+	require.Equal(t, Slack{URL: "https://hooks.slack.com/services/T0/B0/secret", Client: client}, sink)
+
+	_, err = ParseSink("https://hooks.slack.com/services/T0/B0/secret", client)
+	require.EqualError(t, err, "invalid notification: must be KIND=URL, where KIND is one of webhook, slack")
+
+	_, err = ParseSink("email=me@example.com", client)
+	require.EqualError(t, err, `invalid notification kind "email": must be one of webhook, slack`)
+
+	_, err = ParseSink("slack=hooks.slack.com/services/T0/B0/secret", client)
+	require.EqualError(t, err, "invalid slack notification URL: must be http or https")
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	server, body := receive(t, http.StatusNoContent)
+
+	sink := Webhook{URL: server.URL, Client: server.Client()}
+	require.NoError(t, sink.Notify(context.Background(), failedEvent))
+	require.JSONEq(t, `{
+		"command": "pr",
+		"target": "owner/repo#1",
+		"outcome": "failure",
+		"message": "CI failed",
+		"failed_checks": ["test", "<lint>"],
+		"duration_seconds": 90
+	}`, string(*body))
+
+	require.NoError(t, sink.Notify(context.Background(), Event{Command: "ci", Outcome: OutcomeSuccess}))
+	require.Contains(t, string(*body), `"failed_checks":[]`)
+}
+
+func TestSlack(t *testing.T) {
+	t.Parallel()
+
+	server, body := receive(t, http.StatusOK)
+
+	sink := Slack{URL: server.URL, Client: server.Client()}
+	require.NoError(t, sink.Notify(context.Background(), failedEvent))
+
+	var got slackMessage
+	require.NoError(t, json.Unmarshal(*body, &got))
+	require.Equal(t, slackMessage{
+		Text: "wait-for-github pr owner/repo#1: failure",
+		Blocks: []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: ":x: `wait-for-github pr` *owner/repo#1*: failure"}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*Failed checks*\n• test\n• &lt;lint&gt;"}},
+			{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: "CI failed · Took 1m30s"}}},
+		},
+	}, got)
+}
+
+func TestNotifyError(t *testing.T) {
+	t.Parallel()
+
+	server, _ := receive(t, http.StatusForbidden)
+
+	sink := Slack{URL: server.URL + "/services/secret", Client: server.Client()}
+	err := sink.Notify(context.Background(), failedEvent)
+	require.EqualError(t, err, "failed to send notification: 403 Forbidden: invalid_token")
+
+	server.Close()
+	err = sink.Notify(context.Background(), failedEvent)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "secret")
+}
@@ -0,0 +1,214 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing exports OpenTelemetry traces of waits, their polls and the
+// GitHub API calls they make, if an OTLP endpoint is configured with the
+// standard OTEL_* environment variables.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/grafana/wait-for-github"
+	serviceName         = "wait-for-github"
+
+	// shutdownTimeout bounds how long sending the last spans can take when
+	// the command finishes.
+	shutdownTimeout = 10 * time.Second
+
+	// protocolHTTP is the only OTLP protocol supported, to avoid pulling in
+	// gRPC.
+	protocolHTTP = "http/protobuf"
+)
+
+// Enabled returns whether traces should be exported, which is when an OTLP
+// endpoint is configured and the SDK isn't disabled.
+func Enabled() bool {
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		return false
+	}
+	if os.Getenv("OTEL_TRACES_EXPORTER") == "none" {
+		return false
+	}
+
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup starts exporting traces if Enabled. The function it returns sends
+// any spans which haven't been sent yet, and must be called before exiting.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	if !Enabled() {
+		return noop, nil
+	}
+
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	if protocol != "" && protocol != protocolHTTP {
+		return noop, fmt.Errorf("unsupported OTLP protocol %q: only %s is supported", protocol, protocolHTTP)
+	}
+
+	// the endpoint, headers and so on are read from the environment
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return noop, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return noop, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+// ContextFromEnv returns ctx with the span in the TRACEPARENT environment
+// variable as its parent, if there is one, so that a wait run from a traced
+// pipeline shows up in its trace.
+func ContextFromEnv(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{
+		"traceparent": os.Getenv("TRACEPARENT"),
+		"tracestate":  os.Getenv("TRACESTATE"),
+	}
+
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
+
+// Start starts a span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// exitCoder is the interface of errors which say how the wait ended, which
+// cli.Exit returns.
+type exitCoder interface {
+	error
+	ExitCode() int
+}
+
+// End ends span, which ended with err. Errors which say how the wait ended
+// are only failures if they have a non-zero exit code.
+func End(span trace.Span, err error) {
+	defer span.End()
+
+	if err == nil {
+		return
+	}
+
+	var exitErr exitCoder
+	if errors.As(err, &exitErr) {
+		span.SetAttributes(attribute.Int("exit_code", exitErr.ExitCode()))
+		if exitErr.ExitCode() == 0 {
+			return
+		}
+	} else {
+		span.RecordError(err)
+	}
+
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// AddEvent adds an event to the span in ctx, if there is one.
+func AddEvent(ctx context.Context, name string, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// fromCacheHeader is set by httpcache on responses which came from its
+// cache, including those GitHub said were unchanged.
+const fromCacheHeader = "X-From-Cache"
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// Transport returns a transport which makes a span of each request made
+// through next, named after the operation it's for.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return transport{next: next}
+}
+
+// Unwrap returns the transport requests are passed on to.
+func (t transport) Unwrap() http.RoundTripper {
+	return t.next
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	op := metrics.Operation(req.Context())
+	ctx, span := otel.Tracer(instrumentationName).Start(req.Context(), "GitHub "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("github.operation", op),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("http.response.status_code", resp.StatusCode),
+		attribute.Bool("http.cache_hit", resp.Header.Get(fromCacheHeader) == "1"),
+	)
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+
+	return resp, nil
+}
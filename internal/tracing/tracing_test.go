@@ -0,0 +1,208 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/grafana/wait-for-github/internal/metrics"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an OTLP/HTTP trace collector which keeps the spans it's sent.
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			c.spans = append(c.spans, ss.GetSpans()...)
+		}
+	}
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	resp, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	_, _ = w.Write(resp)
+}
+
+func (c *collector) span(t *testing.T, name string) *tracepb.Span {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, span := range c.spans {
+		if span.GetName() == name {
+			return span
+		}
+	}
+
+	t.Fatalf("no span named %q was exported", name)
+	return nil
+}
+
+func attr(span *tracepb.Span, key string) *commonpb.AnyValue {
+	for _, kv := range span.GetAttributes() {
+		if kv.GetKey() == key {
+			return kv.GetValue()
+		}
+	}
+
+	return nil
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	require.False(t, Enabled())
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	require.True(t, Enabled())
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	require.False(t, Enabled())
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	require.False(t, Enabled())
+}
+
+func TestSetupUnsupportedProtocol(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+
+	_, err := Setup(context.Background())
+	require.ErrorContains(t, err, `unsupported OTLP protocol "grpc"`)
+}
+
+func TestExport(t *testing.T) {
+	col := &collector{}
+	collectorServer := httptest.NewServer(col)
+	defer collectorServer.Close()
+
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cached" {
+			w.Header().Set(fromCacheHeader, "1")
+		}
+	}))
+	defer github.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collectorServer.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "")
+	t.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	ctx := context.Background()
+	shutdown, err := Setup(ctx)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+	get := func(ctx context.Context, path string) {
+		req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, "Test"+path[1:]), http.MethodGet, github.URL+path, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	rootCtx, root := Start(ContextFromEnv(ctx), "wait")
+	pollCtx, poll := Start(rootCtx, "poll")
+	get(pollCtx, "/fresh")
+	get(pollCtx, "/cached")
+	AddEvent(pollCtx, "rate limit wait")
+	End(poll, errors.New("rate limited"))
+	End(root, cli.Exit("CI successful", 0))
+
+	require.NoError(t, shutdown(ctx))
+
+	rootSpan := col.span(t, "wait")
+	require.Equal(t, "0af7651916cd43dd8448eb211c80319c", hex.EncodeToString(rootSpan.GetTraceId()))
+	require.Equal(t, tracepb.Status_STATUS_CODE_UNSET, rootSpan.GetStatus().GetCode())
+	require.Equal(t, int64(0), attr(rootSpan, "exit_code").GetIntValue())
+
+	pollSpan := col.span(t, "poll")
+	require.Equal(t, rootSpan.GetSpanId(), pollSpan.GetParentSpanId())
+	require.Equal(t, tracepb.Status_STATUS_CODE_ERROR, pollSpan.GetStatus().GetCode())
+	require.Len(t, pollSpan.GetEvents(), 2) // the wait and the error
+	require.Equal(t, "rate limit wait", pollSpan.GetEvents()[0].GetName())
+
+	fresh := col.span(t, "GitHub Testfresh")
+	require.Equal(t, pollSpan.GetSpanId(), fresh.GetParentSpanId())
+	require.Equal(t, tracepb.Span_SPAN_KIND_CLIENT, fresh.GetKind())
+	require.Equal(t, "Testfresh", attr(fresh, "github.operation").GetStringValue())
+	require.Equal(t, int64(http.StatusOK), attr(fresh, "http.response.status_code").GetIntValue())
+	require.False(t, attr(fresh, "http.cache_hit").GetBoolValue())
+
+	cached := col.span(t, "GitHub Testcached")
+	require.True(t, attr(cached, "http.cache_hit").GetBoolValue())
+}
+
+func TestEnd(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode tracepb.Status_StatusCode
+	}{
+		{"success", nil, tracepb.Status_STATUS_CODE_UNSET},
+		{"exit zero", cli.Exit("done", 0), tracepb.Status_STATUS_CODE_UNSET},
+		{"exit non-zero", cli.Exit("CI failed", 1), tracepb.Status_STATUS_CODE_ERROR},
+		{"error", errors.New("boom"), tracepb.Status_STATUS_CODE_ERROR},
+	}
+
+	col := &collector{}
+	collectorServer := httptest.NewServer(col)
+	defer collectorServer.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collectorServer.URL)
+	t.Setenv("TRACEPARENT", "")
+
+	ctx := context.Background()
+	shutdown, err := Setup(ctx)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		_, span := Start(ctx, tt.name)
+		End(span, tt.err)
+	}
+
+	require.NoError(t, shutdown(ctx))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.wantCode, col.span(t, tt.name).GetStatus().GetCode())
+		})
+	}
+}
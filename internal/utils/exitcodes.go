@@ -0,0 +1,41 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+// Exit codes for each way waiting can end, so that callers can tell them
+// apart, e.g. to retry after a timeout but not after a failure. These are
+// documented in the README, and mustn't change.
+const (
+	ExitSuccess = 0
+	// ExitFailed is for when what was waited for failed, e.g. CI.
+	ExitFailed = 1
+	// ExitClosed is for when a PR was closed without being merged.
+	ExitClosed  = 2
+	ExitTimeout = 3
+	// ExitMergeConflict is for when a PR can't be auto-merged because it
+	// conflicts with its base branch.
+	ExitMergeConflict = 4
+	// ExitAuth is for when GitHub rejects the credentials, or they don't
+	// have permission for what was asked.
+	ExitAuth = 5
+	// ExitUsage is for invalid arguments or flags, like EX_USAGE in
+	// sysexits.h.
+	ExitUsage = 64
+	// ExitInterrupted is for SIGINT, following the shell convention of 128
+	// plus the signal number.
+	ExitInterrupted = 130
+)
@@ -0,0 +1,230 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// The states a node in a graph can finish in.
+const (
+	NodeSucceeded = "succeeded"
+	NodeFailed    = "failed"
+	// NodeSkipped means a node didn't run because a dependency didn't succeed.
+	NodeSkipped = "skipped"
+	// NodePending means a node was still waiting when the graph timed out.
+	NodePending = "pending"
+)
+
+// GraphNode is a condition to wait for once the nodes it depends on have
+// succeeded.
+type GraphNode struct {
+	Name      string
+	DependsOn []string
+	// Timeout bounds how long the node waits once it has started. Zero means
+	// no limit other than the graph's own.
+	Timeout time.Duration
+
+	// Run waits for the node's condition, and returns the node's outputs for
+	// the nodes which depend on it. It's given the outputs of the nodes it
+	// depends on, keyed by name. Like a Check, it finishes the node by
+	// returning a cli.ExitCoder; exit code 0 means the node succeeded.
+	Run func(ctx context.Context, inputs map[string]map[string]string) (map[string]string, error)
+}
+
+// GraphNodeResult is the outcome of a node.
+type GraphNodeResult struct {
+	Name     string
+	State    string
+	Message  string
+	Duration time.Duration
+}
+
+// ValidateGraph checks that node names are unique, that all dependencies
+// exist, and that there are no cycles.
+func ValidateGraph(nodes []GraphNode) error {
+	byName := make(map[string]GraphNode, len(nodes))
+	for _, node := range nodes {
+		if node.Name == "" {
+			return errors.New("every node must have a name")
+		}
+		if _, ok := byName[node.Name]; ok {
+			return fmt.Errorf("duplicate node %q", node.Name)
+		}
+		byName[node.Name] = node
+	}
+
+	for _, node := range nodes {
+		for _, dep := range node.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("node %q depends on unknown node %q", node.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(nodes))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, dep := range byName[name].DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, node := range nodes {
+		if err := visit(node.Name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunGraph runs every node as soon as the nodes it depends on have succeeded,
+// so independent nodes wait concurrently. A node whose dependency fails is
+// skipped. It returns the result of every node, in the order given, and an
+// exit error which is successful only if every node succeeded.
+func RunGraph(ctx context.Context, logger *slog.Logger, nodes []GraphNode) ([]GraphNodeResult, error) {
+	if err := ValidateGraph(nodes); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]GraphNodeResult, len(nodes))
+	outputs := make(map[string]map[string]string, len(nodes))
+
+	done := make(map[string]chan struct{}, len(nodes))
+	for _, node := range nodes {
+		done[node.Name] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[node.Name])
+
+			result := runGraphNode(ctx, logger, node, done, &mu, results, outputs)
+
+			mu.Lock()
+			results[node.Name] = result
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	ordered := make([]GraphNodeResult, 0, len(nodes))
+	var failed []string
+	for _, node := range nodes {
+		result := results[node.Name]
+		ordered = append(ordered, result)
+
+		if result.State != NodeSucceeded {
+			failed = append(failed, node.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return ordered, cli.Exit(fmt.Sprintf("Not all waits succeeded: %s", strings.Join(failed, ", ")), ExitFailed)
+	}
+
+	return ordered, cli.Exit("All waits succeeded", ExitSuccess)
+}
+
+func runGraphNode(ctx context.Context, logger *slog.Logger, node GraphNode, done map[string]chan struct{}, mu *sync.Mutex, results map[string]GraphNodeResult, outputs map[string]map[string]string) GraphNodeResult {
+	logger = logger.With("node", node.Name)
+	inputs := make(map[string]map[string]string, len(node.DependsOn))
+
+	for _, dep := range node.DependsOn {
+		select {
+		case <-done[dep]:
+		case <-ctx.Done():
+			return GraphNodeResult{Name: node.Name, State: NodePending, Message: "Timeout reached"}
+		}
+
+		mu.Lock()
+		depResult, depOutputs := results[dep], outputs[dep]
+		mu.Unlock()
+
+		if depResult.State != NodeSucceeded {
+			logger.InfoContext(ctx, "skipping, as a dependency didn't succeed", "dependency", dep)
+			return GraphNodeResult{Name: node.Name, State: NodeSkipped, Message: fmt.Sprintf("%s %s", dep, depResult.State)}
+		}
+		inputs[dep] = depOutputs
+	}
+
+	nodeCtx := ctx
+	if node.Timeout > 0 {
+		var cancel context.CancelFunc
+		nodeCtx, cancel = context.WithTimeout(ctx, node.Timeout)
+		defer cancel()
+	}
+
+	logger.InfoContext(ctx, "starting")
+	start := time.Now()
+	nodeOutputs, err := node.Run(nodeCtx, inputs)
+	result := GraphNodeResult{Name: node.Name, State: NodeFailed, Duration: time.Since(start)}
+
+	var exitErr cli.ExitCoder
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 0:
+		result.State = NodeSucceeded
+		result.Message = exitErr.Error()
+	case err != nil:
+		result.Message = err.Error()
+	default:
+		result.Message = "finished without a result"
+	}
+
+	logger.InfoContext(ctx, "finished", "state", result.State, "message", result.Message)
+
+	if result.State == NodeSucceeded {
+		mu.Lock()
+		outputs[node.Name] = nodeOutputs
+		mu.Unlock()
+	}
+
+	return result
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestValidateGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []GraphNode
+		wantErr string
+	}{
+		{
+			name: "valid",
+			nodes: []GraphNode{
+				{Name: "a"},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"a", "b"}},
+			},
+		},
+		{
+			name:    "duplicate",
+			nodes:   []GraphNode{{Name: "a"}, {Name: "a"}},
+			wantErr: `duplicate node "a"`,
+		},
+		{
+			name:    "unknown dependency",
+			nodes:   []GraphNode{{Name: "a", DependsOn: []string{"b"}}},
+			wantErr: `node "a" depends on unknown node "b"`,
+		},
+		{
+			name: "cycle",
+			nodes: []GraphNode{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+			wantErr: "dependency cycle: a -> c -> b -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGraph(tt.nodes)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func succeedWith(outputs map[string]string) func(context.Context, map[string]map[string]string) (map[string]string, error) {
+	return func(ctx context.Context, inputs map[string]map[string]string) (map[string]string, error) {
+		return outputs, cli.Exit("done", 0)
+	}
+}
+
+func TestRunGraph(t *testing.T) {
+	var gotInputs map[string]map[string]string
+
+	nodes := []GraphNode{
+		{Name: "merge", Run: succeedWith(map[string]string{"commit": "abc123"})},
+		{Name: "ci", DependsOn: []string{"merge"}, Run: func(ctx context.Context, inputs map[string]map[string]string) (map[string]string, error) {
+			gotInputs = inputs
+			return nil, cli.Exit("done", 0)
+		}},
+		{Name: "broken", Run: func(ctx context.Context, inputs map[string]map[string]string) (map[string]string, error) {
+			return nil, errors.New("boom")
+		}},
+		{Name: "after-broken", DependsOn: []string{"broken"}, Run: succeedWith(nil)},
+		{Name: "slow", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context, inputs map[string]map[string]string) (map[string]string, error) {
+			<-ctx.Done()
+			return nil, cli.Exit("Timeout reached", 1)
+		}},
+	}
+
+	results, err := RunGraph(context.Background(), testLogger, nodes)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, exitErr.ExitCode())
+	assert.Equal(t, "Not all waits succeeded: broken, after-broken, slow", exitErr.Error())
+
+	assert.Equal(t, map[string]map[string]string{"merge": {"commit": "abc123"}}, gotInputs)
+
+	require.Len(t, results, 5)
+	states := make(map[string]string)
+	for _, result := range results {
+		states[result.Name] = result.State
+	}
+	assert.Equal(t, map[string]string{
+		"merge":        NodeSucceeded,
+		"ci":           NodeSucceeded,
+		"broken":       NodeFailed,
+		"after-broken": NodeSkipped,
+		"slow":         NodeFailed,
+	}, states)
+	assert.Equal(t, "boom", results[2].Message)
+	assert.Equal(t, "Timeout reached", results[4].Message)
+}
+
+func TestRunGraphTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := make(chan struct{})
+
+	nodes := []GraphNode{
+		{Name: "a", Run: func(ctx context.Context, inputs map[string]map[string]string) (map[string]string, error) {
+			close(blocked)
+			<-ctx.Done()
+			return nil, cli.Exit("Timeout reached", 1)
+		}},
+		{Name: "b", DependsOn: []string{"a"}, Run: succeedWith(nil)},
+	}
+
+	go func() {
+		<-blocked
+		cancel()
+	}()
+
+	results, err := RunGraph(ctx, testLogger, nodes)
+	require.Error(t, err)
+	assert.Equal(t, NodeFailed, results[0].State)
+	assert.Contains(t, []string{NodePending, NodeSkipped}, results[1].State)
+}
+
+func TestRunGraphAllSucceeded(t *testing.T) {
+	results, err := RunGraph(context.Background(), testLogger, []GraphNode{{Name: "a", Run: succeedWith(nil)}})
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 0, exitErr.ExitCode())
+	assert.Equal(t, NodeSucceeded, results[0].State)
+}
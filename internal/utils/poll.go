@@ -0,0 +1,107 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"maps"
+	"math/rand/v2"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+)
+
+// pollGrowth is how much the interval grows by each time nothing has changed.
+const pollGrowth = 2
+
+// PollStrategy decides how long to wait between checks. Checks are made every
+// Interval for FastPeriod, at first and again whenever the status of a check
+// changes. After that, while nothing changes, the interval doubles each time
+// up to MaxInterval. Its zero value, other than Interval, checks every
+// Interval.
+type PollStrategy struct {
+	Interval    time.Duration
+	FastPeriod  time.Duration
+	MaxInterval time.Duration
+	// Jitter is the fraction of each interval which is randomly added or
+	// taken away, so that waits started together don't all check at once.
+	Jitter float64
+}
+
+// poller keeps track of where a PollStrategy has got to.
+type poller struct {
+	strategy PollStrategy
+
+	interval  time.Duration
+	fastUntil time.Time
+	// states are the last statuses seen of every check
+	states map[string]github.CIStatus
+
+	now    func() time.Time
+	random func() float64
+}
+
+func newPoller(strategy PollStrategy) *poller {
+	p := &poller{
+		strategy: strategy,
+		interval: strategy.Interval,
+		states:   make(map[string]github.CIStatus),
+		now:      time.Now,
+		random:   rand.Float64,
+	}
+	p.fastUntil = p.now().Add(strategy.FastPeriod)
+
+	return p
+}
+
+// next returns how long to wait before checking again, given the statuses of
+// the checks seen by the last check. It also returns whether any of them
+// changed.
+func (p *poller) next(states map[string]github.CIStatus) (time.Duration, bool) {
+	// checks which aren't of CI, or which failed, don't see any statuses,
+	// which doesn't mean they changed, so only the ones seen are compared. A
+	// check appearing is a change too, unless it's the first time any are
+	// seen.
+	changed := false
+	for check, status := range states {
+		if last, ok := p.states[check]; len(p.states) > 0 && (!ok || last != status) {
+			changed = true
+		}
+	}
+	maps.Copy(p.states, states)
+
+	now := p.now()
+	switch {
+	case changed:
+		p.fastUntil = now.Add(p.strategy.FastPeriod)
+		p.interval = p.strategy.Interval
+	case now.Before(p.fastUntil):
+		p.interval = p.strategy.Interval
+	default:
+		p.interval = max(p.strategy.Interval, min(p.interval*pollGrowth, p.strategy.MaxInterval))
+	}
+
+	return p.jitter(p.interval), changed
+}
+
+func (p *poller) jitter(interval time.Duration) time.Duration {
+	if p.strategy.Jitter <= 0 {
+		return interval
+	}
+
+	offset := p.strategy.Jitter * (2*p.random() - 1)
+	return time.Duration(float64(interval) * (1 + offset))
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPoller(strategy PollStrategy, now *time.Time) *poller {
+	p := newPoller(strategy)
+	p.now = func() time.Time { return *now }
+	p.fastUntil = now.Add(strategy.FastPeriod)
+
+	return p
+}
+
+func TestPollerFixedInterval(t *testing.T) {
+	now := time.Now()
+	p := newTestPoller(PollStrategy{Interval: time.Minute}, &now)
+
+	for range 5 {
+		now = now.Add(time.Hour)
+		interval, changed := p.next(nil)
+		require.Equal(t, time.Minute, interval)
+		require.False(t, changed)
+	}
+}
+
+func TestPollerGrowsAndResets(t *testing.T) {
+	now := time.Now()
+	p := newTestPoller(PollStrategy{
+		Interval:    10 * time.Second,
+		FastPeriod:  time.Minute,
+		MaxInterval: time.Minute,
+	}, &now)
+
+	pending := map[string]github.CIStatus{"grafana/wait-for-github: build": github.CIStatusPending}
+
+	next := func(states map[string]github.CIStatus) time.Duration {
+		t.Helper()
+
+		interval, _ := p.next(states)
+		now = now.Add(interval)
+		return interval
+	}
+
+	// the first statuses seen aren't a change
+	interval, changed := p.next(pending)
+	require.False(t, changed)
+	require.Equal(t, 10*time.Second, interval)
+	now = now.Add(interval)
+
+	// fast until a minute has gone by
+	for range 5 {
+		require.Equal(t, 10*time.Second, next(pending))
+	}
+
+	require.Equal(t, 20*time.Second, next(pending))
+	require.Equal(t, 40*time.Second, next(nil))
+	require.Equal(t, time.Minute, next(pending))
+	require.Equal(t, time.Minute, next(pending))
+
+	// a check finishing resets to fast polling
+	interval, changed = p.next(map[string]github.CIStatus{"grafana/wait-for-github: build": github.CIStatusPassed})
+	require.True(t, changed)
+	require.Equal(t, 10*time.Second, interval)
+
+	// as does a new check, but not one of them not being seen
+	now = now.Add(time.Hour)
+	require.Equal(t, 20*time.Second, next(nil))
+	interval, changed = p.next(map[string]github.CIStatus{"grafana/wait-for-github: lint": github.CIStatusPending})
+	require.True(t, changed)
+	require.Equal(t, 10*time.Second, interval)
+}
+
+func TestPollerJitter(t *testing.T) {
+	now := time.Now()
+	p := newTestPoller(PollStrategy{Interval: 100 * time.Second, Jitter: 0.1}, &now)
+
+	p.random = func() float64 { return 0 }
+	interval, _ := p.next(nil)
+	require.Equal(t, 90*time.Second, interval)
+
+	p.random = func() float64 { return 0.5 }
+	interval, _ = p.next(nil)
+	require.Equal(t, 100*time.Second, interval)
+
+	p.random = func() float64 { return 0.99 }
+	interval, _ = p.next(nil)
+	require.InDelta(t, 110*time.Second, interval, float64(time.Second))
+}
@@ -0,0 +1,221 @@
+// wait-for-github
+// Copyright (C) 2026, Grafana Labs
+
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU Affero General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option) any
+// later version.
+
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS
+// FOR A PARTICULAR PURPOSE.  See the GNU Affero General Public License for more
+// details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/metrics"
+	"github.com/grafana/wait-for-github/internal/tracing"
+	"github.com/urfave/cli/v3"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// How the results of several targets are combined.
+const (
+	// ModeAll succeeds once every target has succeeded, and fails as soon as
+	// any target fails.
+	ModeAll = "all"
+	// ModeAny succeeds as soon as any target succeeds, and fails once every
+	// target has failed.
+	ModeAny = "any"
+)
+
+// Modes returns the valid modes for RunTargetsUntilCancelledOrTimeout.
+func Modes() []string {
+	return []string{ModeAll, ModeAny}
+}
+
+// Target is a check to run alongside others, with a name to report its result
+// under.
+type Target struct {
+	Name  string
+	Check Check
+}
+
+// TargetResult is the outcome of a target. Done is false if the target was
+// still being waited for when RunTargetsUntilCancelledOrTimeout returned.
+type TargetResult struct {
+	Name     string
+	Done     bool
+	ExitCode int
+	Message  string
+}
+
+// RunTargetsUntilCancelledOrTimeout is RunUntilCancelledOrTimeout for several
+// targets. All targets which haven't finished yet are checked concurrently on
+// each interval. If any of them is rate limited, every target waits for the
+// rate limit to reset, since they share the same API quota.
+//
+// A check finishes its target by returning a cli.ExitCoder, like with
+// RunUntilCancelledOrTimeout. Any other error fails only that target. Once the
+// targets' results decide the outcome according to mode, the results of all
+// targets are returned along with the exit error to use.
+func RunTargetsUntilCancelledOrTimeout(ctx context.Context, logger *slog.Logger, targets []Target, poll PollStrategy, mode string) ([]TargetResult, error) {
+	results := make([]TargetResult, len(targets))
+	for i, target := range targets {
+		results[i].Name = target.Name
+	}
+
+	timer := time.NewTimer(poll.Interval)
+	defer timer.Stop()
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT)
+	defer signal.Stop(signalChan)
+
+	p := newPoller(poll)
+
+	for {
+		requests := github.DefaultRateBudget.Requests()
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			newWait time.Duration
+			states  = make([]github.CheckStates, len(targets))
+		)
+
+		for i, target := range targets {
+			if results[i].Done {
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				metrics.Poll()
+				pollCtx, span := tracing.Start(github.WithCheckStates(ctx, &states[i]), "poll", attribute.String("target", target.Name))
+				err := target.Check.Check(pollCtx)
+				defer tracing.End(span, err)
+				if err == nil {
+					return
+				}
+
+				var exitErr cli.ExitCoder
+				if errors.As(err, &exitErr) {
+					results[i] = TargetResult{Name: target.Name, Done: true, ExitCode: exitErr.ExitCode(), Message: exitErr.Error()}
+					return
+				}
+
+				if wait, ok := rateLimitWait(pollCtx, logger, err, poll.Interval); ok {
+					mu.Lock()
+					newWait = max(newWait, wait)
+					mu.Unlock()
+					return
+				}
+
+				logger.ErrorContext(ctx, "check failed", "target", target.Name, "error", err)
+				results[i] = TargetResult{Name: target.Name, Done: true, ExitCode: ExitFailed, Message: err.Error()}
+			}()
+		}
+
+		wg.Wait()
+
+		if exitErr := targetsOutcome(results, mode); exitErr != nil {
+			return results, exitErr
+		}
+
+		interval, changed := p.next(targetStates(targets, states))
+		if changed {
+			logger.DebugContext(ctx, "status of checks changed, rechecking sooner")
+		}
+		wait := budgetWait(ctx, logger, max(interval, newWait), github.DefaultRateBudget.RequestsSince(requests))
+		logger.InfoContext(ctx, "rechecking", "interval", wait, "targets_remaining", remainingTargets(results))
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			logger.InfoContext(ctx, "timeout reached, exiting")
+			return results, cli.Exit("Timeout reached", ExitTimeout)
+		case <-signalChan:
+			logger.InfoContext(ctx, "Received SIGINT, exiting")
+			return results, cli.Exit("Received SIGINT", ExitInterrupted)
+		}
+	}
+}
+
+// targetsOutcome returns the exit error to finish with, or nil if the results
+// so far don't decide the outcome yet.
+func targetsOutcome(results []TargetResult, mode string) cli.ExitCoder {
+	var firstFailure *TargetResult
+	done := 0
+
+	for i, result := range results {
+		if !result.Done {
+			continue
+		}
+		done++
+
+		if result.ExitCode == 0 && mode == ModeAny {
+			return cli.Exit(fmt.Sprintf("%s succeeded", result.Name), ExitSuccess)
+		}
+
+		if result.ExitCode != 0 && firstFailure == nil {
+			firstFailure = &results[i]
+		}
+	}
+
+	if mode == ModeAll && firstFailure != nil {
+		return cli.Exit(fmt.Sprintf("%s failed: %s", firstFailure.Name, firstFailure.Message), firstFailure.ExitCode)
+	}
+
+	if done < len(results) {
+		return nil
+	}
+
+	if mode == ModeAny {
+		return cli.Exit("All targets failed", firstFailure.ExitCode)
+	}
+
+	return cli.Exit("All targets succeeded", ExitSuccess)
+}
+
+func remainingTargets(results []TargetResult) int {
+	remaining := 0
+	for _, result := range results {
+		if !result.Done {
+			remaining++
+		}
+	}
+
+	return remaining
+}
+
+// targetStates returns the statuses of the checks seen for each target, keyed
+// by target too, as targets can be different commits in the same repository.
+func targetStates(targets []Target, states []github.CheckStates) map[string]github.CIStatus {
+	all := make(map[string]github.CIStatus)
+	for i, target := range targets {
+		for check, status := range states[i].Statuses() {
+			all[target.Name+": "+check] = status
+		}
+	}
+
+	return all
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gh "github.com/grafana/wait-for-github/internal/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// exitAfter returns a check which exits with code after being called n times.
+func exitAfter(n int, code int) *TestCheck {
+	calls := 0
+	return &TestCheck{
+		fn: func() error {
+			calls++
+			if calls >= n {
+				return cli.Exit("finished", code)
+			}
+			return nil
+		},
+	}
+}
+
+func TestRunTargets(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         string
+		targets      []Target
+		expectedCode int
+		expectedDone []bool
+	}{
+		{
+			name: "all succeed",
+			mode: ModeAll,
+			targets: []Target{
+				{Name: "a", Check: exitAfter(1, 0)},
+				{Name: "b", Check: exitAfter(3, 0)},
+			},
+			expectedCode: 0,
+			expectedDone: []bool{true, true},
+		},
+		{
+			name: "all fails fast",
+			mode: ModeAll,
+			targets: []Target{
+				{Name: "a", Check: exitAfter(1, 1)},
+				{Name: "b", Check: exitAfter(3, 0)},
+			},
+			expectedCode: 1,
+			expectedDone: []bool{true, false},
+		},
+		{
+			name: "any succeeds early",
+			mode: ModeAny,
+			targets: []Target{
+				{Name: "a", Check: exitAfter(1, 0)},
+				{Name: "b", Check: exitAfter(3, 0)},
+			},
+			expectedCode: 0,
+			expectedDone: []bool{true, false},
+		},
+		{
+			name: "any fails once all fail",
+			mode: ModeAny,
+			targets: []Target{
+				{Name: "a", Check: exitAfter(1, 1)},
+				{Name: "b", Check: exitAfter(3, 1)},
+			},
+			expectedCode: 1,
+			expectedDone: []bool{true, true},
+		},
+		{
+			name: "errors fail only their target",
+			mode: ModeAny,
+			targets: []Target{
+				{Name: "a", Check: &TestCheck{fn: func() error { return errors.New("boom") }}},
+				{Name: "b", Check: exitAfter(2, 0)},
+			},
+			expectedCode: 0,
+			expectedDone: []bool{true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			results, err := RunTargetsUntilCancelledOrTimeout(ctx, testLogger, tt.targets, PollStrategy{Interval: time.Millisecond}, tt.mode)
+
+			var exitErr cli.ExitCoder
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tt.expectedCode, exitErr.ExitCode())
+
+			require.Len(t, results, len(tt.targets))
+			for i, result := range results {
+				assert.Equal(t, tt.targets[i].Name, result.Name)
+				assert.Equal(t, tt.expectedDone[i], result.Done, "target %s", result.Name)
+			}
+		})
+	}
+}
+
+// TestRunTargetsRateLimitPausesAll tests that a rate limit on one target
+// delays the next check of every target.
+func TestRunTargetsRateLimitPausesAll(t *testing.T) {
+	var bCalls atomic.Int32
+	aCalls := 0
+
+	targets := []Target{
+		{Name: "a", Check: &TestCheck{fn: func() error {
+			aCalls++
+			if aCalls == 1 {
+				return &gh.GitHubRateLimitError{ResetTime: time.Now().Add(200 * time.Millisecond)}
+			}
+			return cli.Exit("done", 0)
+		}}},
+		{Name: "b", Check: &TestCheck{fn: func() error {
+			bCalls.Add(1)
+			return nil
+		}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	results, err := RunTargetsUntilCancelledOrTimeout(ctx, testLogger, targets, PollStrategy{Interval: time.Millisecond}, ModeAny)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "Timeout reached", exitErr.Error())
+	assert.Equal(t, int32(1), bCalls.Load(), "b should not be checked while rate limited")
+	assert.False(t, results[0].Done)
+	assert.False(t, results[1].Done)
+}
+
+func TestRunTargetsTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	targets := []Target{
+		{Name: "a", Check: exitAfter(10, 0)},
+		{Name: "b", Check: exitAfter(10, 0)},
+	}
+
+	results, err := RunTargetsUntilCancelledOrTimeout(ctx, testLogger, targets, PollStrategy{Interval: time.Second}, ModeAll)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, ExitTimeout, exitErr.ExitCode())
+	assert.Len(t, results, 2)
+}
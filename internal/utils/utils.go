@@ -26,7 +26,10 @@ import (
 	"time"
 
 	"github.com/grafana/wait-for-github/internal/github"
+	"github.com/grafana/wait-for-github/internal/metrics"
+	"github.com/grafana/wait-for-github/internal/tracing"
 	"github.com/urfave/cli/v3"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Check interface {
@@ -50,6 +53,7 @@ func TryRerunFailedWorkflows(ctx context.Context, client github.RerunFailedWorkf
 	}
 
 	if rerunCount > 0 {
+		metrics.WorkflowsRerun(rerunCount)
 		retriesDone++
 		logger.InfoContext(ctx, "re-ran failed workflows, continuing to wait",
 			"workflows_rerun", rerunCount, "retries_done", retriesDone)
@@ -71,53 +75,151 @@ func TryRerunFailedWorkflows(ctx context.Context, client github.RerunFailedWorkf
 	return false, retriesDone
 }
 
-func RunUntilCancelledOrTimeout(ctx context.Context, logger *slog.Logger, check Check, interval time.Duration) error {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// ChecksGracePeriod decides what a commit without any checks or statuses
+// means. They might not have been created yet, or the commit might not have
+// been pushed yet, so it's treated as pending for Period. After that, it's a
+// failure if RequireChecks is set and a success otherwise.
+type ChecksGracePeriod struct {
+	Period        time.Duration
+	RequireChecks bool
+
+	firstSeen time.Time
+	now       func() time.Time
+}
+
+// Resolve returns the status to use in place of status. Only
+// CIStatusNoChecks is changed; other statuses are returned as they are.
+func (g *ChecksGracePeriod) Resolve(ctx context.Context, logger *slog.Logger, status github.CIStatus) github.CIStatus {
+	if status != github.CIStatusNoChecks {
+		g.firstSeen = time.Time{}
+		return status
+	}
+
+	now := time.Now
+	if g.now != nil {
+		now = g.now
+	}
+
+	if g.firstSeen.IsZero() {
+		g.firstSeen = now()
+	}
+
+	if waited := now().Sub(g.firstSeen); waited < g.Period {
+		logger.InfoContext(ctx, "no checks or statuses found yet, waiting for them to be created",
+			"waited", waited.Round(time.Second).String(), "grace_period", g.Period.String())
+		return github.CIStatusPending
+	}
+
+	if g.RequireChecks {
+		logger.ErrorContext(ctx, "no checks or statuses found and checks are required; check that the ref is correct")
+		return github.CIStatusFailed
+	}
+
+	logger.WarnContext(ctx, "no checks or statuses found, assuming success. Use --require-checks to fail instead")
+	return github.CIStatusPassed
+}
+
+// rateLimitWait returns how long to wait before checking again if err is a
+// rate limiting error, or GitHub failing in a way which should go away by
+// itself. The RetryAfter and x-ratelimit-reset headers, where present, act as a
+// minimum wait time unless interval is already larger.
+func rateLimitWait(ctx context.Context, logger *slog.Logger, err error, interval time.Duration) (time.Duration, bool) {
+	rle := &github.GitHubRateLimitError{}
+	arle := &github.GitHubAbuseRateLimitError{}
+	te := &github.GitHubTransientError{}
+
+	switch {
+	case errors.As(err, &rle):
+		newWait := max(interval, time.Until(rle.ResetTime))
+		logger.InfoContext(ctx, "check was rate limited", "type", "rate-limit", "wait", newWait.String())
+		metrics.RateLimitWait()
+		tracing.AddEvent(ctx, "rate limit wait", attribute.String("type", "rate-limit"), attribute.String("wait", newWait.String()))
+		return newWait, true
+	case errors.As(err, &arle):
+		newWait := max(interval, arle.RetryAfter)
+		logger.InfoContext(ctx, "check was rate limited", "type", "abuse-rate-limit", "wait", newWait.String())
+		metrics.RateLimitWait()
+		tracing.AddEvent(ctx, "rate limit wait", attribute.String("type", "abuse-rate-limit"), attribute.String("wait", newWait.String()))
+		return newWait, true
+	case errors.As(err, &te):
+		logger.WarnContext(ctx, "check failed, will retry", "error", err, "wait", interval.String())
+		tracing.AddEvent(ctx, "transient error", attribute.String("wait", interval.String()))
+		return interval, true
+	default:
+		return 0, false
+	}
+}
+
+// budgetWait returns how long to wait before checking again, stretching wait
+// if checking every wait would run out of the rate limit before it resets,
+// when each check makes cost requests. Other waits using the same credentials
+// use up the rate limit too, which slows this one down.
+func budgetWait(ctx context.Context, logger *slog.Logger, wait time.Duration, cost map[string]int) time.Duration {
+	minWait, resource := github.DefaultRateBudget.MinInterval(time.Now(), cost)
+	if minWait <= wait {
+		return wait
+	}
+
+	limit, _ := github.DefaultRateBudget.Limit(resource)
+	logger.InfoContext(ctx, "slowing down so that the rate limit lasts until it resets",
+		"resource", resource, "remaining", limit.Remaining, "reset", limit.Reset.Format(time.RFC3339),
+		"requests_per_check", cost[resource], "wait", minWait.String())
+	tracing.AddEvent(ctx, "rate limit budget wait", attribute.String("resource", resource), attribute.String("wait", minWait.String()))
+
+	return minWait
+}
+
+// RunUntilCancelledOrTimeout calls check until it returns an error, waiting
+// between calls as poll says. A cli.ExitCoder error is how the wait ended;
+// rate limit errors make it wait longer before checking again.
+func RunUntilCancelledOrTimeout(ctx context.Context, logger *slog.Logger, check Check, poll PollStrategy) error {
+	timer := time.NewTimer(poll.Interval)
+	defer timer.Stop()
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT)
+	defer signal.Stop(signalChan)
+
+	p := newPoller(poll)
 
-	rateLimited := false
+	for n := 1; ; n++ {
+		metrics.Poll()
+		var states github.CheckStates
+		pollCtx, span := tracing.Start(github.WithCheckStates(ctx, &states), "poll", attribute.Int("poll", n))
+		requests := github.DefaultRateBudget.Requests()
+		err := check.Check(pollCtx)
+
+		interval, changed := p.next(states.Statuses())
+		if changed {
+			logger.DebugContext(ctx, "status of checks changed, rechecking sooner")
+		}
 
-	for {
-		err := check.Check(ctx)
+		wait := interval
 		if err != nil {
-			// If this is a rate limiting error, we should consider the
-			// RetryAfter and x-ratelimit-reset headers where present. They
-			// should act as minimum wait time unless interval is already
-			// larger:
-			rle := &github.GitHubRateLimitError{}
-			arle := &github.GitHubAbuseRateLimitError{}
-			if errors.As(err, &rle) {
-				newWait := max(interval, time.Until(rle.ResetTime))
-				ticker.Reset(newWait)
-				logger.InfoContext(ctx, "check was rate limited", "type", "rate-limit", "wait", newWait.String())
-				rateLimited = true
-			} else if errors.As(err, &arle) {
-				newWait := max(interval, arle.RetryAfter)
-				ticker.Reset(newWait)
-				logger.InfoContext(ctx, "check was rate limited", "type", "abuse-rate-limit", "wait", newWait.String())
-				rateLimited = true
-			} else {
+			newWait, ok := rateLimitWait(pollCtx, logger, err, interval)
+			if !ok {
+				tracing.End(span, err)
 				return err
 			}
+
+			wait = newWait
 		}
 
-		logger.InfoContext(ctx, "rechecking", "interval", interval)
+		// only once it's known that there'll be another check
+		wait = budgetWait(pollCtx, logger, wait, github.DefaultRateBudget.RequestsSince(requests))
+		tracing.End(span, err)
+
+		logger.InfoContext(ctx, "rechecking", "interval", wait)
+		timer.Reset(wait)
 
 		select {
-		case <-ticker.C:
-			if rateLimited {
-				ticker.Reset(interval)
-				rateLimited = false
-			}
+		case <-timer.C:
 		case <-ctx.Done():
 			logger.InfoContext(ctx, "timeout reached, exiting")
-			return cli.Exit("Timeout reached", 1)
+			return cli.Exit("Timeout reached", ExitTimeout)
 		case <-signalChan:
 			logger.InfoContext(ctx, "Received SIGINT, exiting")
-			return cli.Exit("Received SIGINT", 1)
+			return cli.Exit("Received SIGINT", ExitInterrupted)
 		}
 	}
 }
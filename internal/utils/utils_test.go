@@ -41,7 +41,7 @@ func TestCancel(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, 1*time.Second)
+	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, PollStrategy{Interval: 1 * time.Second})
 
 	assert.Error(t, err)
 }
@@ -65,7 +65,7 @@ func TestCalledRepeatedly(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, 1*time.Millisecond)
+	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, PollStrategy{Interval: 1 * time.Millisecond})
 
 	assert.Equal(t, 10, n)
 	assert.Equal(t, exitError, err)
@@ -86,7 +86,7 @@ func TestInterrupt(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, 1*time.Millisecond)
+	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, PollStrategy{Interval: 1 * time.Millisecond})
 
 	assert.EqualError(t, err, "Received SIGINT")
 }
@@ -120,7 +120,7 @@ func TestPrimaryRateLimitRetries(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, interval)
+	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, PollStrategy{Interval: interval})
 
 	assert.Equal(t, sentinelErr, err, "expected sentinel error from second call, not the rate limit error")
 	assert.Equal(t, 2, calls)
@@ -155,7 +155,7 @@ func TestAbuseRateLimitRespectsRetryAfter(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, interval)
+	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, PollStrategy{Interval: interval})
 
 	assert.Equal(t, sentinelErr, err, "expected sentinel error from second call, not the abuse rate limit error")
 	assert.Equal(t, 2, calls)
@@ -190,13 +190,48 @@ func TestAbuseRateLimitFallsBackToInterval(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, interval)
+	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, PollStrategy{Interval: interval})
 
 	assert.Equal(t, sentinelErr, err, "expected sentinel error from second call, not the abuse rate limit error")
 	assert.Equal(t, 2, calls)
 	assert.GreaterOrEqual(t, secondCallAt.Sub(firstCallAt), interval, "second call should not happen before interval elapses")
 }
 
+// TestTransientErrorRetries tests that RunUntilCancelledOrTimeout checks again
+// after the interval when GitHub fails in a way which should go away, rather
+// than exiting.
+func TestTransientErrorRetries(t *testing.T) {
+	ctx := t.Context()
+	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	interval := 50 * time.Millisecond
+	var firstCallAt time.Time
+	var secondCallAt time.Time
+	sentinelErr := fmt.Errorf("sentinel: second call succeeded")
+
+	calls := 0
+	check := &TestCheck{
+		fn: func() error {
+			calls++
+			if calls == 1 {
+				firstCallAt = time.Now()
+				return fmt.Errorf("failed to get CI status: %w", &gh.GitHubTransientError{
+					Message: "Something went wrong while executing your query.",
+				})
+			}
+			secondCallAt = time.Now()
+			return sentinelErr
+		},
+	}
+
+	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, PollStrategy{Interval: interval})
+
+	assert.Equal(t, sentinelErr, err, "expected sentinel error from second call, not the transient error")
+	assert.Equal(t, 2, calls)
+	assert.GreaterOrEqual(t, secondCallAt.Sub(firstCallAt), interval, "second call should not happen before interval elapses")
+}
+
 // TestPrimaryRateLimitContextCancellation tests that if the context is already
 // cancelled when a GitHubRateLimitError is encountered, the function exits with
 // "Timeout reached" and does not retry.
@@ -215,7 +250,7 @@ func TestPrimaryRateLimitContextCancellation(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(ctx, testLogger, check, interval)
+	err := RunUntilCancelledOrTimeout(ctx, testLogger, check, PollStrategy{Interval: interval})
 
 	assert.EqualError(t, err, "Timeout reached")
 	assert.Equal(t, 1, calls, "Check should be called once but not retried after cancellation")
@@ -239,7 +274,7 @@ func TestAbuseRateLimitContextCancellation(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(ctx, testLogger, check, interval)
+	err := RunUntilCancelledOrTimeout(ctx, testLogger, check, PollStrategy{Interval: interval})
 
 	assert.EqualError(t, err, "Timeout reached")
 	assert.Equal(t, 1, calls, "Check should be called once but not retried after cancellation")
@@ -256,7 +291,35 @@ func TestGlobalTimeout(t *testing.T) {
 		},
 	}
 
-	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, 1*time.Millisecond)
+	err := RunUntilCancelledOrTimeout(timeoutCtx, testLogger, check, PollStrategy{Interval: 1 * time.Millisecond})
 
 	assert.EqualError(t, err, "Timeout reached")
 }
+
+func TestChecksGracePeriod(t *testing.T) {
+	now := time.Now()
+	grace := &ChecksGracePeriod{
+		Period: time.Minute,
+		now:    func() time.Time { return now },
+	}
+
+	ctx := context.Background()
+
+	// Other statuses are left alone
+	assert.Equal(t, gh.CIStatusFailed, grace.Resolve(ctx, testLogger, gh.CIStatusFailed))
+
+	// No checks is pending until the grace period has passed...
+	assert.Equal(t, gh.CIStatusPending, grace.Resolve(ctx, testLogger, gh.CIStatusNoChecks))
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, gh.CIStatusPending, grace.Resolve(ctx, testLogger, gh.CIStatusNoChecks))
+
+	// ...then successful, unless checks are required
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, gh.CIStatusPassed, grace.Resolve(ctx, testLogger, gh.CIStatusNoChecks))
+	grace.RequireChecks = true
+	assert.Equal(t, gh.CIStatusFailed, grace.Resolve(ctx, testLogger, gh.CIStatusNoChecks))
+
+	// Seeing checks restarts the grace period
+	assert.Equal(t, gh.CIStatusPending, grace.Resolve(ctx, testLogger, gh.CIStatusPending))
+	assert.Equal(t, gh.CIStatusPending, grace.Resolve(ctx, testLogger, gh.CIStatusNoChecks))
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	w := zip.NewWriter(f)
+	for name, contents := range files {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	return path
+}
+
+func TestExtractZip(t *testing.T) {
+	path := writeTestZip(t, map[string]string{
+		"coverage.txt":    "mode: set",
+		"nested/file.txt": "hello",
+	})
+
+	dir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, ExtractZip(path, dir))
+
+	contents, err := os.ReadFile(filepath.Join(dir, "coverage.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "mode: set", string(contents))
+
+	contents, err = os.ReadFile(filepath.Join(dir, "nested", "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(contents))
+}
+
+// TestExtractZipRejectsPathTraversal tests that entries which would escape the
+// destination directory are not extracted.
+func TestExtractZipRejectsPathTraversal(t *testing.T) {
+	path := writeTestZip(t, map[string]string{
+		"../evil.txt": "nope",
+	})
+
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "out")
+	err := ExtractZip(path, dir)
+
+	assert.ErrorContains(t, err, "outside of")
+	assert.NoFileExists(t, filepath.Join(parent, "evil.txt"))
+}